@@ -0,0 +1,62 @@
+package bigshamir
+
+import "fmt"
+
+//ShareMany shares every secret like Share, so shares[j] are the shares of secrets[j].
+//The points of the parties are looked up once for all secrets.
+func (ss *SecretSharingScheme[E]) ShareMany(secrets []E) [][]SecretShare[E] {
+	f := ss.field
+	points := ss.pointsOf(ss.parties)
+
+	shares := make([][]SecretShare[E], len(secrets))
+	h := make(polynomial[E], ss.threshold+1)
+	for j, secret := range secrets {
+		h[0] = secret
+		for coefficient := 1; coefficient <= ss.threshold; coefficient++ {
+			h[coefficient] = f.Random()
+		}
+		shares[j] = make([]SecretShare[E], ss.n)
+		for i, x := range points {
+			shares[j][i] = SecretShare[E]{X: ss.parties[i], Y: hornerAt(f, h, x)}
+		}
+	}
+	return shares
+}
+
+//ReconstructMany extracts the secret of every sharing, so secrets[j] is the secret of sharings[j].
+//All sharings must hold shares of the same t+1 or more parties in the same order,
+//so the recombination vector is looked up once and applied to every sharing.
+func (ss *SecretSharingScheme[E]) ReconstructMany(sharings [][]SecretShare[E]) ([]E, error) {
+	if len(sharings) == 0 {
+		return nil, nil
+	}
+	xs := xsOf(sharings[0])
+	if len(xs) < ss.threshold+1 {
+		return nil, fmt.Errorf("bigshamir: %d shares cannot reconstruct a sharing of threshold %d", len(xs), ss.threshold)
+	}
+	if err := ss.checkParties(xs); err != nil {
+		return nil, err
+	}
+	r := ss.recombinationVector(xs)
+	coefficients := make([]E, len(xs))
+	for i, x := range xs {
+		coefficients[i] = r[x]
+	}
+
+	f := ss.field
+	secrets := make([]E, len(sharings))
+	for j, shares := range sharings {
+		if len(shares) != len(xs) {
+			return nil, fmt.Errorf("bigshamir: sharing %d has %d shares, sharing 0 has %d", j, len(shares), len(xs))
+		}
+		sum := f.Zero()
+		for i, share := range shares {
+			if share.X != xs[i] {
+				return nil, fmt.Errorf("bigshamir: share %d of sharing %d is of party %d, not %d", i, j, share.X, xs[i])
+			}
+			sum = f.Add(sum, f.Mul(coefficients[i], share.Y))
+		}
+		secrets[j] = sum
+	}
+	return secrets, nil
+}
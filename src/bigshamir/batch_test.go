@@ -0,0 +1,98 @@
+package bigshamir
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"../field"
+)
+
+func TestShareManyReconstructMany(t *testing.T) {
+	f, _ := field.NewMont64(4001)
+	setting := NewScheme(f, 2, 7)
+	secrets := elements(f, 0, 1, 2, 4000, 1234)
+	sharings := setting.ShareMany(secrets)
+	if len(sharings) != len(secrets) {
+		t.Fatalf("Shared %d secrets to %d sharings", len(secrets), len(sharings))
+	}
+	for j, shares := range sharings {
+		//Every sharing is a sharing like those of Share
+		if reconstructed, err := setting.Reconstruct(shares[4:]); err != nil || !f.Equal(reconstructed, secrets[j]) {
+			t.Errorf("Sharing %d reconstructed to %s, not %s", j, f.String(reconstructed), f.String(secrets[j]))
+		}
+	}
+
+	//Any t+1 parties reconstruct, in any order as long as it is the same in every sharing
+	subset := make([][]SecretShare[field.Mont64Element], len(sharings))
+	for j, shares := range sharings {
+		subset[j] = []SecretShare[field.Mont64Element]{shares[6], shares[1], shares[3]}
+	}
+	reconstructed, err := setting.ReconstructMany(subset)
+	if err != nil || !reflect.DeepEqual(reconstructed, secrets) {
+		t.Errorf("Reconstructed %v, %v", reconstructed, err)
+	}
+}
+
+func TestReconstructManyRejectsDifferentParties(t *testing.T) {
+	f, _ := field.NewMont64(4001)
+	setting := NewScheme(f, 1, 4)
+	sharings := setting.ShareMany(elements(f, 5, 6))
+	for _, sharings := range [][][]SecretShare[field.Mont64Element]{
+		{sharings[0][:1], sharings[1][:1]},
+		{sharings[0][:2], sharings[1][1:3]},
+		{sharings[0][:2], sharings[1][:3]},
+		{{sharings[0][0], sharings[0][0]}, {sharings[1][0], sharings[1][0]}},
+	} {
+		if secrets, err := setting.ReconstructMany(sharings); err == nil {
+			t.Errorf("Reconstructed %v from %v", secrets, sharings)
+		}
+	}
+}
+
+func TestReconstructWithManyParties(t *testing.T) {
+	//Products of 60 points overflow an int, but not the field
+	f := field.NewBig(new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 127), big.NewInt(1)))
+	setting := NewScheme(f, 29, 60)
+	secret := big.NewInt(123456789)
+	shares := setting.Share(secret)
+	for _, subset := range [][]SecretShare[*big.Int]{shares[:30], shares[30:], shares} {
+		if reconstructed, err := setting.Reconstruct(subset); err != nil || reconstructed.Cmp(secret) != 0 {
+			t.Errorf("Reconstructed %d from %d shares", reconstructed, len(subset))
+		}
+	}
+	reconstructed, err := setting.ReconstructMany(setting.ShareMany([]*big.Int{secret}))
+	if err != nil || reconstructed[0].Cmp(secret) != 0 {
+		t.Errorf("Reconstructed %v, %v", reconstructed, err)
+	}
+}
+
+func benchmarkReconstruct(b *testing.B, batch bool) {
+	f, _ := field.NewMont64(4001)
+	setting := NewScheme(f, 9, 20)
+	secrets := make([]field.Mont64Element, 1000)
+	for j := range secrets {
+		secrets[j] = f.FromInt(int64(j))
+	}
+	sharings := setting.ShareMany(secrets)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if batch {
+			setting.ReconstructMany(sharings)
+			continue
+		}
+		for _, shares := range sharings {
+			setting.Reconstruct(shares)
+		}
+	}
+}
+
+//BenchmarkReconstruct reconstructs 1000 secrets one at a time
+func BenchmarkReconstruct(b *testing.B) {
+	benchmarkReconstruct(b, false)
+}
+
+//BenchmarkReconstructMany reconstructs 1000 secrets at once
+func BenchmarkReconstructMany(b *testing.B) {
+	benchmarkReconstruct(b, true)
+}
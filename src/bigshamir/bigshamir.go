@@ -1,147 +1,160 @@
 package bigshamir
 
 import (
-	"crypto/rand"
 	"fmt"
 	"math/big"
 	"strconv"
+
+	"../field"
 )
 
 type (
 	//SecretShare represented by a point on a polynomial
 	//i.e. the share of party X has value Y
-	SecretShare struct {
+	SecretShare[E any] struct {
 		X int
-		Y *big.Int
+		Y E
 	}
 	//RecombinationShare is indexed by the party who shared it for recombining
-	RecombinationShare struct {
-		SecretShare SecretShare
+	RecombinationShare[E any] struct {
+		SecretShare SecretShare[E]
 		Index       int
 	}
 )
 
-func newPoint(X, Y int) SecretShare {
-	return SecretShare{X: X, Y: big.NewInt(int64(Y))}
+func newPoint(X, Y int) SecretShare[*big.Int] {
+	return SecretShare[*big.Int]{X: X, Y: big.NewInt(int64(Y))}
 }
 
-func (p SecretShare) String() string {
-	return "(" + strconv.FormatInt(int64(p.X), 10) + "," + p.Y.String() + ")"
+func (p SecretShare[E]) String() string {
+	return "(" + strconv.FormatInt(int64(p.X), 10) + "," + fmt.Sprint(p.Y) + ")"
 }
 
-type polynomial []*big.Int
+type polynomial[E any] []E
 
-//SecretSharingScheme defines a shamir secret sharings scheme for n parties in Zp
-type SecretSharingScheme struct {
-	p         *big.Int
+//SecretSharingScheme defines a shamir secret sharings scheme for n parties over a prime field with elements of type E.
+//The X of a share is the index of the party holding it. Party i holds the polynomial at i, unless the scheme was constructed with other points.
+type SecretSharingScheme[E any] struct {
+	field     field.Field[E]
 	threshold int
 	n         int
+	//parties are the indices of the parties in increasing order
+	parties []int
+	//points are the evaluation points of the parties by index, nil if party i holds the point i
+	points map[int]E
+	//cache holds the recombination vectors of the sets of parties shares were recombined from
+	cache *coefficientCache[E]
+}
+
+//NewSS constructs a secret sharing scheme over the field of integers modulo the prime p
+func NewSS(p *big.Int, threshold, n int) SecretSharingScheme[*big.Int] {
+	return NewScheme[*big.Int](field.NewBig(p), threshold, n)
 }
 
-//NewSS constructs a secret sharing scheme with initialised reconstruction vector
-func NewSS(p int64, threshold, n int) SecretSharingScheme {
-	ss := SecretSharingScheme{}
-	ss.p = big.NewInt(p)
-	ss.n = n
-	ss.threshold = threshold
-	return ss
+//NewScheme constructs a secret sharing scheme over any field
+func NewScheme[E any](f field.Field[E], threshold, n int) SecretSharingScheme[E] {
+	parties := make([]int, n)
+	for i := range parties {
+		parties[i] = i + 1
+	}
+	return SecretSharingScheme[E]{field: f, threshold: threshold, n: n, parties: parties, cache: newCoefficientCache[E]()}
 }
 
-var standardSetting = SecretSharingScheme{p: big.NewInt(5), threshold: 1, n: 3}
+var standardSetting = NewSS(big.NewInt(5), 1, 3)
 
 //Share splits a secret into shares (points)
-func (ss *SecretSharingScheme) Share(secret *big.Int) []SecretShare {
+func (ss *SecretSharingScheme[E]) Share(secret E) []SecretShare[E] {
 	//Draw random polynomial h
-	h := make([]*big.Int, ss.threshold+1)
+	h := make(polynomial[E], ss.threshold+1)
 	h[0] = secret
 	for coefficient := 1; coefficient <= ss.threshold; coefficient++ {
-		randBigInt, _ := rand.Int(rand.Reader, ss.p)
-		h[coefficient] = randBigInt
+		h[coefficient] = ss.field.Random()
 	}
 
 	//Evaluate points on h
-	shares := make([]SecretShare, ss.n)
-	for i := 1; i <= ss.n; i++ {
-		shares[i-1] = SecretShare{
-			X: i,
-			Y: hornersEvaluatePolynomialAt(h, int64(i), ss.p)}
+	shares := make([]SecretShare[E], ss.n)
+	for i, party := range ss.parties {
+		shares[i] = SecretShare[E]{
+			X: party,
+			Y: hornerAt(ss.field, h, ss.point(party))}
 	}
 
 	return shares
 }
 
-//Reconstruct extracts the secret from threshold or more shares
-func (ss *SecretSharingScheme) Reconstruct(shares []SecretShare) *big.Int {
+//Reconstruct extracts the secret from threshold+1 or more shares of distinct parties of the scheme
+func (ss *SecretSharingScheme[E]) Reconstruct(shares []SecretShare[E]) (E, error) {
 	k := len(shares)
-
 	if k < ss.threshold+1 {
-		fmt.Println("Not enough shares to reconstruct")
-		return big.NewInt(0)
+		return ss.field.Zero(), fmt.Errorf("bigshamir: %d shares cannot reconstruct a secret of threshold %d", k, ss.threshold)
+	}
+	if err := ss.checkParties(xsOf(shares)); err != nil {
+		return ss.field.Zero(), err
 	}
 
-	return lagrangeInterpolationAtZero(shares, ss.p)
+	return interpolateAtZero(ss.field, ss.recombinationVector(xsOf(shares)), shares), nil
 }
 
 //Add creates a secret sharing of the sum of two secret shared values
 //Slices should have same indicies in same order
 //Can panic
 //Todo: implement sorting?
-func (ss *SecretSharingScheme) Add(aShares, bShares []SecretShare) []SecretShare {
-	aPlusBShares := make([]SecretShare, len(aShares))
+func (ss *SecretSharingScheme[E]) Add(aShares, bShares []SecretShare[E]) []SecretShare[E] {
+	aPlusBShares := make([]SecretShare[E], len(aShares))
 
 	for i := range aShares {
 		X := aShares[i].X
-		Y := new(big.Int).Add(aShares[i].Y, bShares[i].Y)
-		aPlusBShares[i] = SecretShare{X: X, Y: Y}
+		Y := ss.field.Add(aShares[i].Y, bShares[i].Y)
+		aPlusBShares[i] = SecretShare[E]{X: X, Y: Y}
 	}
 
 	return aPlusBShares
 }
 
 //Scale multiplies a secret sharing by a constant
-func (ss *SecretSharingScheme) Scale(scalar *big.Int, shares []SecretShare) []SecretShare {
-	scaledShares := make([]SecretShare, len(shares))
+func (ss *SecretSharingScheme[E]) Scale(scalar E, shares []SecretShare[E]) []SecretShare[E] {
+	scaledShares := make([]SecretShare[E], len(shares))
 
-	for _, share := range shares {
-		scaled := new(big.Int).Mul(scalar, share.Y)
-		scaledShares[share.X-1] = SecretShare{X: share.X, Y: scaled}
+	for i, share := range shares {
+		scaled := ss.field.Mul(scalar, share.Y)
+		scaledShares[i] = SecretShare[E]{X: share.X, Y: scaled}
 	}
 
 	return scaledShares
 }
 
 //Mul creates a secret sharing of the product of two secret shared values
-//slices should have same indicies in same order
+//slices should hold the shares of all parties in the order of Parties
 //Can panic
 //Todo: implement sorting?
-func (ss *SecretSharingScheme) Mul(aShares, bShares []SecretShare) []SecretShare {
+func (ss *SecretSharingScheme[E]) Mul(aShares, bShares []SecretShare[E]) []SecretShare[E] {
 	if len(aShares) != ss.n || len(bShares) != ss.n {
 		panic("Missing shares")
 	}
 
 	//Step 1: Each party locally computes the product of its two shares
-	aTimesBShares := make([]SecretShare, len(aShares))
+	aTimesBShares := make([]SecretShare[E], len(aShares))
 	for party := range aShares {
 		X := aShares[party].X
-		Y := new(big.Int).Mul(aShares[party].Y, bShares[party].Y)
-		aTimesBShares[party] = SecretShare{X: X, Y: Y.Mod(Y, ss.p)}
+		Y := ss.field.Mul(aShares[party].Y, bShares[party].Y)
+		aTimesBShares[party] = SecretShare[E]{X: X, Y: Y}
 	}
 
 	//Step 2:Each P_i distributes [h(i);f_i]_t
-	partyLocalShares := make([][]RecombinationShare, ss.n)
+	partyLocalShares := make([][]RecombinationShare[E], ss.n)
 	for _, share := range aTimesBShares {
 		newShares := ss.Share(share.Y)
 		for i, newShare := range newShares {
 			partyLocalShares[i] = append(partyLocalShares[i],
-				RecombinationShare{SecretShare: newShare, Index: share.X})
+				RecombinationShare[E]{SecretShare: newShare, Index: share.X})
 		}
 	}
 
 	//Step 3: Create degree threshold sharing
-	shares := make([]SecretShare, ss.n)
+	shares := make([]SecretShare[E], ss.n)
 	for party := range shares {
 		yValue := ss.RecombineMultiplicationShares(partyLocalShares[party])
-		shares[party] = SecretShare{X: party + 1, Y: yValue}
+		shares[party] = SecretShare[E]{X: ss.parties[party], Y: yValue}
 	}
 
 	return shares
@@ -149,89 +162,106 @@ func (ss *SecretSharingScheme) Mul(aShares, bShares []SecretShare) []SecretShare
 
 //RecombineMultiplicationShares takes at least 2t+1 shares of degree <2t+1
 //returns shares of degree t
-func (ss *SecretSharingScheme) RecombineMultiplicationShares(shares []RecombinationShare) *big.Int {
-	//Compute recombination vector
+func (ss *SecretSharingScheme[E]) RecombineMultiplicationShares(shares []RecombinationShare[E]) E {
+	//Recombination vector of the parties, computed once per set of parties
 	xs := make([]int, len(shares))
 	for i := range shares {
 		xs[i] = shares[i].Index
 	}
-	r := recombinationVector(ss.p, xs...)
+	r := ss.recombinationVector(xs)
 
-	sum := big.NewInt(0)
+	sum := ss.field.Zero()
 	for _, share := range shares {
 		ri := r[share.Index]
-
-		product := new(big.Int).Mul(ri, share.SecretShare.Y)
-		sum.Add(sum, product)
+		sum = ss.field.Add(sum, ss.field.Mul(ri, share.SecretShare.Y))
 	}
 
-	return sum.Mod(sum, ss.p)
+	return sum
 }
 
-func evaluatePolynomialAt(p polynomial, X int64, prime *big.Int) *big.Int {
+func evaluatePolynomialAt[E any](f field.Field[E], p polynomial[E], X int64) E {
 	if len(p) == 0 {
-		return big.NewInt(0)
+		return f.Zero()
 	}
-	bigX := big.NewInt(X)
-	xPower := big.NewInt(X)
-	sum := new(big.Int).Set(p[0])
+	x := f.FromInt(X)
+	xPower := x
+	sum := p[0]
 	for i := 1; i < len(p); i++ {
-		term := new(big.Int).Mul(p[i], xPower)
-		sum.Add(sum, term)
-		xPower.Mul(xPower, bigX)
+		sum = f.Add(sum, f.Mul(p[i], xPower))
+		xPower = f.Mul(xPower, x)
 	}
-	return sum.Mod(sum, prime)
+	return sum
 }
 
-func hornersEvaluatePolynomialAt(p polynomial, X int64, prime *big.Int) *big.Int {
-	bigX := big.NewInt(X)
-	res := big.NewInt(0)
+func hornersEvaluatePolynomialAt[E any](f field.Field[E], p polynomial[E], X int64) E {
+	return hornerAt(f, p, f.FromInt(X))
+}
+
+//hornerAt evaluates p at the field element x
+func hornerAt[E any](f field.Field[E], p polynomial[E], x E) E {
+	res := f.Zero()
 	for i := len(p) - 1; i >= 0; i-- {
-		res.Mul(res, bigX)
-		res.Add(res, p[i])
-		// res.Mod(res, prime) //todo benchmark, with and without
+		res = f.Add(f.Mul(res, x), p[i])
 	}
-	return res.Mod(res, prime)
+	return res
 }
 
-func lagrangeInterpolationAtZero(points []SecretShare, prime *big.Int) *big.Int {
-	r := reconstructionVectorFromPoints(points, prime)
+func lagrangeInterpolationAtZero[E any](f field.Field[E], points []SecretShare[E]) E {
+	return interpolateAtZero(f, reconstructionVectorFromPoints(f, points), points)
+}
 
-	sum := big.NewInt(0)
+//interpolateAtZero combines the points with their recombination vector r
+func interpolateAtZero[E any](f field.Field[E], r map[int]E, points []SecretShare[E]) E {
+	sum := f.Zero()
 	for _, share := range points {
-		tmp := new(big.Int)
-		tmp.Mul(share.Y, r[share.X]) //y_i*delta_i(0)
-		sum = sum.Add(sum, tmp)
+		sum = f.Add(sum, f.Mul(share.Y, r[share.X])) //y_i*delta_i(0)
 	}
 
-	return sum.Mod(sum, prime)
+	return sum
+}
+
+func reconstructionVectorFromPoints[E any](f field.Field[E], points []SecretShare[E]) map[int]E {
+	return recombinationVector(f, xsOf(points)...)
 }
 
-func reconstructionVectorFromPoints(points []SecretShare, prime *big.Int) map[int]*big.Int {
-	m := make([]int, len(points))
-	for i, p := range points {
-		m[i] = p.X
+//recombinationVector are the Lagrange coefficients delta_i(0) for the points xs.
+//Products are taken in the field, so they do not overflow however many points there are.
+func recombinationVector[E any](f field.Field[E], xs ...int) map[int]E {
+	points := make([]E, len(xs))
+	for k, x := range xs {
+		points[k] = f.FromInt(int64(x))
 	}
-	return recombinationVector(prime, m...)
+	return recombinationVectorAt(f, xs, points)
 }
 
-func recombinationVector(prime *big.Int, xs ...int) map[int]*big.Int {
-	terms := make(map[int]*big.Int)
-	for _, i := range xs {
-		num := 1
-		den := 1
-		for _, j := range xs {
-			if i == j {
+//recombinationVectorAt are the Lagrange coefficients delta_i(0) of the parties xs, where party xs[k] holds the point points[k]
+func recombinationVectorAt[E any](f field.Field[E], xs []int, points []E) map[int]E {
+	nums := make([]E, len(xs))
+	dens := make([]E, len(xs))
+	for k, i := range points {
+		num := f.One()
+		den := f.One()
+		for l, j := range points {
+			if k == l {
 				continue
 			}
-			num *= j
-			den *= j - i
+			num = f.Mul(num, j)
+			den = f.Mul(den, f.Sub(j, i))
 		}
-		term := big.NewInt(int64(den))         //den
-		term.ModInverse(term, prime)           //den^-1
-		term.Mul(term, big.NewInt(int64(num))) //num*den^-1
-		term.Mod(term, prime)
-		terms[i] = term
+		nums[k], dens[k] = num, den
+	}
+
+	//Invert all denominators with a single inversion: prefix[k] is the product of dens[:k]
+	prefix := make([]E, len(xs)+1)
+	prefix[0] = f.One()
+	for k, den := range dens {
+		prefix[k+1] = f.Mul(prefix[k], den)
+	}
+	inverse := f.Inverse(prefix[len(xs)])
+	terms := make(map[int]E, len(xs))
+	for k := len(xs) - 1; k >= 0; k-- {
+		terms[xs[k]] = f.Mul(nums[k], f.Mul(inverse, prefix[k])) //num*den^-1
+		inverse = f.Mul(inverse, dens[k])
 	}
 
 	return terms
@@ -3,23 +3,36 @@ package bigshamir
 import (
 	"math/big"
 	"testing"
+
+	"../field"
 )
 
 func TestShare(t *testing.T) {
-	setting := NewSS(11, 1, 3)
+	setting := NewSS(big.NewInt(11), 1, 3)
+	shares := setting.Share(big.NewInt(7))
+	reconstructed, err := setting.Reconstruct(shares)
+	if err != nil || reconstructed.Cmp(big.NewInt(7)) != 0 {
+		t.Errorf("Share + reconstruct failed: %d, %v", reconstructed, err)
+	}
+}
+
+func TestReconstructRejectsInvalidShares(t *testing.T) {
+	setting := NewSS(big.NewInt(11), 1, 3)
 	shares := setting.Share(big.NewInt(7))
-	reconstructed := setting.Reconstruct(shares)
-	if reconstructed.Cmp(big.NewInt(7)) != 0 {
-		t.Errorf("Share + reconstruct failed")
+	if _, err := setting.Reconstruct(shares[:1]); err == nil {
+		t.Error("Reconstructed from too few shares")
+	}
+	if _, err := setting.Reconstruct([]SecretShare[*big.Int]{shares[0], shares[0]}); err == nil {
+		t.Error("Reconstructed from two shares of the same party")
 	}
 }
 
 func TestEvaluatePolynomialAt(t *testing.T) {
-	p := polynomial{big.NewInt(7), big.NewInt(3), big.NewInt(2)}
-	prime := big.NewInt(11)
+	f := field.NewBig(big.NewInt(11))
+	p := polynomial[*big.Int]{big.NewInt(7), big.NewInt(3), big.NewInt(2)}
 
 	fun := func(x, y int64) {
-		res := evaluatePolynomialAt(p, x, prime)
+		res := evaluatePolynomialAt(f, p, x)
 		if res.Cmp(big.NewInt(y)) != 0 {
 			t.Errorf("Expected f(%d) = %d. Got %d", x, y, res)
 		}
@@ -32,11 +45,11 @@ func TestEvaluatePolynomialAt(t *testing.T) {
 }
 
 func TestHornersEvaluatePolynomialAt(t *testing.T) {
-	p := polynomial{big.NewInt(7), big.NewInt(3), big.NewInt(2)}
-	prime := big.NewInt(11)
+	f := field.NewBig(big.NewInt(11))
+	p := polynomial[*big.Int]{big.NewInt(7), big.NewInt(3), big.NewInt(2)}
 
 	fun := func(x, y int64) {
-		res := hornersEvaluatePolynomialAt(p, x, prime)
+		res := hornersEvaluatePolynomialAt(f, p, x)
 		if res.Cmp(big.NewInt(y)) != 0 {
 			t.Errorf("Expected f(%d) = %d. Got %d", x, y, res)
 		}
@@ -49,47 +62,47 @@ func TestHornersEvaluatePolynomialAt(t *testing.T) {
 }
 
 func TestLagrangeInterpolationAtZero(t *testing.T) {
-	shares := []SecretShare{
+	shares := []SecretShare[*big.Int]{
 		newPoint(1, 1),
 		newPoint(2, 8),
 		newPoint(3, 6),
 	}
-	zeroValue := lagrangeInterpolationAtZero(shares, big.NewInt(11))
+	zeroValue := lagrangeInterpolationAtZero(field.NewBig(big.NewInt(11)), shares)
 	if zeroValue.Cmp(big.NewInt(7)) != 0 {
-		t.Errorf(zeroValue.String())
+		t.Error(zeroValue)
 	}
 }
 
 func TestAdd(t *testing.T) {
-	setting := NewSS(11, 1, 3)
+	setting := NewSS(big.NewInt(11), 1, 3)
 	aShares := setting.Share(big.NewInt(7))
 	bShares := setting.Share(big.NewInt(9))
 	aPlusBShares := setting.Add(aShares, bShares)
-	reconstructed := setting.Reconstruct(aPlusBShares)
-	if reconstructed.Cmp(big.NewInt(5)) != 0 {
-		t.Errorf("Share two values and Add then reconstruct failed")
+	reconstructed, err := setting.Reconstruct(aPlusBShares)
+	if err != nil || reconstructed.Cmp(big.NewInt(5)) != 0 {
+		t.Errorf("Share two values and Add then reconstruct failed: %d, %v", reconstructed, err)
 	}
 }
 
 func TestScale(t *testing.T) {
-	setting := NewSS(11, 1, 3)
+	setting := NewSS(big.NewInt(11), 1, 3)
 	shares := setting.Share(big.NewInt(7))
 	scaled := setting.Scale(big.NewInt(2), shares)
-	reconstructed := setting.Reconstruct(scaled)
-	if reconstructed.Cmp(big.NewInt(3)) != 0 {
-		t.Errorf("Share and scale then reconstruct failed")
+	reconstructed, err := setting.Reconstruct(scaled)
+	if err != nil || reconstructed.Cmp(big.NewInt(3)) != 0 {
+		t.Errorf("Share and scale then reconstruct failed: %d, %v", reconstructed, err)
 	}
 }
 
 func TestMul(t *testing.T) {
-	setting := NewSS(11, 1, 3)
-	fun := func(aShares, bShares []SecretShare) []SecretShare {
+	setting := NewSS(big.NewInt(11), 1, 3)
+	fun := func(aShares, bShares []SecretShare[*big.Int]) []SecretShare[*big.Int] {
 		aTimesBShares := setting.Mul(aShares, bShares)
-		a := setting.Reconstruct(aShares)
-		b := setting.Reconstruct(bShares)
+		a, _ := setting.Reconstruct(aShares)
+		b, _ := setting.Reconstruct(bShares)
 		aTimesB := new(big.Int).Mul(a, b)
-		aTimesB.Mod(aTimesB, setting.p)
-		reconstructed := setting.Reconstruct(aTimesBShares)
+		aTimesB.Mod(aTimesB, setting.field.Modulus())
+		reconstructed, _ := setting.Reconstruct(aTimesBShares)
 		if aTimesB.Cmp(reconstructed) != 0 {
 			t.Errorf("Share two values and Mul then reconstruct failed. Expected %d, got %d.", aTimesB, reconstructed)
 		}
@@ -109,7 +122,7 @@ func TestMul(t *testing.T) {
 }
 
 func TestRecombinationVector(t *testing.T) {
-	r := recombinationVector(big.NewInt(11), 3, 4, 5)
+	r := recombinationVector(field.NewBig(big.NewInt(11)), 3, 4, 5)
 	test := func(index int, rIndex int64) {
 		rI, contains := r[index]
 		if !contains {
@@ -123,3 +136,32 @@ func TestRecombinationVector(t *testing.T) {
 	test(4, 7)
 	test(5, 6)
 }
+
+func TestMont64Scheme(t *testing.T) {
+	f, _ := field.NewMont64(4001)
+	setting := NewScheme(f, 2, 7)
+	a, b := f.FromInt(1234), f.FromInt(-5)
+	shares := setting.Mul(setting.Add(setting.Share(a), setting.Share(b)), setting.Share(b))
+	if reconstructed, err := setting.Reconstruct(shares); err != nil || f.String(reconstructed) != "1857" {
+		t.Errorf("(1234 - 5) * -5 mod 4001 should be 1857, was %s", f.String(reconstructed))
+	}
+}
+
+func benchmarkMul[E any](b *testing.B, f field.Field[E]) {
+	setting := NewScheme(f, 9, 20)
+	aShares := setting.Share(f.FromInt(7))
+	bShares := setting.Share(f.FromInt(9))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		setting.Mul(aShares, bShares)
+	}
+}
+
+func BenchmarkMulBig(b *testing.B) {
+	benchmarkMul(b, field.NewBig(big.NewInt(4001)))
+}
+
+func BenchmarkMulMont64(b *testing.B) {
+	f, _ := field.NewMont64(4001)
+	benchmarkMul(b, f)
+}
@@ -0,0 +1,60 @@
+package bigshamir
+
+import "../field"
+
+//Bivariate is a symmetric bivariate polynomial S(x, y) = sum c_kl x^k y^l of degree t in both variables, with c_kl = c_lk.
+//Row i is the polynomial S(x, i). By symmetry row i at j equals row j at i, which lets parties holding rows check each other.
+//Parties are mapped to their points in the scheme the polynomial was drawn for, and party 0 stands for the point 0 of the secret.
+type Bivariate[E any] struct {
+	field        field.Field[E]
+	coefficients [][]E
+	scheme       *SecretSharingScheme[E]
+}
+
+//ShareBivariate draws a random symmetric bivariate polynomial S with S(0, 0) = secret.
+//Row i at 0, S(0, i), is a share of the secret in ss.
+func (ss *SecretSharingScheme[E]) ShareBivariate(secret E) Bivariate[E] {
+	f := ss.field
+	coefficients := make([][]E, ss.threshold+1)
+	for k := range coefficients {
+		coefficients[k] = make([]E, ss.threshold+1)
+	}
+	for k := range coefficients {
+		for l := k; l <= ss.threshold; l++ {
+			c := f.Random()
+			if k == 0 && l == 0 {
+				c = secret
+			}
+			coefficients[k][l], coefficients[l][k] = c, c
+		}
+	}
+	return Bivariate[E]{field: f, coefficients: coefficients, scheme: ss}
+}
+
+//Row is the coefficients of S(x, i), from the constant one up
+func (b Bivariate[E]) Row(i int) []E {
+	row := make([]E, len(b.coefficients))
+	for k, coefficients := range b.coefficients {
+		row[k] = hornerAt(b.field, coefficients, b.point(i))
+	}
+	return row
+}
+
+//At is S(x, y)
+func (b Bivariate[E]) At(x, y int) E {
+	return hornerAt(b.field, b.Row(y), b.point(x))
+}
+
+//point is the point of party i, or 0 for party 0
+func (b Bivariate[E]) point(i int) E {
+	if i == 0 {
+		return b.field.Zero()
+	}
+	return b.scheme.point(i)
+}
+
+//EvaluateRow evaluates a row, or any polynomial given by its coefficients from the constant one up, at x.
+//That is at party x in schemes where party i holds the point i.
+func EvaluateRow[E any](f field.Field[E], row []E, x int) E {
+	return hornersEvaluatePolynomialAt(f, row, int64(x))
+}
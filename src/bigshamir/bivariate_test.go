@@ -0,0 +1,35 @@
+package bigshamir
+
+import (
+	"testing"
+
+	"../field"
+)
+
+func TestShareBivariate(t *testing.T) {
+	f, _ := field.NewMont64(4001)
+	setting := NewScheme(f, 2, 7)
+	secret := f.FromInt(1234)
+	s := setting.ShareBivariate(secret)
+
+	shares := make([]SecretShare[field.Mont64Element], 7)
+	for i := 1; i <= 7; i++ {
+		row := s.Row(i)
+		if len(row) != 3 {
+			t.Fatalf("Row %d has %d coefficients for threshold 2", i, len(row))
+		}
+		for j := 0; j <= 7; j++ {
+			if !f.Equal(EvaluateRow(f, row, j), s.At(j, i)) || !f.Equal(s.At(j, i), s.At(i, j)) {
+				t.Errorf("S(%d, %d) = %s, S(%d, %d) = %s", j, i, f.String(s.At(j, i)), i, j, f.String(s.At(i, j)))
+			}
+		}
+		shares[i-1] = SecretShare[field.Mont64Element]{X: i, Y: EvaluateRow(f, row, 0)}
+	}
+	//Rows at 0 are shares of the secret of threshold 2, so 3 of them reconstruct it
+	if reconstructed, err := setting.Reconstruct(shares[4:]); err != nil || !f.Equal(reconstructed, secret) {
+		t.Errorf("Reconstructed %s from rows at 0, shared %s", f.String(reconstructed), f.String(secret))
+	}
+	if !f.Equal(s.At(0, 0), secret) {
+		t.Errorf("S(0, 0) = %s, shared %s", f.String(s.At(0, 0)), f.String(secret))
+	}
+}
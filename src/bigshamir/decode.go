@@ -0,0 +1,159 @@
+package bigshamir
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"../field"
+)
+
+//ErrTooManyWrongShares is returned by RobustReconstruct when the shares are not within the errors it can correct of any sharing
+var ErrTooManyWrongShares = errors.New("bigshamir: too many wrong shares to reconstruct")
+
+//MaxWrongShares is the number of wrong shares among k shares RobustReconstruct corrects, floor((k-t-1)/2)
+func (ss *SecretSharingScheme[E]) MaxWrongShares(k int) int {
+	if k < ss.threshold+1 {
+		return 0
+	}
+	return (k - ss.threshold - 1) / 2
+}
+
+//RobustReconstruct extracts the secret from shares of which up to MaxWrongShares(len(shares)) may be wrong.
+//The shares are decoded as a Reed-Solomon codeword with the Berlekamp-Welch algorithm.
+//It returns the secret and the parties whose shares were wrong, in increasing order.
+//If more shares are wrong it fails with ErrTooManyWrongShares, at least when they are detectably wrong.
+func (ss *SecretSharingScheme[E]) RobustReconstruct(shares []SecretShare[E]) (E, []int, error) {
+	f := ss.field
+	k := len(shares)
+	if k < ss.threshold+1 {
+		return f.Zero(), nil, fmt.Errorf("bigshamir: %d shares cannot reconstruct a secret of threshold %d", k, ss.threshold)
+	}
+	if err := ss.checkParties(xsOf(shares)); err != nil {
+		return f.Zero(), nil, err
+	}
+
+	//Find the error locator E(x) = x^e + ... and Q(x) = P(x)E(x) of degree t+e with Q(x_i) = y_i E(x_i),
+	//so E is zero at the wrong shares and P is the polynomial of the sharing
+	e := ss.MaxWrongShares(k)
+	qCoefficients := ss.threshold + e + 1
+	rows := make([][]E, k)
+	rhs := make([]E, k)
+	for i, share := range shares {
+		x := ss.point(share.X)
+		row := make([]E, qCoefficients+e)
+		xPower := f.One()
+		for j := 0; j < qCoefficients; j++ {
+			row[j] = xPower
+			if j < e {
+				row[qCoefficients+j] = f.Neg(f.Mul(share.Y, xPower))
+			}
+			if j == e {
+				rhs[i] = f.Mul(share.Y, xPower)
+			}
+			xPower = f.Mul(xPower, x)
+		}
+		rows[i] = row
+	}
+	solution, solvable := solve(f, rows, rhs)
+	if !solvable {
+		return f.Zero(), nil, ErrTooManyWrongShares
+	}
+
+	q := polynomial[E](solution[:qCoefficients])
+	locator := make(polynomial[E], e+1)
+	copy(locator, solution[qCoefficients:])
+	locator[e] = f.One()
+	p, remainder := divide(f, q, locator)
+	for _, coefficient := range remainder {
+		if !f.IsZero(coefficient) {
+			return f.Zero(), nil, ErrTooManyWrongShares
+		}
+	}
+
+	var wrong []int
+	for _, share := range shares {
+		if !f.Equal(hornerAt(f, p, ss.point(share.X)), share.Y) {
+			wrong = append(wrong, share.X)
+		}
+	}
+	if len(wrong) > e {
+		return f.Zero(), nil, ErrTooManyWrongShares
+	}
+	sort.Ints(wrong)
+	return p[0], wrong, nil
+}
+
+//solve finds a solution of the linear system rows * x = rhs by Gaussian elimination, setting free variables to zero.
+//It modifies rows and rhs, and reports false if there is no solution.
+func solve[E any](f field.Field[E], rows [][]E, rhs []E) ([]E, bool) {
+	columns := len(rows[0])
+	pivotColumns := make([]int, 0, columns)
+	rank := 0
+	for column := 0; column < columns && rank < len(rows); column++ {
+		pivot := -1
+		for row := rank; row < len(rows); row++ {
+			if !f.IsZero(rows[row][column]) {
+				pivot = row
+				break
+			}
+		}
+		if pivot < 0 {
+			continue
+		}
+		rows[rank], rows[pivot] = rows[pivot], rows[rank]
+		rhs[rank], rhs[pivot] = rhs[pivot], rhs[rank]
+
+		inverse := f.Inverse(rows[rank][column])
+		for j := column; j < columns; j++ {
+			rows[rank][j] = f.Mul(rows[rank][j], inverse)
+		}
+		rhs[rank] = f.Mul(rhs[rank], inverse)
+		for row := range rows {
+			if row == rank || f.IsZero(rows[row][column]) {
+				continue
+			}
+			factor := rows[row][column]
+			for j := column; j < columns; j++ {
+				rows[row][j] = f.Sub(rows[row][j], f.Mul(factor, rows[rank][j]))
+			}
+			rhs[row] = f.Sub(rhs[row], f.Mul(factor, rhs[rank]))
+		}
+		pivotColumns = append(pivotColumns, column)
+		rank++
+	}
+	//Rows without a pivot are zero, so their right hand side must be too
+	for row := rank; row < len(rows); row++ {
+		if !f.IsZero(rhs[row]) {
+			return nil, false
+		}
+	}
+
+	solution := make([]E, columns)
+	for j := range solution {
+		solution[j] = f.Zero()
+	}
+	for row, column := range pivotColumns {
+		solution[column] = rhs[row]
+	}
+	return solution, true
+}
+
+//divide divides a by the monic polynomial b, returning the quotient and the remainder
+func divide[E any](f field.Field[E], a, b polynomial[E]) (quotient, remainder polynomial[E]) {
+	degree := len(b) - 1
+	remainder = make(polynomial[E], len(a))
+	copy(remainder, a)
+	if len(a) <= degree {
+		return polynomial[E]{f.Zero()}, remainder
+	}
+	quotient = make(polynomial[E], len(a)-degree)
+	for i := len(a) - 1; i >= degree; i-- {
+		coefficient := remainder[i]
+		quotient[i-degree] = coefficient
+		for j := 0; j <= degree; j++ {
+			remainder[i-degree+j] = f.Sub(remainder[i-degree+j], f.Mul(coefficient, b[j]))
+		}
+	}
+	return quotient, remainder[:degree]
+}
@@ -0,0 +1,96 @@
+package bigshamir
+
+import (
+	"errors"
+	"math/big"
+	mathrand "math/rand"
+	"reflect"
+	"sort"
+	"testing"
+
+	"../field"
+)
+
+//corrupt adds a nonzero value to the shares at count random positions and returns the parties of those shares in increasing order
+func corrupt(f *field.Mont64, shares []SecretShare[field.Mont64Element], count int, random *mathrand.Rand) []int {
+	var wrong []int
+	for _, i := range random.Perm(len(shares))[:count] {
+		shares[i].Y = f.Add(shares[i].Y, f.FromInt(1+random.Int63n(4000)))
+		wrong = append(wrong, shares[i].X)
+	}
+	sort.Ints(wrong)
+	return wrong
+}
+
+func TestRobustReconstruct(t *testing.T) {
+	f, _ := field.NewMont64(4001)
+	random := mathrand.New(mathrand.NewSource(1))
+	for n := 1; n <= 12; n++ {
+		for threshold := 0; 2*threshold+1 <= n; threshold++ {
+			setting := NewScheme(f, threshold, n)
+			for wrongCount := 0; wrongCount <= setting.MaxWrongShares(n); wrongCount++ {
+				secret := f.Random()
+				shares := setting.Share(secret)
+				wrong := corrupt(f, shares, wrongCount, random)
+				reconstructed, inconsistent, err := setting.RobustReconstruct(shares)
+				if err != nil || !f.Equal(reconstructed, secret) || !reflect.DeepEqual(inconsistent, wrong) {
+					t.Errorf("n = %d, t = %d: reconstructed %s with wrong shares of %v, %v, expected %s with wrong shares of %v",
+						n, threshold, f.String(reconstructed), inconsistent, err, f.String(secret), wrong)
+				}
+			}
+		}
+	}
+}
+
+func TestRobustReconstructFromSomeShares(t *testing.T) {
+	setting := NewSS(big.NewInt(4001), 2, 10)
+	shares := setting.Share(big.NewInt(1234))
+	//7 of the shares correct up to (7-2-1)/2 = 2 wrong ones
+	shares = shares[3:]
+	shares[1].Y = new(big.Int).Add(shares[1].Y, big.NewInt(1))
+	shares[1].Y.Mod(shares[1].Y, big.NewInt(4001))
+	shares[6].Y = new(big.Int).Add(shares[6].Y, big.NewInt(1))
+	shares[6].Y.Mod(shares[6].Y, big.NewInt(4001))
+	reconstructed, inconsistent, err := setting.RobustReconstruct(shares)
+	if err != nil || reconstructed.Cmp(big.NewInt(1234)) != 0 || !reflect.DeepEqual(inconsistent, []int{5, 10}) {
+		t.Errorf("Reconstructed %d with wrong shares of %v, %v", reconstructed, inconsistent, err)
+	}
+}
+
+func TestRobustReconstructDetectsTooManyWrongShares(t *testing.T) {
+	f, _ := field.NewMont64(4001)
+	random := mathrand.New(mathrand.NewSource(2))
+	setting := NewScheme(f, 2, 8)
+	//Other sharings differ in at least n-t = 6 shares. With 3 wrong shares the nearest is 3 away,
+	//more than the 2 wrong shares that can be corrected, so the wrong shares are always detected.
+	for i := 0; i < 100; i++ {
+		shares := setting.Share(f.Random())
+		corrupt(f, shares, setting.MaxWrongShares(8)+1, random)
+		if _, _, err := setting.RobustReconstruct(shares); !errors.Is(err, ErrTooManyWrongShares) {
+			t.Fatalf("Reconstructed with %d wrong shares: %v", setting.MaxWrongShares(8)+1, err)
+		}
+	}
+}
+
+func TestRobustReconstructRejectsInvalidShares(t *testing.T) {
+	setting := NewSS(big.NewInt(11), 1, 3)
+	shares := setting.Share(big.NewInt(7))
+	if _, _, err := setting.RobustReconstruct(shares[:1]); err == nil {
+		t.Error("Reconstructed from too few shares")
+	}
+	if _, _, err := setting.RobustReconstruct([]SecretShare[*big.Int]{shares[0], shares[0], shares[1]}); err == nil {
+		t.Error("Reconstructed from two shares of the same party")
+	}
+}
+
+func TestDivide(t *testing.T) {
+	f := field.NewBig(big.NewInt(11))
+	//(x^2 + 3x + 5) = (x + 1)(x + 2) + 3
+	quotient, remainder := divide[*big.Int](f,
+		polynomial[*big.Int]{big.NewInt(5), big.NewInt(3), big.NewInt(1)},
+		polynomial[*big.Int]{big.NewInt(1), big.NewInt(1)})
+	if !reflect.DeepEqual(quotient, polynomial[*big.Int]{big.NewInt(2), big.NewInt(1)}) ||
+		!reflect.DeepEqual(remainder, polynomial[*big.Int]{big.NewInt(3)}) {
+		t.Errorf("Divided to %v remainder %v", quotient, remainder)
+	}
+}
@@ -0,0 +1,71 @@
+package bigshamir
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+//maxCachedVectors bounds the recombination vectors a scheme keeps.
+//Parties mostly reconstruct from the same few sets of parties, but robust openings and timeouts may see many.
+const maxCachedVectors = 256
+
+//coefficientCache holds the recombination vectors of the sets of parties a scheme has seen.
+//It is shared by copies of the scheme and safe for concurrent use.
+type coefficientCache[E any] struct {
+	lock    sync.Mutex
+	vectors map[string]map[int]E
+}
+
+func newCoefficientCache[E any]() *coefficientCache[E] {
+	return &coefficientCache[E]{vectors: make(map[string]map[int]E)}
+}
+
+//recombinationVector are the Lagrange coefficients delta_i(0) for the parties xs, computed once per set of parties.
+//The returned map is shared and must not be modified.
+func (ss *SecretSharingScheme[E]) recombinationVector(xs []int) map[int]E {
+	if ss.cache == nil {
+		return recombinationVectorAt(ss.field, xs, ss.pointsOf(xs))
+	}
+	key := partiesKey(xs)
+	ss.cache.lock.Lock()
+	r, exists := ss.cache.vectors[key]
+	ss.cache.lock.Unlock()
+	if exists {
+		return r
+	}
+
+	r = recombinationVectorAt(ss.field, xs, ss.pointsOf(xs))
+	ss.cache.lock.Lock()
+	if len(ss.cache.vectors) >= maxCachedVectors {
+		//Start over rather than track usage, sets of parties that are still in use are cached again on their next use
+		ss.cache.vectors = make(map[string]map[int]E)
+	}
+	ss.cache.vectors[key] = r
+	ss.cache.lock.Unlock()
+	return r
+}
+
+//partiesKey identifies a set of parties regardless of their order
+func partiesKey(xs []int) string {
+	sorted := append([]int(nil), xs...)
+	sort.Ints(sorted)
+	var b strings.Builder
+	for i, x := range sorted {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(strconv.Itoa(x))
+	}
+	return b.String()
+}
+
+//xsOf are the parties of the shares
+func xsOf[E any](shares []SecretShare[E]) []int {
+	xs := make([]int, len(shares))
+	for i, share := range shares {
+		xs[i] = share.X
+	}
+	return xs
+}
@@ -0,0 +1,36 @@
+package bigshamir
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"../field"
+)
+
+func TestRecombinationVectorIsCached(t *testing.T) {
+	setting := NewSS(big.NewInt(11), 1, 5)
+	r := setting.recombinationVector([]int{3, 4, 5})
+	if !reflect.DeepEqual(r, recombinationVector(setting.field, 3, 4, 5)) {
+		t.Errorf("Cached vector %v differs", r)
+	}
+	//The same set of points in another order, and in a copy of the scheme
+	copied := setting
+	if again := copied.recombinationVector([]int{5, 3, 4}); reflect.ValueOf(again).Pointer() != reflect.ValueOf(r).Pointer() {
+		t.Error("Recomputed the vector of the same points")
+	}
+	if other := setting.recombinationVector([]int{1, 2}); reflect.ValueOf(other).Pointer() == reflect.ValueOf(r).Pointer() {
+		t.Error("Returned the vector of other points")
+	}
+}
+
+func TestCacheIsBounded(t *testing.T) {
+	f, _ := field.NewMont64(4001)
+	setting := NewScheme(f, 1, 2*maxCachedVectors)
+	for i := 1; i < 2*maxCachedVectors; i++ {
+		setting.recombinationVector([]int{i, i + 1})
+	}
+	if cached := len(setting.cache.vectors); cached > maxCachedVectors {
+		t.Errorf("Cached %d vectors", cached)
+	}
+}
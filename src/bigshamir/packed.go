@@ -0,0 +1,216 @@
+package bigshamir
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+
+	"../field"
+)
+
+//PackedSecretSharingScheme hides k secrets in one polynomial of degree t+k-1, at the points 0, -1, ..., -(k-1).
+//Any t shares reveal nothing about the secrets and any t+k shares reconstruct all of them,
+//so a sharing of k secrets costs one share per party instead of k.
+type PackedSecretSharingScheme[E any] struct {
+	field     field.Field[E]
+	threshold int
+	k         int
+	n         int
+}
+
+//NewPackedSS constructs a packed secret sharing scheme of k secrets over the field of integers modulo p
+func NewPackedSS(p *big.Int, threshold, k, n int) PackedSecretSharingScheme[*big.Int] {
+	return NewPackedScheme(field.NewBig(p), threshold, k, n)
+}
+
+//NewPackedScheme constructs a packed secret sharing scheme of k secrets over the field f.
+//The field must have more than n+k elements, so the points of parties and secrets differ.
+func NewPackedScheme[E any](f field.Field[E], threshold, k, n int) PackedSecretSharingScheme[E] {
+	return PackedSecretSharingScheme[E]{field: f, threshold: threshold, k: k, n: n}
+}
+
+//K is the number of secrets in a sharing
+func (ss *PackedSecretSharingScheme[E]) K() int {
+	return ss.k
+}
+
+//Degree of the polynomials of the sharings, t+k-1. Degree+1 shares reconstruct.
+func (ss *PackedSecretSharingScheme[E]) Degree() int {
+	return ss.threshold + ss.k - 1
+}
+
+//secretPoints are the points 0, -1, ..., -(k-1) where the secrets are
+func (ss *PackedSecretSharingScheme[E]) secretPoints() []int {
+	points := make([]int, ss.k)
+	for m := range points {
+		points[m] = -m
+	}
+	return points
+}
+
+//Share hides the k secrets in one sharing.
+//The polynomial takes the secrets at their points and random values at the points of parties 1 to t.
+func (ss *PackedSecretSharingScheme[E]) Share(secrets []E) []SecretShare[E] {
+	if len(secrets) != ss.k {
+		panic("bigshamir: " + strconv.Itoa(len(secrets)) + " secrets for a packed sharing of " + strconv.Itoa(ss.k))
+	}
+	f := ss.field
+	xs := ss.secretPoints()
+	ys := append([]E(nil), secrets...)
+	for i := 1; i <= ss.threshold; i++ {
+		xs = append(xs, i)
+		ys = append(ys, f.Random())
+	}
+
+	shares := make([]SecretShare[E], ss.n)
+	for i := 1; i <= ss.n; i++ {
+		shares[i-1] = SecretShare[E]{X: i, Y: interpolateAt(f, xs, ys, i)}
+	}
+	return shares
+}
+
+//Reconstruct extracts the k secrets from the first t+k shares
+func (ss *PackedSecretSharingScheme[E]) Reconstruct(shares []SecretShare[E]) ([]E, error) {
+	if len(shares) < ss.Degree()+1 {
+		return nil, fmt.Errorf("bigshamir: %d shares cannot reconstruct a packed sharing of degree %d", len(shares), ss.Degree())
+	}
+	xs := make([]int, ss.Degree()+1)
+	ys := make([]E, ss.Degree()+1)
+	for i := range xs {
+		xs[i], ys[i] = shares[i].X, shares[i].Y
+	}
+	for i := range xs {
+		for j := i + 1; j < len(xs); j++ {
+			if xs[i] == xs[j] {
+				return nil, fmt.Errorf("bigshamir: two shares of party %d", xs[i])
+			}
+		}
+	}
+
+	secrets := make([]E, ss.k)
+	for m, point := range ss.secretPoints() {
+		secrets[m] = interpolateAt(ss.field, xs, ys, point)
+	}
+	return secrets, nil
+}
+
+//RobustReconstruct extracts the k secrets from shares of which up to (len(shares)-t-k)/2 may be wrong,
+//and returns the parties whose shares were wrong like SecretSharingScheme.RobustReconstruct
+func (ss *PackedSecretSharingScheme[E]) RobustReconstruct(shares []SecretShare[E]) ([]E, []int, error) {
+	//The sharing is a codeword of degree t+k-1 like a sharing of a single secret of that threshold
+	decoder := NewScheme(ss.field, ss.Degree(), ss.n)
+	_, wrong, err := decoder.RobustReconstruct(shares)
+	if err != nil {
+		return nil, nil, err
+	}
+	correct := make([]SecretShare[E], 0, len(shares))
+	for _, share := range shares {
+		if !containsParty(wrong, share.X) {
+			correct = append(correct, share)
+		}
+	}
+	secrets, err := ss.Reconstruct(correct)
+	return secrets, wrong, err
+}
+
+func containsParty(parties []int, party int) bool {
+	for _, p := range parties {
+		if p == party {
+			return true
+		}
+	}
+	return false
+}
+
+//Add creates a packed sharing of the element-wise sum of two packed sharings.
+//Slices should have the same parties in the same order.
+func (ss *PackedSecretSharingScheme[E]) Add(aShares, bShares []SecretShare[E]) []SecretShare[E] {
+	aPlusBShares := make([]SecretShare[E], len(aShares))
+	for i := range aShares {
+		aPlusBShares[i] = SecretShare[E]{X: aShares[i].X, Y: ss.field.Add(aShares[i].Y, bShares[i].Y)}
+	}
+	return aPlusBShares
+}
+
+//Multipliers is the number of parties that reshare their local products in a multiplication, 2(t+k-1)+1.
+//Parties 1 to Multipliers do, and there must be at least as many parties.
+func (ss *PackedSecretSharingScheme[E]) Multipliers() int {
+	return 2*ss.Degree() + 1
+}
+
+//ReshareProduct is the degree reduction of party index in a multiplication, which must be one of the Multipliers.
+//The product of the shares of the party lies on a polynomial h of degree 2(t+k-1), and the secret products
+//are h(-m) = sum_j L_mj h(j) over the multipliers j. The party shares its terms L_mj h(j) of all k products,
+//so the sum of the sharings of all multipliers is a sharing of degree t+k-1 of the products.
+func (ss *PackedSecretSharingScheme[E]) ReshareProduct(index int, product E) []SecretShare[E] {
+	multipliers := make([]int, ss.Multipliers())
+	for j := range multipliers {
+		multipliers[j] = j + 1
+	}
+	terms := make([]E, ss.k)
+	for m, point := range ss.secretPoints() {
+		terms[m] = ss.field.Mul(lagrangeAt(ss.field, multipliers, point)[index-1], product)
+	}
+	return ss.Share(terms)
+}
+
+//RecombineMultiplicationShares sums the shares from ReshareProduct of all multipliers to the share of the product
+func (ss *PackedSecretSharingScheme[E]) RecombineMultiplicationShares(shares []RecombinationShare[E]) E {
+	sum := ss.field.Zero()
+	for _, share := range shares {
+		sum = ss.field.Add(sum, share.SecretShare.Y)
+	}
+	return sum
+}
+
+//Mul creates a packed sharing of the element-wise product of two packed sharings, running the protocol for all parties.
+//Slices must hold the shares of all n parties in the same order, and there must be Multipliers parties or more.
+func (ss *PackedSecretSharingScheme[E]) Mul(aShares, bShares []SecretShare[E]) []SecretShare[E] {
+	if len(aShares) != ss.n || len(bShares) != ss.n {
+		panic("bigshamir: missing shares")
+	}
+	if ss.Multipliers() > ss.n {
+		panic("bigshamir: " + strconv.Itoa(ss.n) + " parties cannot multiply packed sharings of degree " + strconv.Itoa(ss.Degree()))
+	}
+
+	partyLocalShares := make([][]RecombinationShare[E], ss.n)
+	for party := 0; party < ss.Multipliers(); party++ {
+		product := ss.field.Mul(aShares[party].Y, bShares[party].Y)
+		for i, newShare := range ss.ReshareProduct(aShares[party].X, product) {
+			partyLocalShares[i] = append(partyLocalShares[i], RecombinationShare[E]{SecretShare: newShare, Index: aShares[party].X})
+		}
+	}
+
+	shares := make([]SecretShare[E], ss.n)
+	for party := range shares {
+		shares[party] = SecretShare[E]{X: party + 1, Y: ss.RecombineMultiplicationShares(partyLocalShares[party])}
+	}
+	return shares
+}
+
+//lagrangeAt are the Lagrange basis polynomials of the points xs at x, L_j(x) = prod_i (x - x_i) / (x_j - x_i)
+func lagrangeAt[E any](f field.Field[E], xs []int, x int) []E {
+	basis := make([]E, len(xs))
+	for j, xj := range xs {
+		num := f.One()
+		den := f.One()
+		for i, xi := range xs {
+			if i == j {
+				continue
+			}
+			num = f.Mul(num, f.FromInt(int64(x-xi)))
+			den = f.Mul(den, f.FromInt(int64(xj-xi)))
+		}
+		basis[j] = f.Mul(num, f.Inverse(den))
+	}
+	return basis
+}
+
+//interpolateAt evaluates the polynomial through the points (xs, ys) at x
+func interpolateAt[E any](f field.Field[E], xs []int, ys []E, x int) E {
+	sum := f.Zero()
+	for j, l := range lagrangeAt(f, xs, x) {
+		sum = f.Add(sum, f.Mul(l, ys[j]))
+	}
+	return sum
+}
@@ -0,0 +1,83 @@
+package bigshamir
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"../field"
+)
+
+func elements(f field.Field[field.Mont64Element], values ...int64) []field.Mont64Element {
+	elements := make([]field.Mont64Element, len(values))
+	for i, value := range values {
+		elements[i] = f.FromInt(value)
+	}
+	return elements
+}
+
+func TestPackedShareReconstruct(t *testing.T) {
+	f, _ := field.NewMont64(4001)
+	setting := NewPackedScheme(f, 2, 3, 9)
+	secrets := elements(f, 12, 34, 4000)
+	shares := setting.Share(secrets)
+	//Any t+k shares reconstruct all secrets
+	for _, subset := range [][]SecretShare[field.Mont64Element]{shares[:5], shares[4:], {shares[8], shares[0], shares[6], shares[2], shares[4]}} {
+		reconstructed, err := setting.Reconstruct(subset)
+		if err != nil || !reflect.DeepEqual(reconstructed, secrets) {
+			t.Errorf("Reconstructed %v, %v from %v", reconstructed, err, subset)
+		}
+	}
+	if _, err := setting.Reconstruct(shares[:4]); err == nil {
+		t.Error("Reconstructed from t+k-1 shares")
+	}
+}
+
+func TestPackedAddMul(t *testing.T) {
+	f, _ := field.NewMont64(4001)
+	//Multiplication needs 2(t+k-1)+1 = 7 parties
+	setting := NewPackedScheme(f, 1, 3, 7)
+	aShares, bShares := setting.Share(elements(f, 1, 2, 3)), setting.Share(elements(f, 10, 20, 4000))
+	sum, err := setting.Reconstruct(setting.Add(aShares, bShares))
+	if err != nil || !reflect.DeepEqual(sum, elements(f, 11, 22, 2)) {
+		t.Errorf("Added to %v, %v", sum, err)
+	}
+	product := setting.Mul(aShares, bShares)
+	reconstructed, err := setting.Reconstruct(product)
+	if err != nil || !reflect.DeepEqual(reconstructed, elements(f, 10, 40, 3998)) {
+		t.Errorf("Multiplied to %v, %v", reconstructed, err)
+	}
+	//The product has degree t+k-1 again, so it can be multiplied again
+	square, err := setting.Reconstruct(setting.Mul(product, product))
+	if err != nil || !reflect.DeepEqual(square, elements(f, 100, 1600, 9)) {
+		t.Errorf("Multiplied product to %v, %v", square, err)
+	}
+}
+
+func TestPackedRobustReconstruct(t *testing.T) {
+	f, _ := field.NewMont64(4001)
+	setting := NewPackedScheme(f, 1, 2, 6)
+	secrets := elements(f, 5, 6)
+	shares := setting.Share(secrets)
+	//6 shares of degree 2 correct (6-2-1)/2 = 1 wrong share
+	shares[3].Y = f.Add(shares[3].Y, f.One())
+	reconstructed, wrong, err := setting.RobustReconstruct(shares)
+	if err != nil || !reflect.DeepEqual(reconstructed, secrets) || !reflect.DeepEqual(wrong, []int{4}) {
+		t.Errorf("Reconstructed %v with wrong shares of %v, %v", reconstructed, wrong, err)
+	}
+	shares[4].Y = f.Add(shares[4].Y, f.One())
+	if _, _, err := setting.RobustReconstruct(shares); !errors.Is(err, ErrTooManyWrongShares) {
+		t.Errorf("Reconstructed with 2 wrong shares: %v", err)
+	}
+}
+
+func TestPackedShareOfWrongLength(t *testing.T) {
+	f, _ := field.NewMont64(4001)
+	setting := NewPackedScheme(f, 1, 3, 7)
+	defer func() {
+		if recover() == nil {
+			t.Error("Shared 2 secrets in a packed sharing of 3")
+		}
+	}()
+	setting.Share(elements(f, 1, 2))
+}
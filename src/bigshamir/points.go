@@ -0,0 +1,87 @@
+package bigshamir
+
+import (
+	"fmt"
+	"sort"
+
+	"../field"
+)
+
+//NewSchemeAt constructs a secret sharing scheme over f for the parties of points, in which party i holds the polynomial at points[i].
+//Party indices need not be 1 to n, and points can be chosen freely, e.g. as roots of unity.
+//Party indices must be positive, and points distinct and nonzero, since the secret is the polynomial at 0.
+func NewSchemeAt[E any](f field.Field[E], threshold int, points map[int]E) (SecretSharingScheme[E], error) {
+	if threshold < 0 || threshold >= len(points) {
+		return SecretSharingScheme[E]{}, fmt.Errorf("bigshamir: threshold %d for %d parties", threshold, len(points))
+	}
+	parties := make([]int, 0, len(points))
+	owners := make(map[string]int, len(points))
+	for party, point := range points {
+		if party < 1 {
+			return SecretSharingScheme[E]{}, fmt.Errorf("bigshamir: party index %d is not positive", party)
+		}
+		if f.IsZero(point) {
+			return SecretSharingScheme[E]{}, fmt.Errorf("bigshamir: party %d holds the point 0 of the secret", party)
+		}
+		if other, exists := owners[f.String(point)]; exists {
+			return SecretSharingScheme[E]{}, fmt.Errorf("bigshamir: parties %d and %d hold the same point %s", other, party, f.String(point))
+		}
+		owners[f.String(point)] = party
+		parties = append(parties, party)
+	}
+	sort.Ints(parties)
+
+	copied := make(map[int]E, len(points))
+	for party, point := range points {
+		copied[party] = point
+	}
+	return SecretSharingScheme[E]{
+		field:     f,
+		threshold: threshold,
+		n:         len(points),
+		parties:   parties,
+		points:    copied,
+		cache:     newCoefficientCache[E](),
+	}, nil
+}
+
+//Parties are the indices of the parties in increasing order, which is the order of the shares of Share
+func (ss *SecretSharingScheme[E]) Parties() []int {
+	return append([]int(nil), ss.parties...)
+}
+
+//point is the evaluation point of party. It panics if the scheme has points and party is not one of its parties.
+func (ss *SecretSharingScheme[E]) point(party int) E {
+	if ss.points == nil {
+		return ss.field.FromInt(int64(party))
+	}
+	point, exists := ss.points[party]
+	if !exists {
+		panic(fmt.Sprintf("bigshamir: no party %d", party))
+	}
+	return point
+}
+
+//pointsOf are the evaluation points of the parties xs
+func (ss *SecretSharingScheme[E]) pointsOf(xs []int) []E {
+	points := make([]E, len(xs))
+	for k, x := range xs {
+		points[k] = ss.point(x)
+	}
+	return points
+}
+
+//checkParties checks that the shares are of distinct parties. If the scheme has points, they must be parties of the scheme.
+func (ss *SecretSharingScheme[E]) checkParties(xs []int) error {
+	seen := make(map[int]bool, len(xs))
+	for _, x := range xs {
+		if _, exists := ss.points[x]; ss.points != nil && !exists {
+			return fmt.Errorf("bigshamir: no party %d", x)
+		}
+		if seen[x] {
+			return fmt.Errorf("bigshamir: two shares of party %d", x)
+		}
+		seen[x] = true
+	}
+	return nil
+}
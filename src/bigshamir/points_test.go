@@ -0,0 +1,106 @@
+package bigshamir
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"../field"
+)
+
+func TestSchemeAt(t *testing.T) {
+	f, _ := field.NewMont64(4001)
+	points := map[int]field.Mont64Element{3: f.FromInt(7), 7: f.FromInt(2), 10: f.FromInt(100), 11: f.FromInt(4000), 20: f.FromInt(5), 42: f.FromInt(1234)}
+	setting, err := NewSchemeAt(f, 2, points)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parties := setting.Parties(); !reflect.DeepEqual(parties, []int{3, 7, 10, 11, 20, 42}) {
+		t.Fatalf("Parties %v", parties)
+	}
+	a, b := f.FromInt(1234), f.FromInt(-5)
+	aShares, bShares := setting.Share(a), setting.Share(b)
+	for i, share := range aShares {
+		if share.X != setting.Parties()[i] {
+			t.Errorf("Share %d is of party %d", i, share.X)
+		}
+	}
+	check := func(operation string, shares []SecretShare[field.Mont64Element], expected field.Mont64Element) {
+		t.Helper()
+		//Any t+1 shares in any order
+		subset := []SecretShare[field.Mont64Element]{shares[5], shares[1], shares[3]}
+		if reconstructed, err := setting.Reconstruct(subset); err != nil || !f.Equal(reconstructed, expected) {
+			t.Errorf("%s reconstructed to %s, not %s", operation, f.String(reconstructed), f.String(expected))
+		}
+	}
+	check("Share", aShares, a)
+	check("Add", setting.Add(aShares, bShares), f.Add(a, b))
+	check("Scale", setting.Scale(f.FromInt(3), aShares), f.Mul(f.FromInt(3), a))
+	check("Mul", setting.Mul(aShares, bShares), f.Mul(a, b))
+
+	aShares[2].Y = f.Add(aShares[2].Y, f.One())
+	reconstructed, wrong, err := setting.RobustReconstruct(aShares)
+	if err != nil || !f.Equal(reconstructed, a) || !reflect.DeepEqual(wrong, []int{10}) {
+		t.Errorf("Robustly reconstructed %s with wrong shares of %v, %v", f.String(reconstructed), wrong, err)
+	}
+
+	secrets := elements(f, 1, 2, 3)
+	many, err := setting.ReconstructMany(setting.ShareMany(secrets))
+	if err != nil || !reflect.DeepEqual(many, secrets) {
+		t.Errorf("Reconstructed %v, %v", many, err)
+	}
+
+	s := setting.ShareBivariate(a)
+	rows := make([]SecretShare[field.Mont64Element], 0, 3)
+	for _, i := range []int{42, 3, 11} {
+		if !f.Equal(s.At(7, i), s.At(i, 7)) {
+			t.Errorf("S(7, %d) differs from S(%d, 7)", i, i)
+		}
+		rows = append(rows, SecretShare[field.Mont64Element]{X: i, Y: s.At(0, i)})
+	}
+	if reconstructed, err := setting.Reconstruct(rows); err != nil || !f.Equal(reconstructed, a) {
+		t.Errorf("Reconstructed %s from rows at 0", f.String(reconstructed))
+	}
+}
+
+func TestSchemeAtRejectsSharesOfOtherParties(t *testing.T) {
+	f, _ := field.NewMont64(4001)
+	setting, _ := NewSchemeAt(f, 1, map[int]field.Mont64Element{1: f.FromInt(10), 2: f.FromInt(20), 3: f.FromInt(30)})
+	shares := setting.Share(f.FromInt(5))
+	shares[0].X = 4
+	if _, _, err := setting.RobustReconstruct(shares); err == nil {
+		t.Error("Robustly reconstructed from a share of party 4")
+	}
+	if _, err := setting.ReconstructMany([][]SecretShare[field.Mont64Element]{shares}); err == nil {
+		t.Error("Reconstructed from a share of party 4")
+	}
+	if _, err := setting.Reconstruct(shares); err == nil {
+		t.Error("Reconstructed a single sharing from a share of party 4")
+	}
+}
+
+func TestNewSchemeAtRejectsInvalidPoints(t *testing.T) {
+	f, _ := field.NewMont64(4001)
+	for _, points := range []map[int]field.Mont64Element{
+		{1: f.FromInt(1), 2: f.FromInt(0), 3: f.FromInt(3)},
+		{1: f.FromInt(1), 2: f.FromInt(4002), 3: f.FromInt(3)},
+		{0: f.FromInt(1), 2: f.FromInt(2), 3: f.FromInt(3)},
+		{1: f.FromInt(1)},
+	} {
+		if _, err := NewSchemeAt(f, 1, points); err == nil {
+			t.Errorf("Constructed a scheme of threshold 1 at %v", points)
+		}
+	}
+}
+
+func TestScaleSomeShares(t *testing.T) {
+	setting := NewSS(big.NewInt(11), 1, 3)
+	shares := setting.Share(big.NewInt(7))
+	scaled := setting.Scale(big.NewInt(2), shares[1:])
+	if len(scaled) != 2 || scaled[0].X != 2 || scaled[1].X != 3 {
+		t.Fatalf("Scaled shares of parties 2 and 3 to %v", scaled)
+	}
+	if reconstructed, err := setting.Reconstruct(scaled); err != nil || reconstructed.Cmp(big.NewInt(3)) != 0 {
+		t.Errorf("Reconstructed %d, not 2 * 7 mod 11 = 3", reconstructed)
+	}
+}
@@ -0,0 +1,290 @@
+package cluster
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//Party describes a party of the cluster. Paths are relative to the working directory of the party.
+type Party struct {
+	Index int `json:"index"`
+	//Address is where the party listens for connections from its peers, as host:port
+	Address string `json:"address,omitempty"`
+	//Certificate is the path of the PEM encoded TLS certificate of the party
+	Certificate string `json:"certificate,omitempty"`
+	//Key is the path of the PEM encoded TLS key of the party, only needed by the party itself
+	Key string `json:"key,omitempty"`
+	//PublicKey is the path of the hex encoded X25519 key of the party for relayed connections
+	PublicKey string `json:"publicKey,omitempty"`
+	//PrivateKey is the path of the matching private key, only needed by the party itself
+	PrivateKey string `json:"privateKey,omitempty"`
+}
+
+//Number is an integer of any size. In JSON it is written as a number, or as a string in decimal or, prefixed by 0x, in hex.
+type Number struct {
+	*big.Int
+}
+
+//UnmarshalJSON ...
+func (n *Number) UnmarshalJSON(b []byte) error {
+	text := string(b)
+	if unquoted, err := strconv.Unquote(text); err == nil {
+		text = unquoted
+	}
+	value, ok := new(big.Int).SetString(text, 0)
+	if !ok {
+		return fmt.Errorf("%s is not an integer", b)
+	}
+	n.Int = value
+	return nil
+}
+
+//MarshalJSON writes the number in decimal
+func (n Number) MarshalJSON() ([]byte, error) {
+	if n.Int == nil {
+		return []byte("null"), nil
+	}
+	return []byte(n.Int.String()), nil
+}
+
+//Config describes the parties of a computation and the parameters of the secret sharing scheme.
+//Every party loads the same config, and parties check that they agree on it by comparing digests.
+type Config struct {
+	Prime     Number `json:"prime"`
+	Threshold int    `json:"threshold"`
+	//CA is the path of the PEM encoded certificate of the authority that issued the certificates of the parties.
+	//If it is set, parties connect over mutually authenticated TLS.
+	CA string `json:"ca,omitempty"`
+	//Relay is the address of a relay. If it is set, parties connect through it instead of directly.
+	Relay string `json:"relay,omitempty"`
+	//Timeout bounds how long a party waits for shares from the others, as a duration like "30s".
+	//If it is not set parties wait forever.
+	Timeout string `json:"timeout,omitempty"`
+	//Robust makes parties open values from the shares of all parties, correcting up to (n-t-1)/2 wrong ones
+	Robust bool `json:"robust,omitempty"`
+	//Verifiable makes parties share inputs with verifiable secret sharing, which needs a threshold below n/3
+	Verifiable bool `json:"verifiable,omitempty"`
+	//Batch makes parties send the messages to each party in batches
+	Batch bool `json:"batch,omitempty"`
+	//ReliableBroadcast makes parties broadcast with Bracha's reliable broadcast, which needs a threshold below n/3
+	ReliableBroadcast bool    `json:"reliableBroadcast,omitempty"`
+	Parties           []Party `json:"parties"`
+}
+
+//Load reads and validates the config at path
+func Load(path string) (Config, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return Config{}, err
+	}
+	defer file.Close()
+	c, err := Read(file)
+	if err != nil {
+		return c, fmt.Errorf("%s: %w", path, err)
+	}
+	return c, nil
+}
+
+//Read reads and validates a config. Unknown fields are errors, so misspelled settings are not ignored.
+func Read(r io.Reader) (Config, error) {
+	var c Config
+	decoder := json.NewDecoder(r)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&c); err != nil {
+		return c, fmt.Errorf("cluster: %w", err)
+	}
+	if decoder.More() {
+		return c, errors.New("cluster: data after the config")
+	}
+	return c, c.Validate()
+}
+
+//N is the number of parties
+func (c Config) N() int {
+	return len(c.Parties)
+}
+
+//Validate checks that the config describes a secret sharing scheme the parties can run:
+//parties are numbered 1 to n, the prime is a prime larger than n, and 2t+1 <= n, or 3t+1 <= n for verifiable input.
+//If parties connect through a relay or over TLS, every party must have the keys to do so.
+func (c Config) Validate() error {
+	n := c.N()
+	if n == 0 {
+		return errors.New("cluster: no parties")
+	}
+	seen := make(map[int]bool, n)
+	addresses := make(map[string]int, n)
+	for _, party := range c.Parties {
+		if party.Index < 1 || party.Index > n {
+			return fmt.Errorf("cluster: party index %d is not between 1 and the number of parties %d", party.Index, n)
+		}
+		if seen[party.Index] {
+			return fmt.Errorf("cluster: party index %d is listed more than once", party.Index)
+		}
+		seen[party.Index] = true
+
+		if party.Address != "" {
+			if other, exists := addresses[party.Address]; exists {
+				return fmt.Errorf("cluster: parties %d and %d have the same address %s", other, party.Index, party.Address)
+			}
+			addresses[party.Address] = party.Index
+		}
+		if c.CA != "" && party.Certificate == "" {
+			return fmt.Errorf("cluster: party %d has no certificate", party.Index)
+		}
+		if c.Relay != "" && party.PublicKey == "" {
+			return fmt.Errorf("cluster: party %d has no public key", party.Index)
+		}
+	}
+
+	if c.Prime.Int == nil {
+		return errors.New("cluster: no prime")
+	}
+	if c.Prime.Sign() <= 0 || !c.Prime.ProbablyPrime(20) {
+		return fmt.Errorf("cluster: %s is not a prime", c.Prime)
+	}
+	if c.Prime.Cmp(big.NewInt(int64(n))) <= 0 {
+		return fmt.Errorf("cluster: prime %s must be larger than the number of parties %d", c.Prime, n)
+	}
+	if c.Threshold < 0 {
+		return fmt.Errorf("cluster: negative threshold %d", c.Threshold)
+	}
+	if 2*c.Threshold+1 > n {
+		return fmt.Errorf("cluster: threshold %d needs at least %d parties, there are %d", c.Threshold, 2*c.Threshold+1, n)
+	}
+	if c.Verifiable && 3*c.Threshold >= n {
+		return fmt.Errorf("cluster: verifiable input with threshold %d needs at least %d parties, there are %d", c.Threshold, 3*c.Threshold+1, n)
+	}
+	if c.ReliableBroadcast && 3*c.Threshold >= n {
+		return fmt.Errorf("cluster: reliable broadcast with threshold %d needs at least %d parties, there are %d", c.Threshold, 3*c.Threshold+1, n)
+	}
+	if c.Timeout != "" {
+		timeout, err := time.ParseDuration(c.Timeout)
+		if err != nil {
+			return fmt.Errorf("cluster: timeout: %w", err)
+		}
+		if timeout <= 0 {
+			return fmt.Errorf("cluster: timeout %s is not positive", c.Timeout)
+		}
+	}
+	return nil
+}
+
+//WaitTimeout is the timeout of the config, or zero if parties wait forever
+func (c Config) WaitTimeout() time.Duration {
+	//Validated when the config was read
+	timeout, _ := time.ParseDuration(c.Timeout)
+	return timeout
+}
+
+//Party returns the party with the given index
+func (c Config) Party(index int) (Party, bool) {
+	for _, party := range c.Parties {
+		if party.Index == index {
+			return party, true
+		}
+	}
+	return Party{}, false
+}
+
+//CheckParty checks that the party with the given index can connect to the others in its own process
+func (c Config) CheckParty(index int) error {
+	party, exists := c.Party(index)
+	if !exists {
+		return fmt.Errorf("cluster: no party %d", index)
+	}
+	if c.Relay != "" {
+		if party.PrivateKey == "" {
+			return fmt.Errorf("cluster: party %d has no private key", index)
+		}
+		return nil
+	}
+	for _, other := range c.Parties {
+		if other.Address == "" {
+			return fmt.Errorf("cluster: party %d has no address", other.Index)
+		}
+	}
+	if c.CA != "" && party.Key == "" {
+		return fmt.Errorf("cluster: party %d has no TLS key", index)
+	}
+	return nil
+}
+
+//Addresses of the parties by index
+func (c Config) Addresses() map[int]string {
+	return c.collect(func(party Party) string { return party.Address })
+}
+
+//Certificates are the paths of the certificates of the parties by index
+func (c Config) Certificates() map[int]string {
+	return c.collect(func(party Party) string { return party.Certificate })
+}
+
+//PublicKeys are the paths of the public keys of the parties by index
+func (c Config) PublicKeys() map[int]string {
+	return c.collect(func(party Party) string { return party.PublicKey })
+}
+
+func (c Config) collect(field func(party Party) string) map[int]string {
+	values := make(map[int]string, len(c.Parties))
+	for _, party := range c.Parties {
+		if value := field(party); value != "" {
+			values[party.Index] = value
+		}
+	}
+	return values
+}
+
+//Digest identifies everything the parties must agree on, as a hex encoded SHA-256 hash.
+//The certificates and public keys are identified by the contents of their files rather than by their paths,
+//which may differ between parties. Private keys are left out, as they only concern a single party,
+//and so is the order of the parties. It fails if a certificate or public key cannot be read.
+func (c Config) Digest() (string, error) {
+	shared := c
+	var err error
+	if shared.CA, err = fileDigest(c.CA); err != nil {
+		return "", err
+	}
+	shared.Parties = make([]Party, len(c.Parties))
+	for i, party := range c.Parties {
+		party.Key = ""
+		party.PrivateKey = ""
+		if party.Certificate, err = fileDigest(party.Certificate); err != nil {
+			return "", err
+		}
+		if party.PublicKey, err = fileDigest(party.PublicKey); err != nil {
+			return "", err
+		}
+		shared.Parties[i] = party
+	}
+	sort.Slice(shared.Parties, func(i, j int) bool { return shared.Parties[i].Index < shared.Parties[j].Index })
+
+	var b bytes.Buffer
+	//Encoding a config cannot fail
+	json.NewEncoder(&b).Encode(shared)
+	digest := sha256.Sum256(b.Bytes())
+	return hex.EncodeToString(digest[:]), nil
+}
+
+//fileDigest is the hex encoded SHA-256 hash of the file at path without surrounding white space, or empty if path is
+func fileDigest(path string) (string, error) {
+	if path == "" {
+		return "", nil
+	}
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("cluster: %w", err)
+	}
+	digest := sha256.Sum256(bytes.TrimSpace(contents))
+	return hex.EncodeToString(digest[:]), nil
+}
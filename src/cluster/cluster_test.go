@@ -0,0 +1,208 @@
+package cluster
+
+import (
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+const valid = `{
+	"prime": 4001,
+	"threshold": 1,
+	"timeout": "1m30s",
+	"robust": true,
+	"ca": "ca.pem",
+	"parties": [
+		{"index": 1, "address": "127.0.0.1:9001", "certificate": "1.pem", "key": "1.key"},
+		{"index": 2, "address": "127.0.0.1:9002", "certificate": "2.pem"},
+		{"index": 3, "address": "127.0.0.1:9003", "certificate": "3.pem"}
+	]
+}`
+
+func TestRead(t *testing.T) {
+	c, err := Read(strings.NewReader(valid))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.Prime.Cmp(big.NewInt(4001)) != 0 || c.Threshold != 1 || c.N() != 3 || c.WaitTimeout() != 90*time.Second || !c.Robust {
+		t.Errorf("Read %+v", c)
+	}
+	if c.Addresses()[2] != "127.0.0.1:9002" || c.Certificates()[3] != "3.pem" || len(c.PublicKeys()) != 0 {
+		t.Errorf("Read %+v", c)
+	}
+	if err := c.CheckParty(1); err != nil {
+		t.Error(err)
+	}
+	if err := c.CheckParty(2); err == nil {
+		t.Error("Party 2 has no TLS key")
+	}
+	if err := c.CheckParty(4); err == nil {
+		t.Error("There is no party 4")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	invalid := map[string]string{
+		"no parties":         `{"prime": 4001, "threshold": 0, "parties": []}`,
+		"index zero":         `{"prime": 4001, "threshold": 0, "parties": [{"index": 0}]}`,
+		"index too large":    `{"prime": 4001, "threshold": 0, "parties": [{"index": 1}, {"index": 3}]}`,
+		"duplicate index":    `{"prime": 4001, "threshold": 0, "parties": [{"index": 1}, {"index": 1}]}`,
+		"duplicate address":  `{"prime": 4001, "threshold": 0, "parties": [{"index": 1, "address": "a:1"}, {"index": 2, "address": "a:1"}]}`,
+		"composite":          `{"prime": 4000, "threshold": 0, "parties": [{"index": 1}]}`,
+		"negative prime":     `{"prime": -7, "threshold": 0, "parties": [{"index": 1}]}`,
+		"prime too small":    `{"prime": 2, "threshold": 0, "parties": [{"index": 1}, {"index": 2}]}`,
+		"missing prime":      `{"threshold": 0, "parties": [{"index": 1}]}`,
+		"prime not a number": `{"prime": "0xg", "threshold": 0, "parties": [{"index": 1}]}`,
+		"prime as fraction":  `{"prime": 4001.5, "threshold": 0, "parties": [{"index": 1}]}`,
+		"negative threshold": `{"prime": 4001, "threshold": -1, "parties": [{"index": 1}]}`,
+		"threshold too high": `{"prime": 4001, "threshold": 1, "parties": [{"index": 1}, {"index": 2}]}`,
+		"missing certificate": `{"prime": 4001, "threshold": 0, "ca": "ca.pem",
+			"parties": [{"index": 1, "certificate": "1.pem"}, {"index": 2}]}`,
+		"missing public key": `{"prime": 4001, "threshold": 0, "relay": "relay:9000",
+			"parties": [{"index": 1}]}`,
+		"verifiable with threshold too high": `{"prime": 4001, "threshold": 1, "verifiable": true,
+			"parties": [{"index": 1}, {"index": 2}, {"index": 3}]}`,
+		"reliable broadcast with threshold too high": `{"prime": 4001, "threshold": 1, "reliableBroadcast": true,
+			"parties": [{"index": 1}, {"index": 2}, {"index": 3}]}`,
+		"invalid timeout":  `{"prime": 4001, "threshold": 0, "timeout": "soon", "parties": [{"index": 1}]}`,
+		"negative timeout": `{"prime": 4001, "threshold": 0, "timeout": "-1s", "parties": [{"index": 1}]}`,
+		"unknown field":    `{"prime": 4001, "threshold": 0, "treshold": 1, "parties": [{"index": 1}]}`,
+		"trailing data":    `{"prime": 4001, "threshold": 0, "parties": [{"index": 1}]} {}`,
+		"malformed json":   `{"prime": 4001,`,
+	}
+	for name, config := range invalid {
+		if _, err := Read(strings.NewReader(config)); err == nil {
+			t.Errorf("Accepted config with %s", name)
+		}
+	}
+}
+
+func TestLargePrimes(t *testing.T) {
+	mersenne127 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 127), big.NewInt(1))
+	primes := map[string]*big.Int{
+		`170141183460469231731687303715884105727`:   mersenne127,
+		`"170141183460469231731687303715884105727"`: mersenne127,
+		`"0x7fffffffffffffffffffffffffffffff"`:      mersenne127,
+		`"0xfffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f"`: new(big.Int).Sub(
+			new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1<<32+977)),
+	}
+	for prime, expected := range primes {
+		c, err := Read(strings.NewReader(`{"prime": ` + prime + `, "threshold": 0, "parties": [{"index": 1}]}`))
+		if err != nil {
+			t.Errorf("Reading prime %s: %v", prime, err)
+			continue
+		}
+		if c.Prime.Cmp(expected) != 0 {
+			t.Errorf("Read prime %s as %s", prime, c.Prime)
+		}
+	}
+
+	//Parties agree whichever way the prime is written
+	decimal, _ := Read(strings.NewReader(`{"prime": 170141183460469231731687303715884105727, "threshold": 0, "parties": [{"index": 1}]}`))
+	hex, _ := Read(strings.NewReader(`{"prime": "0x7fffffffffffffffffffffffffffffff", "threshold": 0, "parties": [{"index": 1}]}`))
+	decimalDigest, _ := decimal.Digest()
+	hexDigest, _ := hex.Digest()
+	if decimalDigest != hexDigest {
+		t.Error("Digest depends on how the prime is written")
+	}
+}
+
+func TestCheckPartyWithRelay(t *testing.T) {
+	c, err := Read(strings.NewReader(`{"prime": 11, "threshold": 0, "relay": "relay:9000",
+		"parties": [{"index": 1, "publicKey": "1.pub", "privateKey": "1.key"}, {"index": 2, "publicKey": "2.pub"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CheckParty(1); err != nil {
+		t.Errorf("Party 1 connects through the relay without an address: %v", err)
+	}
+	if err := c.CheckParty(2); err == nil {
+		t.Error("Party 2 has no private key")
+	}
+}
+
+func TestCheckPartyWithoutAddress(t *testing.T) {
+	c, err := Read(strings.NewReader(`{"prime": 11, "threshold": 0, "parties": [{"index": 1, "address": "a:1"}, {"index": 2}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.CheckParty(1); err == nil {
+		t.Error("Party 1 cannot reach party 2 without its address")
+	}
+}
+
+//writeFiles writes every file of contents to the working directory of the test
+func writeFiles(t *testing.T, contents map[string]string) {
+	t.Chdir(t.TempDir())
+	for path, content := range contents {
+		if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+//digest is the digest of c, failing the test if it cannot be computed
+func digest(c Config, t *testing.T) string {
+	digest, err := c.Digest()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return digest
+}
+
+func TestDigest(t *testing.T) {
+	writeFiles(t, map[string]string{"ca.pem": "ca", "1.pem": "one", "2.pem": "two", "3.pem": "three"})
+	c, _ := Read(strings.NewReader(valid))
+	other := c
+	other.Parties = []Party{c.Parties[2], c.Parties[1], c.Parties[0]}
+	other.Parties[2].Key = ""
+	other.Parties[0].Key = "3.key"
+	if digest(c, t) != digest(other, t) {
+		t.Error("Digest depends on private keys or order of parties")
+	}
+	if c.Parties[0].Key != "1.key" {
+		t.Error("Digest modified the config")
+	}
+
+	other.Parties[1].Address = "127.0.0.1:9999"
+	if digest(c, t) == digest(other, t) {
+		t.Error("Digest does not depend on addresses")
+	}
+	other = c
+	other.Threshold = 0
+	if digest(c, t) == digest(other, t) {
+		t.Error("Digest does not depend on the threshold")
+	}
+}
+
+func TestDigestOfFiles(t *testing.T) {
+	writeFiles(t, map[string]string{"ca.pem": "ca", "1.pem": "one", "2.pem": "two", "3.pem": "three", "copy.pem": "three\n"})
+	c, _ := Read(strings.NewReader(valid))
+	other := c
+	other.Parties = append([]Party(nil), c.Parties...)
+	other.Parties[2].Certificate = "copy.pem"
+	if digest(c, t) != digest(other, t) {
+		t.Error("Digest depends on the paths of certificates")
+	}
+
+	if err := os.WriteFile("3.pem", []byte("forged"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if digest(c, t) == digest(other, t) {
+		t.Error("Digest does not depend on the contents of certificates")
+	}
+	before := digest(c, t)
+	if err := os.WriteFile("ca.pem", []byte("other ca"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if digest(c, t) == before {
+		t.Error("Digest does not depend on the contents of the CA certificate")
+	}
+
+	other.Parties[1].PublicKey = "missing.pub"
+	if _, err := other.Digest(); err == nil {
+		t.Error("Digest of a config with a missing public key")
+	}
+}
@@ -0,0 +1,145 @@
+package field
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+//Big is a field of any size with elements represented by *big.Int in [0, p).
+//Every operation allocates its result.
+type Big struct {
+	p    *big.Int
+	size int
+}
+
+//NewBig creates the field Z_p. p must be a prime.
+func NewBig(p *big.Int) *Big {
+	return &Big{p: new(big.Int).Set(p), size: (p.BitLen() + 7) / 8}
+}
+
+//Modulus ...
+func (f *Big) Modulus() *big.Int {
+	return new(big.Int).Set(f.p)
+}
+
+//Zero ...
+func (f *Big) Zero() *big.Int {
+	return new(big.Int)
+}
+
+//One ...
+func (f *Big) One() *big.Int {
+	return big.NewInt(1)
+}
+
+//FromInt ...
+func (f *Big) FromInt(x int64) *big.Int {
+	return f.reduce(big.NewInt(x))
+}
+
+//FromBig ...
+func (f *Big) FromBig(x *big.Int) *big.Int {
+	return f.reduce(new(big.Int).Set(x))
+}
+
+//Big ...
+func (f *Big) Big(a *big.Int) *big.Int {
+	return new(big.Int).Set(a)
+}
+
+//reduce sets x to x mod p, which is never negative
+func (f *Big) reduce(x *big.Int) *big.Int {
+	return x.Mod(x, f.p)
+}
+
+//Add ...
+func (f *Big) Add(a, b *big.Int) *big.Int {
+	sum := new(big.Int).Add(a, b)
+	if sum.Cmp(f.p) >= 0 {
+		sum.Sub(sum, f.p)
+	}
+	return sum
+}
+
+//Sub ...
+func (f *Big) Sub(a, b *big.Int) *big.Int {
+	difference := new(big.Int).Sub(a, b)
+	if difference.Sign() < 0 {
+		difference.Add(difference, f.p)
+	}
+	return difference
+}
+
+//Neg ...
+func (f *Big) Neg(a *big.Int) *big.Int {
+	if a.Sign() == 0 {
+		return new(big.Int)
+	}
+	return new(big.Int).Sub(f.p, a)
+}
+
+//Mul ...
+func (f *Big) Mul(a, b *big.Int) *big.Int {
+	return f.reduce(new(big.Int).Mul(a, b))
+}
+
+//Inverse ...
+func (f *Big) Inverse(a *big.Int) *big.Int {
+	inverse := new(big.Int).ModInverse(a, f.p)
+	if inverse == nil {
+		panic("field: inverse of zero")
+	}
+	return inverse
+}
+
+//Sqrt ...
+func (f *Big) Sqrt(a *big.Int) (*big.Int, bool) {
+	root := new(big.Int).ModSqrt(a, f.p)
+	return root, root != nil
+}
+
+//Equal ...
+func (f *Big) Equal(a, b *big.Int) bool {
+	return a.Cmp(b) == 0
+}
+
+//IsZero ...
+func (f *Big) IsZero(a *big.Int) bool {
+	return a.Sign() == 0
+}
+
+//Random ...
+func (f *Big) Random() *big.Int {
+	//Reading from crypto/rand does not fail
+	r, _ := rand.Int(rand.Reader, f.p)
+	return r
+}
+
+//Size ...
+func (f *Big) Size() int {
+	return f.size
+}
+
+//Encode ...
+func (f *Big) Encode(dst []byte, a *big.Int) {
+	a.FillBytes(dst)
+}
+
+//Decode ...
+func (f *Big) Decode(b []byte) (*big.Int, error) {
+	if len(b) != f.size {
+		return nil, fmt.Errorf("field: element of %d bytes should be %d bytes", len(b), f.size)
+	}
+	a := new(big.Int).SetBytes(b)
+	if a.Cmp(f.p) >= 0 {
+		return nil, errors.New("field: element out of range")
+	}
+	return a, nil
+}
+
+//String ...
+func (f *Big) String(a *big.Int) string {
+	return a.String()
+}
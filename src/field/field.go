@@ -0,0 +1,45 @@
+package field
+
+import (
+	"math/big"
+)
+
+//Field is a prime field whose elements have type E.
+//Elements are values: operations return a new element and never modify their arguments,
+//so an element can be stored and shared between routines.
+type Field[E any] interface {
+	//Modulus is the prime p of the field Z_p
+	Modulus() *big.Int
+
+	Zero() E
+	One() E
+	//FromInt is x mod p
+	FromInt(x int64) E
+	//FromBig is x mod p
+	FromBig(x *big.Int) E
+	//Big is the integer in [0, p) representing a
+	Big(a E) *big.Int
+
+	Add(a, b E) E
+	Sub(a, b E) E
+	Neg(a E) E
+	Mul(a, b E) E
+	//Inverse is the multiplicative inverse of a. Like integer division by zero, it panics if a is zero.
+	Inverse(a E) E
+	//Sqrt is a square root of a, if a is a square
+	Sqrt(a E) (E, bool)
+
+	Equal(a, b E) bool
+	IsZero(a E) bool
+	//Random is a uniformly random element drawn from crypto/rand
+	Random() E
+
+	//Size is the number of bytes of an encoded element
+	Size() int
+	//Encode writes a to dst, which must be Size bytes long, as a big endian integer
+	Encode(dst []byte, a E)
+	//Decode reads an element written by Encode. It rejects integers that are not below p.
+	Decode(b []byte) (E, error)
+	//String is a in decimal
+	String(a E) string
+}
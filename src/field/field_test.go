@@ -0,0 +1,165 @@
+package field
+
+import (
+	"bytes"
+	"math/big"
+	mathrand "math/rand"
+	"testing"
+)
+
+//smallPrimes are the primes both backends are tested with, from the smallest odd prime to the largest below 2^63
+var smallPrimes = []uint64{3, 11, 101, 4001, 2147483647, 4611686018427387847, 9223372036854775783}
+
+//testField checks every operation of f on random and edge case elements against math/big
+func testField[E any](t *testing.T, f Field[E]) {
+	p := f.Modulus()
+	random := mathrand.New(mathrand.NewSource(1))
+	values := []*big.Int{big.NewInt(0), big.NewInt(1), big.NewInt(2), new(big.Int).Sub(p, big.NewInt(1))}
+	for i := 0; i < 20; i++ {
+		values = append(values, new(big.Int).Rand(random, p))
+	}
+	mod := func(x *big.Int) *big.Int {
+		return x.Mod(x, p)
+	}
+	check := func(operation string, a, b *big.Int, result E, expected *big.Int) {
+		if f.Big(result).Cmp(expected) != 0 {
+			t.Errorf("%s of %d and %d mod %d is %s, expected %d", operation, a, b, p, f.String(result), expected)
+		}
+	}
+
+	encoded := make([]byte, f.Size())
+	for _, a := range values {
+		x := f.FromBig(a)
+		check("FromBig", a, a, x, a)
+		check("FromBig", a, a, f.FromBig(new(big.Int).Add(a, p)), a)
+		check("FromBig", a, a, f.FromBig(new(big.Int).Sub(a, p)), a)
+		if a.IsInt64() {
+			check("FromInt", a, a, f.FromInt(a.Int64()), a)
+			check("FromInt", a, a, f.FromInt(-a.Int64()), mod(new(big.Int).Neg(a)))
+		}
+		check("Neg", a, a, f.Neg(x), mod(new(big.Int).Neg(a)))
+		if f.IsZero(x) != (a.Sign() == 0) {
+			t.Errorf("IsZero of %d is %v", a, f.IsZero(x))
+		}
+		if f.String(x) != a.String() {
+			t.Errorf("String of %d is %s", a, f.String(x))
+		}
+
+		f.Encode(encoded, x)
+		if !bytes.Equal(encoded, a.FillBytes(make([]byte, f.Size()))) {
+			t.Errorf("Encoded %d as %x", a, encoded)
+		}
+		decoded, err := f.Decode(encoded)
+		if err != nil || !f.Equal(decoded, x) {
+			t.Errorf("Decoded %d as %s, %v", a, f.String(decoded), err)
+		}
+
+		if a.Sign() != 0 {
+			check("Inverse", a, a, f.Inverse(x), new(big.Int).ModInverse(a, p))
+		}
+		root, isSquare := f.Sqrt(x)
+		if expected := new(big.Int).ModSqrt(a, p); (expected != nil) != isSquare {
+			t.Errorf("Sqrt of %d mod %d exists: %v", a, p, isSquare)
+		} else if isSquare {
+			check("Square of Sqrt", a, a, f.Mul(root, root), a)
+		}
+
+		for _, b := range values {
+			y := f.FromBig(b)
+			check("Add", a, b, f.Add(x, y), mod(new(big.Int).Add(a, b)))
+			check("Sub", a, b, f.Sub(x, y), mod(new(big.Int).Sub(a, b)))
+			check("Mul", a, b, f.Mul(x, y), mod(new(big.Int).Mul(a, b)))
+			if f.Equal(x, y) != (a.Cmp(b) == 0) {
+				t.Errorf("Equal of %d and %d is %v", a, b, f.Equal(x, y))
+			}
+		}
+	}
+
+	if _, err := f.Decode(p.FillBytes(make([]byte, f.Size()))); err == nil {
+		t.Errorf("Decoded %d mod %d", p, p)
+	}
+	if _, err := f.Decode(make([]byte, f.Size()+1)); err == nil {
+		t.Error("Decoded element of the wrong size")
+	}
+	for i := 0; i < 100; i++ {
+		if r := f.Big(f.Random()); r.Sign() < 0 || r.Cmp(p) >= 0 {
+			t.Errorf("Random element %d mod %d", r, p)
+		}
+	}
+}
+
+func TestBig(t *testing.T) {
+	for _, p := range smallPrimes {
+		testField(t, NewBig(new(big.Int).SetUint64(p)))
+	}
+	mersenne127 := new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 127), big.NewInt(1))
+	testField(t, NewBig(mersenne127))
+}
+
+func TestMont64(t *testing.T) {
+	for _, p := range smallPrimes {
+		f, err := NewMont64(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		testField(t, f)
+	}
+}
+
+func TestMont64Uint64(t *testing.T) {
+	p := uint64(9223372036854775783)
+	f, _ := NewMont64(p)
+	for _, x := range []uint64{0, 1, p - 1, p, p + 1, 1<<64 - 1} {
+		if f.Uint64(f.FromUint64(x)) != x%p {
+			t.Errorf("%d mod %d is %d", x, p, f.Uint64(f.FromUint64(x)))
+		}
+	}
+}
+
+func TestNewMont64(t *testing.T) {
+	for _, p := range []uint64{0, 1, 2, 4000, 1 << 63, 1<<64 - 59} {
+		if _, err := NewMont64(p); err == nil {
+			t.Errorf("Created Mont64 field of %d", p)
+		}
+	}
+}
+
+func TestInverseOfZero(t *testing.T) {
+	f, _ := NewMont64(11)
+	defer func() {
+		if recover() == nil {
+			t.Error("Inverted zero")
+		}
+	}()
+	f.Inverse(f.Zero())
+}
+
+func TestMont64DoesNotAllocate(t *testing.T) {
+	f, _ := NewMont64(4001)
+	a, b := f.FromInt(1234), f.FromInt(2345)
+	allocations := testing.AllocsPerRun(100, func() {
+		a = f.Add(f.Mul(a, b), f.Inverse(f.Sub(a, f.Neg(b))))
+	})
+	if allocations != 0 {
+		t.Errorf("Arithmetic allocates %v times", allocations)
+	}
+}
+
+//benchmarkMul multiplies and adds like the inner loop of polynomial evaluation
+func benchmarkMul[E any](b *testing.B, f Field[E]) {
+	x, y := f.Random(), f.Random()
+	sum := f.Zero()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		sum = f.Add(f.Mul(sum, x), y)
+	}
+}
+
+func BenchmarkBigMul(b *testing.B) {
+	benchmarkMul(b, NewBig(big.NewInt(4001)))
+}
+
+func BenchmarkMont64Mul(b *testing.B) {
+	f, _ := NewMont64(4001)
+	benchmarkMul(b, f)
+}
@@ -0,0 +1,243 @@
+package field
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+	"math/bits"
+	"strconv"
+)
+
+//Mont64Element is an element of a Mont64 field, in Montgomery form: the element a is stored as a*2^64 mod p.
+//Elements are only meaningful to the field they belong to, use Big or String to read them.
+type Mont64Element uint64
+
+//Mont64 is a field Z_p for an odd prime p < 2^63 with elements in a single machine word.
+//Multiplication uses Montgomery reduction, so no operation allocates.
+type Mont64 struct {
+	p uint64
+	//pInv is -p^-1 mod 2^64
+	pInv uint64
+	//r2 is 2^128 mod p, which takes integers into Montgomery form
+	r2   uint64
+	one  Mont64Element
+	size int
+	//mask keeps the bits a random integer below p can have
+	mask uint64
+}
+
+//NewMont64 creates the field Z_p. p must be an odd prime below 2^63.
+func NewMont64(p uint64) (*Mont64, error) {
+	if p < 3 || p%2 == 0 || p >= 1<<63 {
+		return nil, fmt.Errorf("field: Mont64 needs an odd prime below 2^63, not %d", p)
+	}
+	f := &Mont64{p: p}
+
+	//Newton's iteration doubles the number of correct low bits of p^-1 every step, starting from 1 as p is odd
+	inverse := uint64(1)
+	for i := 0; i < 6; i++ {
+		inverse *= 2 - p*inverse
+	}
+	f.pInv = -inverse
+
+	r2 := new(big.Int).Lsh(big.NewInt(1), 128)
+	r2.Mod(r2, new(big.Int).SetUint64(p))
+	f.r2 = r2.Uint64()
+	f.one = f.FromInt(1)
+
+	length := bits.Len64(p)
+	f.size = (length + 7) / 8
+	f.mask = 1<<length - 1
+	return f, nil
+}
+
+//reduce is x*2^-64 mod p for x = hi*2^64 + lo < p*2^64
+func (f *Mont64) reduce(hi, lo uint64) uint64 {
+	m := lo * f.pInv
+	mHi, mLo := bits.Mul64(m, f.p)
+	//lo + mLo is 0 mod 2^64 and only its carry is needed
+	_, carry := bits.Add64(lo, mLo, 0)
+	//Below 2p < 2^64, as p < 2^63
+	t := hi + mHi + carry
+	if t >= f.p {
+		t -= f.p
+	}
+	return t
+}
+
+func (f *Mont64) toMont(x uint64) Mont64Element {
+	hi, lo := bits.Mul64(x, f.r2)
+	return Mont64Element(f.reduce(hi, lo))
+}
+
+func (f *Mont64) fromMont(a Mont64Element) uint64 {
+	return f.reduce(0, uint64(a))
+}
+
+//Modulus ...
+func (f *Mont64) Modulus() *big.Int {
+	return new(big.Int).SetUint64(f.p)
+}
+
+//Zero ...
+func (f *Mont64) Zero() Mont64Element {
+	return 0
+}
+
+//One ...
+func (f *Mont64) One() Mont64Element {
+	return f.one
+}
+
+//FromInt ...
+func (f *Mont64) FromInt(x int64) Mont64Element {
+	if x < 0 {
+		//-x does not overflow as a uint64, even for the smallest int64
+		return f.Neg(f.FromUint64(uint64(-x)))
+	}
+	return f.FromUint64(uint64(x))
+}
+
+//FromUint64 is x mod p
+func (f *Mont64) FromUint64(x uint64) Mont64Element {
+	return f.toMont(x % f.p)
+}
+
+//Uint64 is the integer in [0, p) representing a. Unlike Big it does not allocate.
+func (f *Mont64) Uint64(a Mont64Element) uint64 {
+	return f.fromMont(a)
+}
+
+//FromBig ...
+func (f *Mont64) FromBig(x *big.Int) Mont64Element {
+	if x.IsUint64() {
+		return f.FromUint64(x.Uint64())
+	}
+	reduced := new(big.Int).Mod(x, new(big.Int).SetUint64(f.p))
+	return f.toMont(reduced.Uint64())
+}
+
+//Big ...
+func (f *Mont64) Big(a Mont64Element) *big.Int {
+	return new(big.Int).SetUint64(f.fromMont(a))
+}
+
+//Add ...
+func (f *Mont64) Add(a, b Mont64Element) Mont64Element {
+	//Does not overflow, as both are below 2^63
+	sum := uint64(a) + uint64(b)
+	if sum >= f.p {
+		sum -= f.p
+	}
+	return Mont64Element(sum)
+}
+
+//Sub ...
+func (f *Mont64) Sub(a, b Mont64Element) Mont64Element {
+	if a >= b {
+		return a - b
+	}
+	return Mont64Element(uint64(a) + f.p - uint64(b))
+}
+
+//Neg ...
+func (f *Mont64) Neg(a Mont64Element) Mont64Element {
+	if a == 0 {
+		return 0
+	}
+	return Mont64Element(f.p - uint64(a))
+}
+
+//Mul ...
+func (f *Mont64) Mul(a, b Mont64Element) Mont64Element {
+	hi, lo := bits.Mul64(uint64(a), uint64(b))
+	return Mont64Element(f.reduce(hi, lo))
+}
+
+//exp is a^e by square and multiply
+func (f *Mont64) exp(a Mont64Element, e uint64) Mont64Element {
+	result := f.one
+	for i := bits.Len64(e) - 1; i >= 0; i-- {
+		result = f.Mul(result, result)
+		if e>>uint(i)&1 == 1 {
+			result = f.Mul(result, a)
+		}
+	}
+	return result
+}
+
+//Inverse is a^(p-2), which is a^-1 by Fermat's little theorem
+func (f *Mont64) Inverse(a Mont64Element) Mont64Element {
+	if a == 0 {
+		panic("field: inverse of zero")
+	}
+	return f.exp(a, f.p-2)
+}
+
+//Sqrt takes the square root with math/big, so unlike other operations it allocates
+func (f *Mont64) Sqrt(a Mont64Element) (Mont64Element, bool) {
+	root := new(big.Int).ModSqrt(f.Big(a), f.Modulus())
+	if root == nil {
+		return 0, false
+	}
+	return f.toMont(root.Uint64()), true
+}
+
+//Equal ...
+func (f *Mont64) Equal(a, b Mont64Element) bool {
+	return a == b
+}
+
+//IsZero ...
+func (f *Mont64) IsZero(a Mont64Element) bool {
+	return a == 0
+}
+
+//Random draws integers with as many bits as p until one is below p, which takes two draws at most on average
+func (f *Mont64) Random() Mont64Element {
+	var b [8]byte
+	for {
+		//Reading from crypto/rand does not fail
+		rand.Read(b[:])
+		x := binary.BigEndian.Uint64(b[:]) & f.mask
+		if x < f.p {
+			return f.toMont(x)
+		}
+	}
+}
+
+//Size ...
+func (f *Mont64) Size() int {
+	return f.size
+}
+
+//Encode ...
+func (f *Mont64) Encode(dst []byte, a Mont64Element) {
+	x := f.fromMont(a)
+	for i := len(dst) - 1; i >= 0; i-- {
+		dst[i] = byte(x)
+		x >>= 8
+	}
+}
+
+//Decode ...
+func (f *Mont64) Decode(b []byte) (Mont64Element, error) {
+	if len(b) != f.size {
+		return 0, fmt.Errorf("field: element of %d bytes should be %d bytes", len(b), f.size)
+	}
+	var x uint64
+	for _, c := range b {
+		x = x<<8 | uint64(c)
+	}
+	if x >= f.p {
+		return 0, errors.New("field: element out of range")
+	}
+	return f.toMont(x), nil
+}
+
+//String ...
+func (f *Mont64) String(a Mont64Element) string {
+	return strconv.FormatUint(f.fromMont(a), 10)
+}
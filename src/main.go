@@ -1,88 +1,208 @@
 package main
 
 import (
-	"bufio"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"strconv"
-	"strings"
 
+	"./cluster"
+	"./field"
+	"./network/recordnetwork"
+	"./network/relaynetwork"
+	"./network/tcpnetwork"
 	"./player"
 )
 
-func runLocally(programPath, inputPath, configPath string) {
-	numberOfParties := 3
-	threshold := 1
-	prime := int64(4001)
-	if configPath != "" {
-		file, err := os.Open(configPath)
-		if err != nil && os.IsExist(err) { //Continue execution if file does not exist
-			log.Fatal(err)
-		}
-		if err == nil {
-			defer file.Close()
-
-			scanner := bufio.NewScanner(file)
-			for scanner.Scan() {
-				line := scanner.Text()
-				tokens := strings.Split(line, " ")
-				if len(tokens) != 2 {
-					continue
-				}
-				switch tokens[0] {
-				case "p":
-					value, err := strconv.ParseInt(tokens[1], 10, 64)
-					if err == nil {
-						prime = value
-					}
-				case "n":
-					value, err := strconv.Atoi(tokens[1])
-					if err == nil {
-						numberOfParties = value
-					}
-				case "t":
-					value, err := strconv.Atoi(tokens[1])
-					if err == nil {
-						threshold = value
-					}
-				}
-			}
-
-			if err := scanner.Err(); err != nil {
-				log.Fatal(err)
-			}
-		}
+//options are the layers between a party and its network chosen by the config
+func options(c cluster.Config) player.Options {
+	//Verifiable input broadcasts complaints and votes, which must reach all parties alike
+	return player.Options{Batch: c.Batch, ReliableBroadcast: c.ReliableBroadcast || c.Verifiable}
+}
 
+//loadConfig loads the cluster config at configPath and stops the program if it is invalid
+func loadConfig(configPath string) cluster.Config {
+	c, err := cluster.Load(configPath)
+	if err != nil {
+		log.Fatal(err)
 	}
+	return c
+}
 
-	parties := player.LocalSetup(prime, threshold, numberOfParties, programPath, inputPath)
+func runLocally[E any](f field.Field[E], c cluster.Config, programPath, inputPath string) {
+	parties := player.LocalSetup(f, c.Threshold, c.N(), options(c), programPath, inputPath)
+	for _, party := range parties {
+		party.SetTimeout(c.WaitTimeout())
+		party.SetRobust(c.Robust)
+		party.SetVerifiable(c.Verifiable)
+	}
 	for i, party := range parties {
 		if i == 1 {
 			continue
 		}
 		go party.Run()
 	}
-	output := parties[1].Run()
+	output, err := parties[1].Run()
+	if err != nil {
+		log.Fatal(err)
+	}
 	for id, val := range output {
 		fmt.Println(id, val)
 	}
 }
 
+//setupParty connects a single party to the others through the relay if one is configured, or else over TCP
+func setupParty[E any](f field.Field[E], c cluster.Config, programPath, inputPath string, index int) (*player.Player[E], error) {
+	if err := c.CheckParty(index); err != nil {
+		return nil, err
+	}
+	party, _ := c.Party(index)
+	if c.Relay != "" {
+		keys, err := relaynetwork.LoadKeys(party.PrivateKey, c.PublicKeys())
+		if err != nil {
+			return nil, err
+		}
+		return player.RelaySetup(f, c.Threshold, c.N(), index, options(c), c.Relay, keys, programPath, inputPath)
+	}
+
+	var credentials *tcpnetwork.Credentials
+	if c.CA != "" {
+		loaded, err := tcpnetwork.LoadCredentials(party.Certificate, party.Key, c.CA, c.Certificates())
+		if err != nil {
+			return nil, err
+		}
+		credentials = &loaded
+	}
+	return player.TCPSetup(f, c.Threshold, c.N(), index, options(c), c.Addresses(), credentials, programPath, inputPath)
+}
+
+//runParty runs a single party in this process, connected to the others over TCP or through a relay.
+//Before running the program the parties check that they all loaded the same config.
+//If transcript is set, the party records its run to transcript followed by its index.
+func runParty[E any](f field.Field[E], c cluster.Config, programPath, inputPath string, index int, transcript string) {
+	party, err := setupParty(f, c, programPath, inputPath, index)
+	if err != nil {
+		log.Fatal(err)
+	}
+	party.SetTimeout(c.WaitTimeout())
+	party.SetRobust(c.Robust)
+	party.SetVerifiable(c.Verifiable)
+	var recorder *recordnetwork.Recordnetwork
+	if transcript != "" {
+		file, err := os.Create(transcript + strconv.Itoa(index))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer file.Close()
+		recorder = party.Record(file)
+	}
+	digest, err := c.Digest()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := party.Agree(digest); err != nil {
+		log.Fatal(err)
+	}
+	output, err := party.Run()
+	if err != nil {
+		log.Fatal(err)
+	}
+	//The other parties may still need the shares opened last
+	party.WaitForSends()
+	log.Println("party", index, party.Stats())
+	for id, parties := range party.InconsistentShares() {
+		log.Println("parties", parties, "opened", id, "with wrong shares")
+	}
+	for id, dealer := range party.Disqualified() {
+		log.Println("party", dealer, "was disqualified from providing", id)
+	}
+	if recorder != nil {
+		if err := recorder.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+	for id, val := range output {
+		fmt.Println(id, val)
+	}
+}
+
+//replayParty runs a single party on the data it received in the run recorded in the transcript at replayPath
+func replayParty[E any](f field.Field[E], c cluster.Config, programPath, inputPath string, index int, replayPath string) {
+	file, err := os.Open(replayPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	events, err := recordnetwork.ReadTranscript(file)
+	file.Close()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	party, replay := player.ReplaySetup(events, f, c.Threshold, c.N(), index, programPath, inputPath)
+	party.SetRobust(c.Robust)
+	party.SetVerifiable(c.Verifiable)
+	go func() {
+		if err := replay.Replay(); err != nil {
+			log.Fatal(err)
+		}
+	}()
+	output, err := party.Run()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for id, val := range output {
+		fmt.Println(id, val)
+	}
+}
+
+//run runs the parties in the field f. Without an index all parties run in this process.
+func run[E any](f field.Field[E], c cluster.Config, programPath, inputPath string, index int, transcript, replay string) {
+	switch {
+	case index == 0:
+		runLocally(f, c, programPath, inputPath)
+	case replay != "":
+		replayParty(f, c, programPath, inputPath, index, replay)
+	default:
+		runParty(f, c, programPath, inputPath, index, transcript)
+	}
+}
+
 func main() {
+	transcript := flag.String("transcript", "", "record the run of the party to this path followed by its index")
+	replay := flag.String("replay", "", "replay the run of the party recorded in this transcript")
+	flag.Parse()
+	args := flag.Args()
+
 	var directory string = "player/tests/compiled/"
 	programPath := directory + "prog"
 	inputPath := directory + "input"
-	configPath := directory + "config"
-	if len(os.Args) == 3 {
-		programPath = os.Args[1]
-		inputPath = os.Args[2]
+	configPath := directory + "config.json"
+	if len(args) == 2 {
+		programPath = args[0]
+		inputPath = args[1]
+	}
+	if len(args) >= 3 {
+		programPath = args[0]
+		inputPath = args[1]
+		configPath = args[2]
 	}
-	if len(os.Args) == 4 {
-		programPath = os.Args[1]
-		inputPath = os.Args[2]
-		configPath = os.Args[3]
+	index := 0
+	if len(args) == 4 {
+		var err error
+		index, err = strconv.Atoi(args[3])
+		if err != nil {
+			log.Fatal("party index must be a number: ", args[3])
+		}
 	}
-	runLocally(programPath, inputPath, configPath)
 
+	c := loadConfig(configPath)
+	//Primes below 2^63 fit the allocation free Montgomery field, which is much faster
+	if c.Prime.IsUint64() {
+		if f, err := field.NewMont64(c.Prime.Uint64()); err == nil {
+			run(f, c, programPath, inputPath, index, *transcript, *replay)
+			return
+		}
+	}
+	run(field.NewBig(c.Prime.Int), c, programPath, inputPath, index, *transcript, *replay)
 }
@@ -0,0 +1,188 @@
+package batchnetwork
+
+import (
+	"sync"
+	"time"
+
+	".."
+)
+
+//Batch is the data of several messages to the same receiver sent as one
+type Batch []interface{}
+
+//Policy decides when queued messages are sent.
+//Queues are always sent when Flush is called,
+//which a player does whenever it is about to wait for data.
+type Policy struct {
+	//MaxMessages sends the queue of a receiver once it holds this many messages. Zero means no limit.
+	MaxMessages int
+	//MaxDelay sends all queues at most this long after a message was queued. Zero means no limit.
+	MaxDelay time.Duration
+}
+
+//Batchnetwork queues the messages for each receiver and sends each queue as one Batch.
+//It sits between a handler and the network carrying the batches:
+//it is the network of the handler and the handler of the network below.
+type Batchnetwork struct {
+	policy  Policy
+	inner   network.Network
+	handler network.Handler
+
+	lock   sync.Mutex
+	queues map[int][]interface{}
+	timer  *time.Timer
+	//sending holds a lock per receiver from taking its queue until the queue is sent,
+	//so queues to a receiver are sent in the order they were queued
+	sending map[int]*sync.Mutex
+
+	messages int
+	frames   int
+}
+
+//New creates a batch network sending queues according to policy
+func New(policy Policy) *Batchnetwork {
+	return &Batchnetwork{
+		policy:  policy,
+		queues:  make(map[int][]interface{}),
+		sending: make(map[int]*sync.Mutex),
+	}
+}
+
+//Send queues data for receiver.
+//It only returns an error if the queue is sent, errors of queues sent later are returned by Flush.
+func (bn *Batchnetwork) Send(data interface{}, receiver int) error {
+	bn.lock.Lock()
+	bn.messages++
+	bn.queues[receiver] = append(bn.queues[receiver], data)
+	if bn.policy.MaxMessages > 0 && len(bn.queues[receiver]) >= bn.policy.MaxMessages {
+		bn.lock.Unlock()
+		return bn.sendQueue(receiver)
+	}
+	if bn.policy.MaxDelay > 0 && bn.timer == nil {
+		bn.timer = time.AfterFunc(bn.policy.MaxDelay, bn.flushDelayed)
+	}
+	bn.lock.Unlock()
+	return nil
+}
+
+//sendQueue takes the queue of receiver and sends it, holding the send lock of receiver throughout
+func (bn *Batchnetwork) sendQueue(receiver int) error {
+	bn.lock.Lock()
+	sending, exists := bn.sending[receiver]
+	if !exists {
+		sending = &sync.Mutex{}
+		bn.sending[receiver] = sending
+	}
+	bn.lock.Unlock()
+
+	sending.Lock()
+	defer sending.Unlock()
+	bn.lock.Lock()
+	queue := bn.queues[receiver]
+	delete(bn.queues, receiver)
+	if len(queue) > 0 {
+		bn.frames++
+	}
+	bn.lock.Unlock()
+	if len(queue) == 0 {
+		//Another send took the queue first and has sent it
+		return nil
+	}
+	return bn.inner.Send(batch(queue), receiver)
+}
+
+//flushDelayed reports errors to the handler, as no caller is waiting for them
+func (bn *Batchnetwork) flushDelayed() {
+	if err := bn.Flush(); err != nil {
+		bn.handler.HandleError(err)
+	}
+}
+
+//Broadcast queues data for every party
+func (bn *Batchnetwork) Broadcast(data interface{}) error {
+	return network.SendToAll(bn.Send, data, bn.Parties())
+}
+
+//Parties of the network carrying the batches
+func (bn *Batchnetwork) Parties() []int {
+	return bn.inner.Parties()
+}
+
+//Flush sends the queue of every receiver and returns the first error
+func (bn *Batchnetwork) Flush() error {
+	bn.lock.Lock()
+	receivers := make([]int, 0, len(bn.queues))
+	for receiver := range bn.queues {
+		receivers = append(receivers, receiver)
+	}
+	if bn.timer != nil {
+		bn.timer.Stop()
+		bn.timer = nil
+	}
+	bn.lock.Unlock()
+
+	var err error
+	for _, receiver := range receivers {
+		if sendErr := bn.sendQueue(receiver); err == nil {
+			err = sendErr
+		}
+	}
+	return err
+}
+
+//Close sends what is queued and closes the network carrying the batches
+func (bn *Batchnetwork) Close() error {
+	err := bn.Flush()
+	if closeErr := bn.inner.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+//batch wraps the queue, unless it holds a single message
+func batch(queue []interface{}) interface{} {
+	if len(queue) == 1 {
+		return queue[0]
+	}
+	return Batch(queue)
+}
+
+//Counts is the number of messages sent through the batch network and the number of frames they were sent in
+func (bn *Batchnetwork) Counts() (messages, frames int) {
+	bn.lock.Lock()
+	defer bn.lock.Unlock()
+	return bn.messages, bn.frames
+}
+
+//RegisterHandler ...
+func (bn *Batchnetwork) RegisterHandler(handler network.Handler) {
+	bn.handler = handler
+	bn.handler.RegisterNetwork(bn)
+}
+
+//Handle passes every message of a batch to the handler in the order they were queued
+func (bn *Batchnetwork) Handle(data interface{}, sender int) {
+	b, isBatch := data.(Batch)
+	if !isBatch {
+		bn.handler.Handle(data, sender)
+		return
+	}
+	for _, data := range b {
+		bn.handler.Handle(data, sender)
+	}
+}
+
+//HandleError passes errors of the network carrying the batches to the handler
+func (bn *Batchnetwork) HandleError(err error) {
+	bn.handler.HandleError(err)
+}
+
+//RegisterNetwork sets the network carrying the batches
+func (bn *Batchnetwork) RegisterNetwork(network network.Network) {
+	bn.inner = network
+}
+
+//Index of the handler
+func (bn *Batchnetwork) Index() int {
+	return bn.handler.Index()
+}
@@ -0,0 +1,245 @@
+package batchnetwork
+
+import (
+	"errors"
+	"math/rand"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	".."
+)
+
+type sent struct {
+	data     interface{}
+	receiver int
+}
+
+//recordingNetwork records what is sent through it, after a random delay of up to delay
+type recordingNetwork struct {
+	delay time.Duration
+
+	lock   sync.Mutex
+	sent   []sent
+	err    error
+	closed bool
+}
+
+func (rn *recordingNetwork) Send(data interface{}, receiver int) error {
+	if rn.delay > 0 {
+		time.Sleep(time.Duration(rand.Int63n(int64(rn.delay))))
+	}
+	rn.lock.Lock()
+	defer rn.lock.Unlock()
+	rn.sent = append(rn.sent, sent{data: data, receiver: receiver})
+	return rn.err
+}
+
+func (rn *recordingNetwork) Broadcast(data interface{}) error {
+	return network.SendToAll(rn.Send, data, rn.Parties())
+}
+
+func (rn *recordingNetwork) Parties() []int {
+	return []int{1, 2, 3}
+}
+
+func (rn *recordingNetwork) Close() error {
+	rn.lock.Lock()
+	rn.closed = true
+	rn.lock.Unlock()
+	return nil
+}
+
+func (rn *recordingNetwork) RegisterHandler(handler network.Handler) {
+	handler.RegisterNetwork(rn)
+}
+
+func (rn *recordingNetwork) frames() []sent {
+	rn.lock.Lock()
+	defer rn.lock.Unlock()
+	return append([]sent(nil), rn.sent...)
+}
+
+type handler struct {
+	index    int
+	network  network.Network
+	received []interface{}
+
+	lock   sync.Mutex
+	errors []error
+}
+
+func (h *handler) Handle(data interface{}, sender int) {
+	h.received = append(h.received, data)
+}
+
+func (h *handler) HandleError(err error) {
+	h.lock.Lock()
+	h.errors = append(h.errors, err)
+	h.lock.Unlock()
+}
+
+func (h *handler) RegisterNetwork(network network.Network) {
+	h.network = network
+}
+
+func (h *handler) Index() int {
+	return h.index
+}
+
+func setting(policy Policy) (*handler, *Batchnetwork, *recordingNetwork) {
+	inner := new(recordingNetwork)
+	bn := New(policy)
+	h := &handler{index: 1}
+	bn.RegisterHandler(h)
+	inner.RegisterHandler(bn)
+	return h, bn, inner
+}
+
+func TestFlush(t *testing.T) {
+	h, bn, inner := setting(Policy{})
+	h.network.Send("a", 2)
+	h.network.Send("b", 2)
+	h.network.Send("c", 3)
+	if len(inner.frames()) != 0 {
+		t.Errorf("Sent %v before flushing", inner.frames())
+	}
+
+	bn.Flush()
+	frames := inner.frames()
+	if len(frames) != 2 {
+		t.Fatalf("Expected one frame per receiver, got %v", frames)
+	}
+	for _, frame := range frames {
+		switch frame.receiver {
+		case 2:
+			if !reflect.DeepEqual(frame.data, Batch{"a", "b"}) {
+				t.Errorf("Party 2 was sent %v", frame.data)
+			}
+		case 3:
+			if frame.data != "c" {
+				t.Errorf("Party 3 was sent %v", frame.data)
+			}
+		}
+	}
+
+	messages, count := bn.Counts()
+	if messages != 3 || count != 2 {
+		t.Errorf("Counted %d messages in %d frames", messages, count)
+	}
+}
+
+func TestMaxMessages(t *testing.T) {
+	h, _, inner := setting(Policy{MaxMessages: 2})
+	h.network.Send("a", 2)
+	h.network.Send("b", 3)
+	h.network.Send("c", 2)
+	frames := inner.frames()
+	if len(frames) != 1 || !reflect.DeepEqual(frames[0], sent{Batch{"a", "c"}, 2}) {
+		t.Errorf("Expected the full queue of party 2 to be sent, got %v", frames)
+	}
+}
+
+func TestMaxDelay(t *testing.T) {
+	h, _, inner := setting(Policy{MaxDelay: 10 * time.Millisecond})
+	h.network.Send("a", 2)
+	deadline := time.Now().Add(time.Second)
+	for len(inner.frames()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("Queue was not sent after MaxDelay")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHandleUnpacksBatch(t *testing.T) {
+	h, bn, _ := setting(Policy{})
+	bn.Handle(Batch{"a", "b", "c"}, 2)
+	bn.Handle("d", 2)
+	if !reflect.DeepEqual(h.received, []interface{}{"a", "b", "c", "d"}) {
+		t.Errorf("Received %v", h.received)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	h, bn, inner := setting(Policy{MaxDelay: 10 * time.Millisecond})
+	inner.err = errors.New("broken")
+	if err := h.network.Send("a", 2); err != nil {
+		t.Errorf("Queueing returned %v", err)
+	}
+	deadline := time.Now().Add(time.Second)
+	for {
+		h.lock.Lock()
+		reported := len(h.errors)
+		h.lock.Unlock()
+		if reported == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Error of delayed flush was not reported to the handler")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	h.network.Send("b", 2)
+	if err := bn.Flush(); err != inner.err {
+		t.Errorf("Flush returned %v", err)
+	}
+}
+
+func TestClose(t *testing.T) {
+	h, bn, inner := setting(Policy{})
+	h.network.Send("a", 2)
+	if err := bn.Close(); err != nil {
+		t.Error(err)
+	}
+	if len(inner.frames()) != 1 || !inner.closed {
+		t.Errorf("Close sent %v and closed the inner network: %v", inner.frames(), inner.closed)
+	}
+}
+
+func TestConcurrentSendsKeepOrder(t *testing.T) {
+	_, bn, inner := setting(Policy{MaxMessages: 3})
+	inner.delay = 200 * time.Microsecond
+	//Full queues and flushes are sent concurrently
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+					bn.Flush()
+				}
+			}
+		}()
+	}
+	for i := 0; i < 100; i++ {
+		bn.Send(i, 2)
+	}
+	close(done)
+	wg.Wait()
+	bn.Flush()
+
+	next := 0
+	for _, frame := range inner.frames() {
+		messages, isBatch := frame.data.(Batch)
+		if !isBatch {
+			messages = Batch{frame.data}
+		}
+		for _, data := range messages {
+			if data != next {
+				t.Fatalf("Sent %v after %d", data, next-1)
+			}
+			next++
+		}
+	}
+	if next != 100 {
+		t.Errorf("Sent %d of 100 messages", next)
+	}
+}
@@ -0,0 +1,93 @@
+package batchnetwork
+
+import (
+	"encoding/binary"
+	"errors"
+
+	".."
+)
+
+const (
+	singleTag byte = iota
+	batchTag
+)
+
+//Codec encodes batches using an inner codec for the messages they hold
+type Codec struct {
+	inner network.Codec
+}
+
+//NewCodec ...
+func NewCodec(inner network.Codec) *Codec {
+	return &Codec{inner: inner}
+}
+
+//Encode writes a tag followed by either one message
+//or the number of messages and each message prefixed by its length
+func (c *Codec) Encode(data interface{}) ([]byte, error) {
+	b, isBatch := data.(Batch)
+	if !isBatch {
+		encoded, err := c.inner.Encode(data)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{singleTag}, encoded...), nil
+	}
+
+	buf := make([]byte, 5)
+	buf[0] = batchTag
+	binary.BigEndian.PutUint32(buf[1:], uint32(len(b)))
+	for _, data := range b {
+		encoded, err := c.inner.Encode(data)
+		if err != nil {
+			return nil, err
+		}
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(encoded)))
+		buf = append(buf, encoded...)
+	}
+	return buf, nil
+}
+
+//Decode ...
+func (c *Codec) Decode(b []byte) (interface{}, error) {
+	if len(b) == 0 {
+		return nil, errors.New("batchnetwork: empty message")
+	}
+	switch b[0] {
+	case singleTag:
+		return c.inner.Decode(b[1:])
+	case batchTag:
+	default:
+		return nil, errors.New("batchnetwork: unknown tag")
+	}
+
+	if len(b) < 5 {
+		return nil, errors.New("batchnetwork: batch too short")
+	}
+	count := binary.BigEndian.Uint32(b[1:])
+	b = b[5:]
+	if uint64(count)*4 > uint64(len(b)) {
+		return nil, errors.New("batchnetwork: batch too short")
+	}
+	decoded := make(Batch, count)
+	for i := range decoded {
+		if len(b) < 4 {
+			return nil, errors.New("batchnetwork: batch too short")
+		}
+		length := binary.BigEndian.Uint32(b)
+		b = b[4:]
+		if uint64(length) > uint64(len(b)) {
+			return nil, errors.New("batchnetwork: batch too short")
+		}
+		data, err := c.inner.Decode(b[:length])
+		if err != nil {
+			return nil, err
+		}
+		decoded[i] = data
+		b = b[length:]
+	}
+	if len(b) != 0 {
+		return nil, errors.New("batchnetwork: trailing bytes after batch")
+	}
+	return decoded, nil
+}
@@ -0,0 +1,43 @@
+package batchnetwork
+
+import (
+	"reflect"
+	"testing"
+)
+
+type stringCodec struct{}
+
+func (stringCodec) Encode(data interface{}) ([]byte, error) {
+	return []byte(data.(string)), nil
+}
+
+func (stringCodec) Decode(b []byte) (interface{}, error) {
+	return string(b), nil
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	codec := NewCodec(stringCodec{})
+	for _, data := range []interface{}{"single", Batch{"a", "", "ccc"}, Batch{}} {
+		b, err := codec.Encode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := codec.Decode(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(data, decoded) {
+			t.Errorf("Expected %v after round trip, got %v", data, decoded)
+		}
+	}
+}
+
+func TestCodecRejectsTruncatedBatch(t *testing.T) {
+	codec := NewCodec(stringCodec{})
+	b, _ := codec.Encode(Batch{"a", "b"})
+	for length := 0; length < len(b); length++ {
+		if _, err := codec.Decode(b[:length]); err == nil {
+			t.Errorf("Decoded batch truncated to %d bytes", length)
+		}
+	}
+}
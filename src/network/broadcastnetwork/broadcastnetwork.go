@@ -0,0 +1,355 @@
+package broadcastnetwork
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	".."
+)
+
+//Mode decides how Broadcast delivers data
+type Mode int
+
+const (
+	//Plain sends data to every party. A corrupt sender may send different data to different parties.
+	Plain Mode = iota
+	//Reliable runs Bracha's broadcast, so all honest parties that deliver data from a sender
+	//deliver the same data, and if one delivers, all do. It needs at least 3t+1 parties to tolerate t corrupt ones.
+	Reliable
+)
+
+//Step is the step of a reliable broadcast a message belongs to
+type Step byte
+
+const (
+	//Initial is sent by the origin to every party
+	Initial Step = iota + 1
+	//Echo is sent by every party on receiving the initial message
+	Echo
+	//Ready is sent by every party once it knows enough parties echoed the same data
+	Ready
+)
+
+//Message is a message of a reliable broadcast.
+//Origin and Sequence identify the broadcast, as every party numbers its broadcasts.
+type Message struct {
+	Step     Step
+	Origin   int
+	Sequence uint64
+	Data     interface{}
+}
+
+//Broadcastnetwork adds a broadcast to a network of point-to-point links.
+//It sits between a handler and the network below:
+//it is the network of the handler and the handler of the network below.
+//Send passes data through unchanged.
+type Broadcastnetwork struct {
+	mode      Mode
+	threshold int
+	codec     network.Codec
+	inner     network.Network
+	handler   network.Handler
+
+	lock      sync.Mutex
+	sequence  uint64
+	instances map[instanceID]*instance
+	delivered map[int]*deliveries
+
+	//Echoes and readies wait in the outbox for the sender goroutine
+	outboxLock sync.Mutex
+	outbox     []Message
+	wake       chan struct{}
+	done       chan struct{}
+	startOnce  sync.Once
+	closeOnce  sync.Once
+}
+
+//Window bounds how far the broadcasts of an origin may run ahead of the first one this party has not delivered.
+//Messages of broadcasts beyond it are dropped, so a corrupt party cannot make others keep unbounded state.
+const Window = 1 << 12
+
+//deliveries are the broadcasts of an origin that have been delivered, whose instances are removed
+type deliveries struct {
+	//floor is the sequence number up to which all broadcasts have been delivered
+	floor uint64
+	//above are the delivered sequence numbers above floor
+	above map[uint64]bool
+}
+
+//has tells whether the broadcast with sequence has been delivered
+func (d *deliveries) has(sequence uint64) bool {
+	return sequence <= d.floor || d.above[sequence]
+}
+
+//add marks the broadcast with sequence as delivered and raises the floor past every delivered broadcast
+func (d *deliveries) add(sequence uint64) {
+	d.above[sequence] = true
+	for d.above[d.floor+1] {
+		delete(d.above, d.floor+1)
+		d.floor++
+	}
+}
+
+type instanceID struct {
+	origin   int
+	sequence uint64
+}
+
+//instance is the state of a single reliable broadcast.
+//Every party is counted once per step, whatever data it sent.
+//It is removed once its data is delivered.
+type instance struct {
+	echoed  bool
+	readied bool
+
+	echoes  map[int]bool
+	readies map[int]bool
+	//echoVotes and readyVotes count the parties for each value, keyed by the encoded data
+	echoVotes  map[string]int
+	readyVotes map[string]int
+}
+
+//New creates a broadcast network tolerating threshold corrupt parties.
+//Reliable mode compares data by its encoding with codec, which may be nil in plain mode.
+func New(mode Mode, threshold int, codec network.Codec) *Broadcastnetwork {
+	return &Broadcastnetwork{
+		mode:      mode,
+		threshold: threshold,
+		codec:     codec,
+		instances: make(map[instanceID]*instance),
+		delivered: make(map[int]*deliveries),
+		wake:      make(chan struct{}, 1),
+		done:      make(chan struct{}),
+	}
+}
+
+//Send ...
+func (bn *Broadcastnetwork) Send(data interface{}, receiver int) error {
+	return bn.inner.Send(data, receiver)
+}
+
+//Broadcast delivers data to the handler of every party according to the mode
+func (bn *Broadcastnetwork) Broadcast(data interface{}) error {
+	if bn.mode == Plain {
+		return bn.inner.Broadcast(data)
+	}
+	if err := bn.check(); err != nil {
+		return err
+	}
+	bn.lock.Lock()
+	bn.sequence++
+	sequence := bn.sequence
+	bn.lock.Unlock()
+	return bn.inner.Broadcast(Message{Step: Initial, Origin: bn.handler.Index(), Sequence: sequence, Data: data})
+}
+
+func (bn *Broadcastnetwork) check() error {
+	if bn.codec == nil {
+		return errors.New("broadcastnetwork: reliable broadcast needs a codec")
+	}
+	n := len(bn.inner.Parties())
+	if n < 3*bn.threshold+1 {
+		return fmt.Errorf("broadcastnetwork: reliable broadcast among %d parties cannot tolerate %d corrupt parties", n, bn.threshold)
+	}
+	return nil
+}
+
+//Parties of the network below
+func (bn *Broadcastnetwork) Parties() []int {
+	return bn.inner.Parties()
+}
+
+//ReliableBroadcast tells whether bn runs Bracha's broadcast
+func (bn *Broadcastnetwork) ReliableBroadcast() bool {
+	return bn.mode == Reliable
+}
+
+//RegisterHandler ...
+func (bn *Broadcastnetwork) RegisterHandler(handler network.Handler) {
+	bn.handler = handler
+	bn.handler.RegisterNetwork(bn)
+}
+
+//Flush sends the queued steps of broadcasts and flushes the network below if it holds data back
+func (bn *Broadcastnetwork) Flush() error {
+	if err := bn.sendQueued(); err != nil {
+		return err
+	}
+	if flusher, isFlusher := bn.inner.(network.Flusher); isFlusher {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+//Close stops sending queued steps of broadcasts and closes the network below
+func (bn *Broadcastnetwork) Close() error {
+	bn.closeOnce.Do(func() {
+		close(bn.done)
+	})
+	return bn.inner.Close()
+}
+
+//Handle runs the steps of reliable broadcasts and passes other data to the handler
+func (bn *Broadcastnetwork) Handle(data interface{}, sender int) {
+	message, isMessage := data.(Message)
+	if !isMessage || bn.mode == Plain {
+		bn.handler.Handle(data, sender)
+		return
+	}
+	key, err := bn.codec.Encode(message.Data)
+	if err != nil {
+		bn.handler.HandleError(&network.PeerError{Peer: sender, Err: err})
+		return
+	}
+
+	send, deliver := bn.step(message, string(key), sender)
+	if send != 0 {
+		bn.queue(Message{Step: send, Origin: message.Origin, Sequence: message.Sequence, Data: message.Data})
+	}
+	if deliver {
+		bn.handler.Handle(message.Data, message.Origin)
+	}
+}
+
+//step records message and returns the step to send next, if any, and whether to deliver its data
+func (bn *Broadcastnetwork) step(message Message, key string, sender int) (send Step, deliver bool) {
+	n := len(bn.inner.Parties())
+	t := bn.threshold
+
+	bn.lock.Lock()
+	defer bn.lock.Unlock()
+	if !bn.isParty(message.Origin) {
+		return 0, false
+	}
+	delivered := bn.delivered[message.Origin]
+	if delivered == nil {
+		delivered = &deliveries{above: make(map[uint64]bool)}
+		bn.delivered[message.Origin] = delivered
+	}
+	//Later messages of delivered broadcasts only need to be ignored
+	if delivered.has(message.Sequence) || message.Sequence > delivered.floor+Window {
+		return 0, false
+	}
+	id := instanceID{origin: message.Origin, sequence: message.Sequence}
+	inst, exists := bn.instances[id]
+	if !exists {
+		inst = &instance{
+			echoes:     make(map[int]bool),
+			readies:    make(map[int]bool),
+			echoVotes:  make(map[string]int),
+			readyVotes: make(map[string]int),
+		}
+		bn.instances[id] = inst
+	}
+
+	switch message.Step {
+	case Initial:
+		//Only the origin starts a broadcast, and only once
+		if sender != message.Origin || inst.echoed {
+			return 0, false
+		}
+		inst.echoed = true
+		return Echo, false
+	case Echo:
+		if inst.echoes[sender] {
+			return 0, false
+		}
+		inst.echoes[sender] = true
+		inst.echoVotes[key]++
+		//Two sets of this many parties share an honest one, so no other value gets as many echoes
+		if !inst.readied && 2*inst.echoVotes[key] >= n+t+1 {
+			inst.readied = true
+			return Ready, false
+		}
+	case Ready:
+		if inst.readies[sender] {
+			return 0, false
+		}
+		inst.readies[sender] = true
+		inst.readyVotes[key]++
+		votes := inst.readyVotes[key]
+		//At least one of them is honest
+		if !inst.readied && votes >= t+1 {
+			inst.readied = true
+			send = Ready
+		}
+		if votes >= 2*t+1 {
+			delete(bn.instances, id)
+			delivered.add(message.Sequence)
+			deliver = true
+		}
+	}
+	return
+}
+
+//isParty tells whether index is a party of the network below
+func (bn *Broadcastnetwork) isParty(index int) bool {
+	for _, party := range bn.inner.Parties() {
+		if party == index {
+			return true
+		}
+	}
+	return false
+}
+
+//queue hands a step of a broadcast to the sender goroutine.
+//Handle runs in the dispatcher of the network below, which must not wait for a peer:
+//the dispatcher of the peer may in turn be waiting to send to this party.
+func (bn *Broadcastnetwork) queue(message Message) {
+	bn.startOnce.Do(func() {
+		go bn.sendLoop()
+	})
+	bn.outboxLock.Lock()
+	bn.outbox = append(bn.outbox, message)
+	bn.outboxLock.Unlock()
+	select {
+	case bn.wake <- struct{}{}:
+	default:
+		//The sender goroutine has already been woken
+	}
+}
+
+//sendLoop sends queued steps of broadcasts until the network is closed
+func (bn *Broadcastnetwork) sendLoop() {
+	for {
+		select {
+		case <-bn.wake:
+		case <-bn.done:
+			return
+		}
+		if err := bn.sendQueued(); err != nil {
+			bn.handler.HandleError(err)
+		}
+	}
+}
+
+//sendQueued sends the steps of broadcasts in the outbox and returns the first error
+func (bn *Broadcastnetwork) sendQueued() error {
+	bn.outboxLock.Lock()
+	messages := bn.outbox
+	bn.outbox = nil
+	bn.outboxLock.Unlock()
+	var err error
+	for _, message := range messages {
+		if sendErr := bn.inner.Broadcast(message); err == nil {
+			err = sendErr
+		}
+	}
+	return err
+}
+
+//HandleError ...
+func (bn *Broadcastnetwork) HandleError(err error) {
+	bn.handler.HandleError(err)
+}
+
+//RegisterNetwork sets the network below
+func (bn *Broadcastnetwork) RegisterNetwork(network network.Network) {
+	bn.inner = network
+}
+
+//Index of the handler
+func (bn *Broadcastnetwork) Index() int {
+	return bn.handler.Index()
+}
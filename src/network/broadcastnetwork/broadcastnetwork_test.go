@@ -0,0 +1,270 @@
+package broadcastnetwork
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	".."
+	"../channetwork"
+)
+
+type stringCodec struct{}
+
+func (stringCodec) Encode(data interface{}) ([]byte, error) {
+	return []byte(data.(string)), nil
+}
+
+func (stringCodec) Decode(b []byte) (interface{}, error) {
+	return string(b), nil
+}
+
+type message struct {
+	data   interface{}
+	sender int
+}
+
+type handler struct {
+	index   int
+	network network.Network
+
+	lock     sync.Mutex
+	received []message
+}
+
+func (h *handler) Handle(data interface{}, sender int) {
+	h.lock.Lock()
+	h.received = append(h.received, message{data: data, sender: sender})
+	h.lock.Unlock()
+}
+
+func (h *handler) HandleError(err error) {}
+
+func (h *handler) RegisterNetwork(network network.Network) {
+	h.network = network
+}
+
+func (h *handler) Index() int {
+	return h.index
+}
+
+func (h *handler) messages() []message {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	return append([]message(nil), h.received...)
+}
+
+//setting connects n parties through broadcast networks in mode,
+//except for the corrupt ones, which use the channel networks directly
+func setting(mode Mode, threshold, n int, corrupt ...int) ([]*handler, []*channetwork.Channetwork) {
+	return connect(channetwork.Networks(n), mode, threshold, corrupt...)
+}
+
+//connect connects the parties of networks like setting
+func connect(networks []*channetwork.Channetwork, mode Mode, threshold int, corrupt ...int) ([]*handler, []*channetwork.Channetwork) {
+	n := len(networks)
+	handlers := make([]*handler, n)
+	for i, cn := range networks {
+		handlers[i] = &handler{index: i + 1}
+		isCorrupt := false
+		for _, c := range corrupt {
+			isCorrupt = isCorrupt || c == i+1
+		}
+		if isCorrupt {
+			cn.RegisterHandler(handlers[i])
+			continue
+		}
+		bn := New(mode, threshold, stringCodec{})
+		bn.RegisterHandler(handlers[i])
+		cn.RegisterHandler(bn)
+	}
+	for _, cn := range networks {
+		cn.SetConnections(networks...)
+	}
+	return handlers, networks
+}
+
+func closeAll(networks []*channetwork.Channetwork) {
+	for _, cn := range networks {
+		cn.Close()
+	}
+}
+
+func waitFor(condition func() bool, t *testing.T) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestPlain(t *testing.T) {
+	handlers, networks := setting(Plain, 1, 3)
+	defer closeAll(networks)
+	if err := handlers[0].network.Broadcast("a"); err != nil {
+		t.Fatal(err)
+	}
+	for _, h := range handlers {
+		waitFor(func() bool { return len(h.messages()) == 1 }, t)
+		if m := h.messages()[0]; m != (message{"a", 1}) {
+			t.Errorf("Party %d received %v", h.index, m)
+		}
+	}
+}
+
+func TestReliable(t *testing.T) {
+	handlers, networks := setting(Reliable, 1, 4)
+	defer closeAll(networks)
+	for _, h := range handlers {
+		if err := h.network.Broadcast("from " + string(rune('0'+h.index))); err != nil {
+			t.Fatal(err)
+		}
+	}
+	handlers[0].network.Send("direct", 2)
+
+	for _, h := range handlers {
+		count := 4
+		if h.index == 2 {
+			count++
+		}
+		waitFor(func() bool { return len(h.messages()) == count }, t)
+		for _, m := range h.messages() {
+			if m.data != "direct" && m.data != "from "+string(rune('0'+m.sender)) {
+				t.Errorf("Party %d received %v", h.index, m)
+			}
+		}
+	}
+}
+
+func TestEquivocatingSender(t *testing.T) {
+	handlers, networks := setting(Reliable, 1, 4, 1)
+	defer closeAll(networks)
+	corrupt := handlers[0].network
+
+	//Party 1 starts a broadcast of a at parties 2 and 3 and of b at party 4,
+	//and echoes a to everyone to push it through
+	corrupt.Send(Message{Step: Initial, Origin: 1, Sequence: 1, Data: "a"}, 2)
+	corrupt.Send(Message{Step: Initial, Origin: 1, Sequence: 1, Data: "a"}, 3)
+	corrupt.Send(Message{Step: Initial, Origin: 1, Sequence: 1, Data: "b"}, 4)
+	corrupt.Broadcast(Message{Step: Echo, Origin: 1, Sequence: 1, Data: "a"})
+
+	for _, h := range handlers[1:] {
+		waitFor(func() bool { return len(h.messages()) == 1 }, t)
+		if m := h.messages()[0]; m != (message{"a", 1}) {
+			t.Errorf("Party %d delivered %v", h.index, m)
+		}
+	}
+}
+
+func TestEquivocationWithoutSupportIsNotDelivered(t *testing.T) {
+	handlers, networks := setting(Reliable, 1, 4, 1)
+	defer closeAll(networks)
+	corrupt := handlers[0].network
+
+	//Neither value is echoed by enough parties
+	corrupt.Send(Message{Step: Initial, Origin: 1, Sequence: 1, Data: "a"}, 2)
+	corrupt.Send(Message{Step: Initial, Origin: 1, Sequence: 1, Data: "b"}, 3)
+	corrupt.Send(Message{Step: Initial, Origin: 1, Sequence: 1, Data: "c"}, 4)
+
+	time.Sleep(50 * time.Millisecond)
+	for _, h := range handlers[1:] {
+		if len(h.messages()) != 0 {
+			t.Errorf("Party %d delivered %v", h.index, h.messages())
+		}
+	}
+}
+
+func TestOnlyOriginStartsBroadcast(t *testing.T) {
+	handlers, networks := setting(Reliable, 1, 4, 1)
+	defer closeAll(networks)
+	handlers[0].network.Broadcast(Message{Step: Initial, Origin: 2, Sequence: 1, Data: "forged"})
+
+	time.Sleep(50 * time.Millisecond)
+	for _, h := range handlers[1:] {
+		if len(h.messages()) != 0 {
+			t.Errorf("Party %d delivered %v", h.index, h.messages())
+		}
+	}
+}
+
+func TestTooFewParties(t *testing.T) {
+	handlers, networks := setting(Reliable, 1, 3)
+	defer closeAll(networks)
+	if err := handlers[0].network.Broadcast("a"); err == nil {
+		t.Error("Expected error with fewer than 3t+1 parties")
+	}
+}
+
+//instances counts the broadcasts a party keeps state for
+func instances(h *handler) int {
+	bn := h.network.(*Broadcastnetwork)
+	bn.lock.Lock()
+	defer bn.lock.Unlock()
+	return len(bn.instances)
+}
+
+func TestDeliveredBroadcastsAreRemoved(t *testing.T) {
+	handlers, networks := setting(Reliable, 1, 4)
+	defer closeAll(networks)
+	for i := 0; i < 10; i++ {
+		for _, h := range handlers {
+			h.network.Broadcast("x")
+		}
+	}
+	for _, h := range handlers {
+		waitFor(func() bool { return len(h.messages()) == 40 }, t)
+	}
+	//Echoes and readies arriving after delivery must not bring the instances back
+	for _, h := range handlers {
+		waitFor(func() bool { return instances(h) == 0 }, t)
+	}
+	time.Sleep(50 * time.Millisecond)
+	for _, h := range handlers {
+		if count := instances(h); count != 0 {
+			t.Errorf("Party %d keeps %d instances", h.index, count)
+		}
+		if floor := h.network.(*Broadcastnetwork).delivered[1].floor; floor != 10 {
+			t.Errorf("Party %d delivered the broadcasts of party 1 up to %d", h.index, floor)
+		}
+	}
+}
+
+func TestBroadcastsOutsideWindowAreIgnored(t *testing.T) {
+	handlers, networks := setting(Reliable, 1, 4, 1)
+	defer closeAll(networks)
+	corrupt := handlers[0].network
+	corrupt.Broadcast(Message{Step: Echo, Origin: 2, Sequence: Window + 1, Data: "far"})
+	corrupt.Broadcast(Message{Step: Echo, Origin: 5, Sequence: 1, Data: "nobody"})
+	corrupt.Broadcast(Message{Step: Echo, Origin: 2, Sequence: 1, Data: "near"})
+
+	for _, h := range handlers[1:] {
+		waitFor(func() bool { return instances(h) == 1 }, t)
+	}
+	time.Sleep(20 * time.Millisecond)
+	for _, h := range handlers[1:] {
+		if count := instances(h); count != 1 {
+			t.Errorf("Party %d keeps %d instances", h.index, count)
+		}
+	}
+}
+
+func TestSmallInboxesDoNotDeadlock(t *testing.T) {
+	networks := make([]*channetwork.Channetwork, 4)
+	for i := range networks {
+		networks[i] = channetwork.New(1)
+	}
+	handlers, networks := connect(networks, Reliable, 1)
+	defer closeAll(networks)
+	for _, h := range handlers {
+		go func(h *handler) {
+			for i := 0; i < 50; i++ {
+				h.network.Broadcast("x")
+			}
+		}(h)
+	}
+	for _, h := range handlers {
+		waitFor(func() bool { return len(h.messages()) == 200 }, t)
+	}
+}
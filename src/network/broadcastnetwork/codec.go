@@ -0,0 +1,74 @@
+package broadcastnetwork
+
+import (
+	"encoding/binary"
+	"errors"
+
+	".."
+)
+
+//plainTag marks data sent with Send, other tags are steps of a reliable broadcast
+const plainTag byte = 0
+
+//Codec encodes messages of reliable broadcasts using an inner codec for their data
+type Codec struct {
+	inner network.Codec
+}
+
+//NewCodec ...
+func NewCodec(inner network.Codec) *Codec {
+	return &Codec{inner: inner}
+}
+
+//Encode writes the step, the origin as 4 bytes and the sequence number as 8 bytes followed by the encoded data.
+//Other data is encoded with a zero tag.
+func (c *Codec) Encode(data interface{}) ([]byte, error) {
+	message, isMessage := data.(Message)
+	if !isMessage {
+		encoded, err := c.inner.Encode(data)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte{plainTag}, encoded...), nil
+	}
+	if message.Step < Initial || message.Step > Ready {
+		return nil, errors.New("broadcastnetwork: unknown step")
+	}
+	encoded, err := c.inner.Encode(message.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 13, 13+len(encoded))
+	buf[0] = byte(message.Step)
+	binary.BigEndian.PutUint32(buf[1:], uint32(message.Origin))
+	binary.BigEndian.PutUint64(buf[5:], message.Sequence)
+	return append(buf, encoded...), nil
+}
+
+//Decode ...
+func (c *Codec) Decode(b []byte) (interface{}, error) {
+	if len(b) == 0 {
+		return nil, errors.New("broadcastnetwork: empty message")
+	}
+	step := Step(b[0])
+	if b[0] == plainTag {
+		return c.inner.Decode(b[1:])
+	}
+	if step > Ready {
+		return nil, errors.New("broadcastnetwork: unknown step")
+	}
+	if len(b) < 13 {
+		return nil, errors.New("broadcastnetwork: message too short")
+	}
+	data, err := c.inner.Decode(b[13:])
+	if err != nil {
+		return nil, err
+	}
+	return Message{
+		Step:     step,
+		Origin:   int(binary.BigEndian.Uint32(b[1:])),
+		Sequence: binary.BigEndian.Uint64(b[5:]),
+		Data:     data,
+	}, nil
+}
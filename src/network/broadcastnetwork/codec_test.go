@@ -0,0 +1,45 @@
+package broadcastnetwork
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	codec := NewCodec(stringCodec{})
+	for _, data := range []interface{}{
+		"plain",
+		Message{Step: Initial, Origin: 1, Sequence: 1, Data: "a"},
+		Message{Step: Echo, Origin: 7, Sequence: 1 << 40, Data: ""},
+		Message{Step: Ready, Origin: 2, Sequence: 3, Data: "b"},
+	} {
+		b, err := codec.Encode(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := codec.Decode(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(data, decoded) {
+			t.Errorf("Expected %v after round trip, got %v", data, decoded)
+		}
+	}
+}
+
+func TestCodecRejectsInvalidMessages(t *testing.T) {
+	codec := NewCodec(stringCodec{})
+	if _, err := codec.Encode(Message{Step: 0, Data: "a"}); err == nil {
+		t.Error("Encoded message without step")
+	}
+	b, _ := codec.Encode(Message{Step: Echo, Origin: 1, Sequence: 1, Data: "a"})
+	for length := 0; length < 13; length++ {
+		if _, err := codec.Decode(b[:length]); err == nil {
+			t.Errorf("Decoded message truncated to %d bytes", length)
+		}
+	}
+	b[0] = 9
+	if _, err := codec.Decode(b); err == nil {
+		t.Error("Decoded message with unknown step")
+	}
+}
@@ -0,0 +1,172 @@
+package channetwork
+
+import (
+	"errors"
+	"sort"
+	"sync"
+
+	".."
+)
+
+//DefaultCapacity is the capacity of every inbox of networks created by Networks
+const DefaultCapacity = 1024
+
+//Channetwork connects parties in the same process through channels.
+//Every party has a bounded inbox for each sender, so data from one sender is handled
+//in the order it was sent, and Send blocks while the inbox at the receiver is full.
+//A single dispatcher per party takes data from the inboxes in turn and hands it to the handler.
+type Channetwork struct {
+	capacity int
+	handler  network.Handler
+
+	//lock guards the connections and inboxes, which SetConnections may change while parties send
+	lock        sync.RWMutex
+	connections map[int]*Channetwork
+	inboxes     map[int]chan interface{}
+	senders     []int
+
+	notify    chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+//New creates a network whose inboxes hold up to capacity messages
+func New(capacity int) *Channetwork {
+	return &Channetwork{
+		capacity: capacity,
+		inboxes:  make(map[int]chan interface{}),
+		notify:   make(chan struct{}, 1),
+		done:     make(chan struct{}),
+	}
+}
+
+//Networks creates numberOfNetworks networks with inboxes of DefaultCapacity
+func Networks(numberOfNetworks int) (networks []*Channetwork) {
+	networks = make([]*Channetwork, numberOfNetworks)
+	for i := range networks {
+		networks[i] = New(DefaultCapacity)
+	}
+	return
+}
+
+//RegisterHandler ...
+func (cn *Channetwork) RegisterHandler(handler network.Handler) {
+	cn.handler = handler
+	cn.handler.RegisterNetwork(cn)
+}
+
+//SetConnections creates an inbox for each of networks and starts dispatching.
+//The handlers of all networks must be registered first.
+func (cn *Channetwork) SetConnections(networks ...*Channetwork) {
+	cn.lock.Lock()
+	start := len(cn.senders) == 0
+	cn.connections = make(map[int]*Channetwork, len(networks))
+	for _, other := range networks {
+		index := other.handler.Index()
+		cn.connections[index] = other
+		if _, exists := cn.inboxes[index]; !exists {
+			cn.inboxes[index] = make(chan interface{}, cn.capacity)
+			cn.senders = append(cn.senders, index)
+		}
+	}
+	sort.Ints(cn.senders)
+	cn.lock.Unlock()
+
+	if start {
+		go cn.dispatch()
+	}
+}
+
+//Send puts data in the inbox for this party at receiver, waiting while it is full
+func (cn *Channetwork) Send(data interface{}, receiver int) error {
+	select {
+	case <-cn.done:
+		return network.ErrClosed
+	default:
+	}
+	cn.lock.RLock()
+	connection, exists := cn.connections[receiver]
+	cn.lock.RUnlock()
+	if !exists {
+		return &network.PeerError{Peer: receiver, Err: errors.New("not connected")}
+	}
+	connection.lock.RLock()
+	inbox, exists := connection.inboxes[cn.handler.Index()]
+	connection.lock.RUnlock()
+	if !exists {
+		return &network.PeerError{Peer: receiver, Err: errors.New("not connected")}
+	}
+
+	select {
+	case inbox <- data:
+	case <-connection.done:
+		return &network.PeerError{Peer: receiver, Err: network.ErrClosed}
+	case <-cn.done:
+		return network.ErrClosed
+	}
+	select {
+	case connection.notify <- struct{}{}:
+	default:
+		//The dispatcher has already been notified
+	}
+	return nil
+}
+
+//Broadcast puts data in the inbox for this party at every connected party
+func (cn *Channetwork) Broadcast(data interface{}) error {
+	return network.SendToAll(cn.Send, data, cn.Parties())
+}
+
+//Parties ...
+func (cn *Channetwork) Parties() []int {
+	cn.lock.RLock()
+	defer cn.lock.RUnlock()
+	parties := make([]int, 0, len(cn.connections))
+	for index := range cn.connections {
+		parties = append(parties, index)
+	}
+	sort.Ints(parties)
+	return parties
+}
+
+//dispatch takes one message from each inbox in turn, in the order of the senders' indexes,
+//and waits for a notification when all inboxes are empty
+func (cn *Channetwork) dispatch() {
+	for {
+		cn.lock.RLock()
+		senders := cn.senders
+		cn.lock.RUnlock()
+
+		handled := false
+		for _, sender := range senders {
+			cn.lock.RLock()
+			inbox := cn.inboxes[sender]
+			cn.lock.RUnlock()
+			select {
+			case data := <-inbox:
+				cn.handler.Handle(data, sender)
+				handled = true
+			case <-cn.done:
+				return
+			default:
+			}
+		}
+		if handled {
+			continue
+		}
+
+		select {
+		case <-cn.notify:
+		case <-cn.done:
+			return
+		}
+	}
+}
+
+//Close stops the dispatcher and makes further sends fail. Data not handled yet is dropped.
+func (cn *Channetwork) Close() error {
+	cn.closeOnce.Do(func() {
+		close(cn.done)
+	})
+	return nil
+}
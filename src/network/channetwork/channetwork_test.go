@@ -0,0 +1,185 @@
+package channetwork
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	".."
+)
+
+type message struct {
+	data   interface{}
+	sender int
+}
+
+type recorder struct {
+	index    int
+	network  network.Network
+	lock     sync.Mutex
+	received []message
+	//block holds Handle until it is closed
+	block chan struct{}
+}
+
+func (r *recorder) Handle(data interface{}, sender int) {
+	if r.block != nil {
+		<-r.block
+	}
+	r.lock.Lock()
+	r.received = append(r.received, message{data: data, sender: sender})
+	r.lock.Unlock()
+}
+
+func (r *recorder) HandleError(err error) {}
+
+func (r *recorder) RegisterNetwork(network network.Network) {
+	r.network = network
+}
+
+func (r *recorder) Index() int {
+	return r.index
+}
+
+func (r *recorder) messages() []message {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]message(nil), r.received...)
+}
+
+func setting(networks []*Channetwork) []*recorder {
+	recorders := make([]*recorder, len(networks))
+	for i, cn := range networks {
+		recorders[i] = &recorder{index: i + 1}
+		cn.RegisterHandler(recorders[i])
+	}
+	for _, cn := range networks {
+		cn.SetConnections(networks...)
+	}
+	return recorders
+}
+
+func waitFor(condition func() bool, t *testing.T) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestOrderPerSender(t *testing.T) {
+	n := 4
+	count := 1000
+	networks := make([]*Channetwork, n)
+	for i := range networks {
+		networks[i] = New(8)
+	}
+	recorders := setting(networks)
+	defer func() {
+		for _, cn := range networks {
+			cn.Close()
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for _, r := range recorders[1:] {
+		wg.Add(1)
+		go func(r *recorder) {
+			defer wg.Done()
+			for i := 0; i < count; i++ {
+				if err := r.network.Send(strconv.Itoa(i), 1); err != nil {
+					t.Error(err)
+				}
+			}
+		}(r)
+	}
+	wg.Wait()
+
+	waitFor(func() bool { return len(recorders[0].messages()) == (n-1)*count }, t)
+	next := make(map[int]int)
+	for _, m := range recorders[0].messages() {
+		if m.data != strconv.Itoa(next[m.sender]) {
+			t.Fatalf("Expected %d from party %d, got %v", next[m.sender], m.sender, m.data)
+		}
+		next[m.sender]++
+	}
+}
+
+func TestBackpressure(t *testing.T) {
+	networks := []*Channetwork{New(2), New(2)}
+	recorders := setting(networks)
+	defer networks[0].Close()
+	defer networks[1].Close()
+	recorders[1].block = make(chan struct{})
+
+	//The dispatcher holds one message in Handle and the inbox holds two more
+	sent := make(chan int, 10)
+	go func() {
+		for i := 0; i < 10; i++ {
+			recorders[0].network.Send(i, 2)
+			sent <- i
+		}
+	}()
+	time.Sleep(50 * time.Millisecond)
+	if len(sent) != 3 {
+		t.Errorf("Expected Send to block after 3 messages, %d were sent", len(sent))
+	}
+
+	close(recorders[1].block)
+	waitFor(func() bool { return len(recorders[1].messages()) == 10 }, t)
+}
+
+func TestSendErrors(t *testing.T) {
+	networks := []*Channetwork{New(1), New(1)}
+	recorders := setting(networks)
+	recorders[1].block = make(chan struct{})
+	defer close(recorders[1].block)
+
+	var peerErr *network.PeerError
+	if err := recorders[0].network.Send("x", 3); !errors.As(err, &peerErr) || peerErr.Peer != 3 {
+		t.Errorf("Sending to unknown party returned %v", err)
+	}
+
+	//The first message is held in Handle and the second fills the inbox
+	recorders[0].network.Send("handled", 2)
+	waitFor(func() bool { return len(networks[1].inboxes[1]) == 0 }, t)
+	recorders[0].network.Send("queued", 2)
+	//A sender waiting for the full inbox is released when the receiver closes
+	failed := make(chan error)
+	go func() {
+		failed <- recorders[0].network.Send("blocked", 2)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	networks[1].Close()
+	if err := <-failed; !errors.As(err, &peerErr) || peerErr.Peer != 2 {
+		t.Errorf("Sending to closed party returned %v", err)
+	}
+
+	networks[0].Close()
+	if err := recorders[0].network.Send("x", 2); err != network.ErrClosed {
+		t.Errorf("Sending on closed network returned %v", err)
+	}
+}
+
+func TestSetConnectionsWhileSending(t *testing.T) {
+	networks := Networks(2)
+	recorders := setting(networks)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			recorders[0].network.Send(i, 2)
+			recorders[0].network.Parties()
+		}
+	}()
+	for i := 0; i < 100; i++ {
+		networks[0].SetConnections(networks...)
+	}
+	<-done
+	waitFor(func() bool { return len(recorders[1].messages()) == 100 }, t)
+}
@@ -0,0 +1,208 @@
+package faultnetwork
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	".."
+)
+
+//Action is what happens to a message matched by a rule
+type Action int
+
+const (
+	//Deliver the message unchanged
+	Deliver Action = iota
+	//Drop the message
+	Drop
+	//Duplicate delivers the message twice
+	Duplicate
+	//Reorder holds the message back until the next message on the same link has been sent
+	Reorder
+	//Corrupt replaces the message by the result of the rule's Corrupt function
+	Corrupt
+	//Delay holds the message back until Release is called
+	Delay
+)
+
+//Any matches every sender, receiver or kind in a rule
+const Any = 0
+
+//Rule selects messages by sender, receiver and kind and applies an action to them
+type Rule struct {
+	Sender   int
+	Receiver int
+	Kind     string
+	Action   Action
+	//Probability that a matching message is affected. Zero means always.
+	Probability float64
+	//Corrupt modifies the data of messages when Action is Corrupt
+	Corrupt func(data interface{}) interface{}
+}
+
+func (r *Rule) matches(sender, receiver int, kind string) bool {
+	return (r.Sender == Any || r.Sender == sender) &&
+		(r.Receiver == Any || r.Receiver == receiver) &&
+		(r.Kind == "" || r.Kind == kind)
+}
+
+//Kind names the kind of data used when matching rules
+var Kind = func(data interface{}) string {
+	return fmt.Sprintf("%T", data)
+}
+
+type held struct {
+	data     interface{}
+	receiver int
+}
+
+//Faultnetwork wraps a network and injects faults into the messages sent through it.
+//Rules are tried in the order they were added and the first matching rule applies.
+type Faultnetwork struct {
+	inner   network.Network
+	handler network.Handler
+
+	lock      sync.Mutex
+	rules     []Rule
+	random    *rand.Rand
+	reordered map[int][]interface{}
+	delayed   []held
+}
+
+//New wraps inner
+func New(inner network.Network) *Faultnetwork {
+	return &Faultnetwork{
+		inner:     inner,
+		random:    rand.New(rand.NewSource(time.Now().UnixNano())),
+		reordered: make(map[int][]interface{}),
+	}
+}
+
+//AddRule adds rule after the rules added so far. A rule that corrupts messages needs a Corrupt function.
+func (fn *Faultnetwork) AddRule(rule Rule) error {
+	if rule.Action == Corrupt && rule.Corrupt == nil {
+		return fmt.Errorf("faultnetwork: rule %+v corrupts messages without a Corrupt function", rule)
+	}
+	fn.lock.Lock()
+	fn.rules = append(fn.rules, rule)
+	fn.lock.Unlock()
+	return nil
+}
+
+//ClearRules removes all rules, messages already held back stay held back
+func (fn *Faultnetwork) ClearRules() {
+	fn.lock.Lock()
+	fn.rules = nil
+	fn.lock.Unlock()
+}
+
+//Seed makes probabilistic rules reproducible
+func (fn *Faultnetwork) Seed(seed int64) {
+	fn.lock.Lock()
+	fn.random = rand.New(rand.NewSource(seed))
+	fn.lock.Unlock()
+}
+
+//Release sends all delayed messages and messages still held back for reordering.
+//It returns the first error of the wrapped network.
+func (fn *Faultnetwork) Release() error {
+	fn.lock.Lock()
+	delayed := fn.delayed
+	fn.delayed = nil
+	for receiver, reordered := range fn.reordered {
+		for _, data := range reordered {
+			delayed = append(delayed, held{data: data, receiver: receiver})
+		}
+	}
+	fn.reordered = make(map[int][]interface{})
+	fn.lock.Unlock()
+
+	var err error
+	for _, h := range delayed {
+		if sendErr := fn.inner.Send(h.data, h.receiver); err == nil {
+			err = sendErr
+		}
+	}
+	return err
+}
+
+//Send applies the first matching rule to data.
+//It returns the first error of the wrapped network, dropped data is not an error.
+func (fn *Faultnetwork) Send(data interface{}, receiver int) error {
+	sender := fn.handler.Index()
+	kind := Kind(data)
+
+	fn.lock.Lock()
+	action := Deliver
+	var corrupt func(interface{}) interface{}
+	for _, rule := range fn.rules {
+		if !rule.matches(sender, receiver, kind) {
+			continue
+		}
+		if rule.Probability == 0 || fn.random.Float64() < rule.Probability {
+			action = rule.Action
+			corrupt = rule.Corrupt
+		}
+		break
+	}
+	//Messages held back for reordering are sent after this one
+	reordered := fn.reordered[receiver]
+	delete(fn.reordered, receiver)
+	switch action {
+	case Reorder:
+		fn.reordered[receiver] = append(reordered, data)
+		reordered = nil
+	case Delay:
+		fn.delayed = append(fn.delayed, held{data: data, receiver: receiver})
+	}
+	fn.lock.Unlock()
+
+	var sent []interface{}
+	switch action {
+	case Deliver:
+		sent = []interface{}{data}
+	case Duplicate:
+		sent = []interface{}{data, data}
+	case Corrupt:
+		sent = []interface{}{corrupt(data)}
+	}
+	var err error
+	for _, data := range append(sent, reordered...) {
+		if sendErr := fn.inner.Send(data, receiver); err == nil {
+			err = sendErr
+		}
+	}
+	return err
+}
+
+//Broadcast sends data to every party, applying the rules to each copy
+func (fn *Faultnetwork) Broadcast(data interface{}) error {
+	return network.SendToAll(fn.Send, data, fn.Parties())
+}
+
+//Parties of the wrapped network
+func (fn *Faultnetwork) Parties() []int {
+	return fn.inner.Parties()
+}
+
+//Flush flushes the wrapped network if it holds data back
+func (fn *Faultnetwork) Flush() error {
+	if flusher, isFlusher := fn.inner.(network.Flusher); isFlusher {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+//Close closes the wrapped network, messages still held back are dropped
+func (fn *Faultnetwork) Close() error {
+	return fn.inner.Close()
+}
+
+//RegisterHandler registers handler with the wrapped network, but lets handler send through the fault network
+func (fn *Faultnetwork) RegisterHandler(handler network.Handler) {
+	fn.handler = handler
+	fn.inner.RegisterHandler(handler)
+	handler.RegisterNetwork(fn)
+}
@@ -0,0 +1,196 @@
+package faultnetwork
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	".."
+)
+
+type sent struct {
+	data     interface{}
+	receiver int
+}
+
+//recordingNetwork records what is sent through it
+type recordingNetwork struct {
+	sent []sent
+	err  error
+}
+
+func (rn *recordingNetwork) Send(data interface{}, receiver int) error {
+	rn.sent = append(rn.sent, sent{data: data, receiver: receiver})
+	return rn.err
+}
+
+func (rn *recordingNetwork) Broadcast(data interface{}) error {
+	return network.SendToAll(rn.Send, data, rn.Parties())
+}
+
+func (rn *recordingNetwork) Parties() []int {
+	return []int{1, 2, 3}
+}
+
+func (rn *recordingNetwork) Close() error {
+	return nil
+}
+
+func (rn *recordingNetwork) RegisterHandler(handler network.Handler) {
+	handler.RegisterNetwork(rn)
+}
+
+type handler struct {
+	index   int
+	network network.Network
+}
+
+func (h *handler) Handle(data interface{}, sender int) {}
+
+func (h *handler) HandleError(err error) {}
+
+func (h *handler) RegisterNetwork(network network.Network) {
+	h.network = network
+}
+
+func (h *handler) Index() int {
+	return h.index
+}
+
+func setting(index int) (*handler, *Faultnetwork, *recordingNetwork) {
+	inner := new(recordingNetwork)
+	fn := New(inner)
+	h := &handler{index: index}
+	fn.RegisterHandler(h)
+	return h, fn, inner
+}
+
+func addRule(fn *Faultnetwork, rule Rule, t *testing.T) {
+	if err := fn.AddRule(rule); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func shouldHaveSent(inner *recordingNetwork, expected []sent, t *testing.T) {
+	if len(inner.sent) == 0 && len(expected) == 0 {
+		return
+	}
+	if !reflect.DeepEqual(inner.sent, expected) {
+		t.Errorf("Expected %v to be sent, got %v", expected, inner.sent)
+	}
+}
+
+func TestRegisterHandler(t *testing.T) {
+	h, fn, _ := setting(1)
+	if h.network != fn {
+		t.Error("Handler should send through the fault network")
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	h, _, inner := setting(1)
+	h.network.Send("a", 2)
+	shouldHaveSent(inner, []sent{{"a", 2}}, t)
+}
+
+func TestDrop(t *testing.T) {
+	h, fn, inner := setting(1)
+	addRule(fn, Rule{Receiver: 2, Action: Drop}, t)
+	h.network.Send("a", 2)
+	h.network.Send("b", 3)
+	shouldHaveSent(inner, []sent{{"b", 3}}, t)
+}
+
+func TestDuplicate(t *testing.T) {
+	h, fn, inner := setting(1)
+	addRule(fn, Rule{Action: Duplicate}, t)
+	h.network.Send("a", 2)
+	shouldHaveSent(inner, []sent{{"a", 2}, {"a", 2}}, t)
+}
+
+func TestReorder(t *testing.T) {
+	h, fn, inner := setting(1)
+	addRule(fn, Rule{Kind: "string", Action: Reorder}, t)
+	h.network.Send("a", 2)
+	h.network.Send(1, 3)
+	h.network.Send(2, 2)
+	shouldHaveSent(inner, []sent{{1, 3}, {2, 2}, {"a", 2}}, t)
+}
+
+func TestCorrupt(t *testing.T) {
+	h, fn, inner := setting(1)
+	addRule(fn, Rule{Sender: 1, Action: Corrupt, Corrupt: func(data interface{}) interface{} {
+		return data.(int) + 1
+	}}, t)
+	h.network.Send(41, 2)
+	shouldHaveSent(inner, []sent{{42, 2}}, t)
+}
+
+func TestCorruptNeedsCorruptFunction(t *testing.T) {
+	h, fn, inner := setting(1)
+	if err := fn.AddRule(Rule{Action: Corrupt}); err == nil {
+		t.Error("Added a corrupting rule without a Corrupt function")
+	}
+	h.network.Send("a", 2)
+	shouldHaveSent(inner, []sent{{"a", 2}}, t)
+}
+
+func TestSenderMustMatch(t *testing.T) {
+	h, fn, inner := setting(1)
+	addRule(fn, Rule{Sender: 2, Action: Drop}, t)
+	h.network.Send("a", 3)
+	shouldHaveSent(inner, []sent{{"a", 3}}, t)
+}
+
+func TestDelay(t *testing.T) {
+	h, fn, inner := setting(1)
+	addRule(fn, Rule{Action: Delay}, t)
+	h.network.Send("a", 2)
+	h.network.Send("b", 3)
+	shouldHaveSent(inner, nil, t)
+
+	fn.ClearRules()
+	h.network.Send("c", 2)
+	fn.Release()
+	shouldHaveSent(inner, []sent{{"c", 2}, {"a", 2}, {"b", 3}}, t)
+}
+
+func TestFirstMatchingRuleApplies(t *testing.T) {
+	h, fn, inner := setting(1)
+	addRule(fn, Rule{Receiver: 2, Action: Deliver}, t)
+	addRule(fn, Rule{Action: Drop}, t)
+	h.network.Send("a", 2)
+	h.network.Send("b", 3)
+	shouldHaveSent(inner, []sent{{"a", 2}}, t)
+}
+
+func TestProbability(t *testing.T) {
+	h, fn, inner := setting(1)
+	fn.Seed(1)
+	addRule(fn, Rule{Action: Drop, Probability: 0.5}, t)
+	for i := 0; i < 1000; i++ {
+		h.network.Send(i, 2)
+	}
+	if len(inner.sent) < 400 || len(inner.sent) > 600 {
+		t.Errorf("Dropping with probability 0.5 delivered %d of 1000 messages", len(inner.sent))
+	}
+}
+
+func TestSendReturnsError(t *testing.T) {
+	h, fn, inner := setting(1)
+	inner.err = errors.New("broken")
+	addRule(fn, Rule{Receiver: 3, Action: Drop}, t)
+	if err := h.network.Send("a", 2); err != inner.err {
+		t.Errorf("Expected error of wrapped network, got %v", err)
+	}
+	if err := h.network.Send("b", 3); err != nil {
+		t.Errorf("Dropping returned %v", err)
+	}
+}
+
+func TestBroadcastAppliesRules(t *testing.T) {
+	h, fn, inner := setting(1)
+	addRule(fn, Rule{Receiver: 2, Action: Drop}, t)
+	h.network.Broadcast("a")
+	shouldHaveSent(inner, []sent{{"a", 1}, {"a", 3}}, t)
+}
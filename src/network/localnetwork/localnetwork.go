@@ -1,34 +1,74 @@
 package localnetwork
 
 import (
+	"errors"
+	"sort"
+	"sync"
+
 	".."
 )
 
 type Localnetwork struct {
 	connections map[int]network.Handler
-	handler network.Handler
+	handler     network.Handler
+
+	closeLock sync.RWMutex
+	closed    bool
+}
+
+func (ln *Localnetwork) Send(data interface{}, receiver int) error {
+	ln.closeLock.RLock()
+	defer ln.closeLock.RUnlock()
+	if ln.closed {
+		return network.ErrClosed
+	}
+	connection, exists := ln.connections[receiver]
+	if !exists {
+		return &network.PeerError{Peer: receiver, Err: errors.New("not connected")}
+	}
+	go connection.Handle(data, ln.handler.Index())
+	return nil
 }
 
-func (ln *Localnetwork)Send(data interface{}, receiver int) {
-	go ln.connections[receiver].Handle(data, ln.handler.Index())
+//Broadcast sends data to every connected party
+func (ln *Localnetwork) Broadcast(data interface{}) error {
+	return network.SendToAll(ln.Send, data, ln.Parties())
 }
 
-func (ln *Localnetwork)RegisterHandler(handler network.Handler) {
+//Parties ...
+func (ln *Localnetwork) Parties() []int {
+	parties := make([]int, 0, len(ln.connections))
+	for index := range ln.connections {
+		parties = append(parties, index)
+	}
+	sort.Ints(parties)
+	return parties
+}
+
+func (ln *Localnetwork) RegisterHandler(handler network.Handler) {
 	ln.handler = handler
 	ln.handler.RegisterNetwork(ln)
 }
 
-func (ln *Localnetwork)SetConnections(handlers ...network.Handler) {
+func (ln *Localnetwork) SetConnections(handlers ...network.Handler) {
 	ln.connections = make(map[int]network.Handler)
 	for _, handler := range handlers {
 		ln.connections[handler.Index()] = handler
 	}
 }
 
+//Close makes further sends fail. Data already sent is still delivered.
+func (ln *Localnetwork) Close() error {
+	ln.closeLock.Lock()
+	ln.closed = true
+	ln.closeLock.Unlock()
+	return nil
+}
+
 func LocalNetworks(numberOfNetworks int) (networks []*Localnetwork) {
 	networks = make([]*Localnetwork, numberOfNetworks)
 	for i := range networks {
 		networks[i] = new(Localnetwork)
 	}
 	return
-}
\ No newline at end of file
+}
@@ -1,26 +1,52 @@
-package localnetwork
+package localnetwork_test
 
 import (
+	"errors"
+	"math/big"
+	"testing"
+
 	".."
+	"../../field"
 	"../../player"
-	"testing"
+	"../localnetwork"
 )
 
 func TestSetConnections(t *testing.T) {
-	prime := int64(11)
+	prime := big.NewInt(11)
 	threshold := 2
-	n := 5	
+	n := 5
 	parties := make([]network.Handler, n)
-	for i := range(parties) {
-		parties[i] = player.NewPlayer(prime, threshold, n, i+1)
+	for i := range parties {
+		parties[i] = player.NewPlayer(field.NewBig(prime), threshold, n, i+1)
 	}
 
-	networks := LocalNetworks(n)
-	for i, network := range(networks) {
+	networks := localnetwork.LocalNetworks(n)
+	for i, network := range networks {
 		network.RegisterHandler(parties[i])
 		network.SetConnections(parties...)
 	}
 
-	parties[0].(*player.Player).Send("data", 3)
+	parties[0].(*player.Player[*big.Int]).Send("data", 3)
 
-}
\ No newline at end of file
+}
+
+func TestSendErrors(t *testing.T) {
+	parties := []network.Handler{player.NewPlayer(field.NewBig(big.NewInt(11)), 1, 3, 1), player.NewPlayer(field.NewBig(big.NewInt(11)), 1, 3, 2)}
+	networks := localnetwork.LocalNetworks(2)
+	for i, network := range networks {
+		network.RegisterHandler(parties[i])
+		network.SetConnections(parties...)
+	}
+
+	if err := networks[0].Send("data", 2); err != nil {
+		t.Error(err)
+	}
+	var peerErr *network.PeerError
+	if err := networks[0].Send("data", 3); !errors.As(err, &peerErr) || peerErr.Peer != 3 {
+		t.Errorf("Sending to unknown party returned %v", err)
+	}
+	networks[0].Close()
+	if err := networks[0].Send("data", 2); err != network.ErrClosed {
+		t.Errorf("Sending on closed network returned %v", err)
+	}
+}
@@ -1,15 +1,80 @@
 package network
 
+import (
+	"errors"
+	"strconv"
+)
+
 type (
 	//Network is an abstraction of the method used to connect parties
 	Network interface {
-		Send(data interface{}, receiver int)
+		//Send returns an error if data cannot be delivered to receiver
+		Send(data interface{}, receiver int) error
+		//Broadcast sends data to every party, including this one
+		Broadcast(data interface{}) error
+		//Parties are the indexes of all connected parties, including this one, in increasing order
+		Parties() []int
 		RegisterHandler(handler Handler)
+		//Close delivers data already sent if possible and releases the connections.
+		//Sending after Close returns ErrClosed.
+		Close() error
 	}
 	//Handler handles data received from the network
 	Handler interface {
 		Handle(data interface{}, sender int)
+		//HandleError is called when the network fails outside of a call to Send,
+		//e.g. when the connection from a peer breaks
+		HandleError(err error)
 		RegisterNetwork(network Network)
 		Index() int
 	}
-)
\ No newline at end of file
+	//Flusher is implemented by networks that hold data back until flushed
+	Flusher interface {
+		Flush() error
+	}
+	//ReliableBroadcaster is implemented by networks that tell whether their broadcast is reliable,
+	//i.e. all honest parties deliver the same data from a sender, or none does
+	ReliableBroadcaster interface {
+		ReliableBroadcast() bool
+	}
+	//Codec converts data to and from bytes for networks that leave the process
+	Codec interface {
+		Encode(data interface{}) ([]byte, error)
+		Decode(b []byte) (interface{}, error)
+	}
+)
+
+//ErrClosed is returned when sending on a closed network
+var ErrClosed = errors.New("network: closed")
+
+//PeerError is a failure of the connection to a peer
+type PeerError struct {
+	Peer int
+	Err  error
+}
+
+func (e *PeerError) Error() string {
+	return "network: party " + strconv.Itoa(e.Peer) + ": " + e.Err.Error()
+}
+
+func (e *PeerError) Unwrap() error {
+	return e.Err
+}
+
+//IsReliable tells whether the broadcast of n is reliable
+func IsReliable(n Network) bool {
+	broadcaster, isBroadcaster := n.(ReliableBroadcaster)
+	return isBroadcaster && broadcaster.ReliableBroadcast()
+}
+
+//SendToAll sends data to each of parties with send and returns the first error.
+//Networks without a broadcast of their own use it to implement Broadcast.
+func SendToAll(send func(data interface{}, receiver int) error, data interface{}, parties []int) error {
+	var err error
+	for _, receiver := range parties {
+		if sendErr := send(data, receiver); err == nil {
+			err = sendErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,169 @@
+package recordnetwork
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+
+	".."
+)
+
+//Event types
+const (
+	Sent     = "sent"
+	Received = "received"
+)
+
+//Event is an entry of a transcript: data sent or received by the recording party
+type Event struct {
+	//Time is the logical time of the event at the recording party, counting its events from 1
+	Time     uint64 `json:"time"`
+	Type     string `json:"type"`
+	Sender   int    `json:"sender"`
+	Receiver int    `json:"receiver"`
+	//Payload describes the data for readers of the transcript
+	Payload string `json:"payload"`
+	//Data is the data as encoded by the codec, from which a replay decodes it
+	Data []byte `json:"data"`
+}
+
+//Recordnetwork wraps a network and writes a transcript of everything sent and received through it,
+//one JSON encoded Event per line, in the order the events happen.
+//It sits between a handler and the wrapped network:
+//it is the network of the handler and the handler of the wrapped network.
+type Recordnetwork struct {
+	inner   network.Network
+	handler network.Handler
+	codec   network.Codec
+
+	//Describe writes data in a readable form for the payload of events. By default it uses fmt.
+	Describe func(data interface{}) string
+
+	lock    sync.Mutex
+	encoder *json.Encoder
+	time    uint64
+	err     error
+}
+
+//New wraps inner, writing the transcript to w with data encoded by codec
+func New(inner network.Network, codec network.Codec, w io.Writer) *Recordnetwork {
+	return &Recordnetwork{
+		inner:    inner,
+		codec:    codec,
+		Describe: func(data interface{}) string { return fmt.Sprintf("%v", data) },
+		encoder:  json.NewEncoder(w),
+	}
+}
+
+//record writes an event. Data that cannot be encoded is recorded without it, and the first error is returned by Close.
+func (rn *Recordnetwork) record(eventType string, data interface{}, sender, receiver int) {
+	encoded, err := rn.codec.Encode(data)
+	event := Event{
+		Type:     eventType,
+		Sender:   sender,
+		Receiver: receiver,
+		Payload:  rn.Describe(data),
+		Data:     encoded,
+	}
+
+	rn.lock.Lock()
+	defer rn.lock.Unlock()
+	rn.time++
+	event.Time = rn.time
+	if err == nil {
+		err = rn.encoder.Encode(event)
+	}
+	if err != nil && rn.err == nil {
+		rn.err = err
+	}
+}
+
+//Send records data and sends it through the wrapped network.
+//Data is recorded before it is sent, so it precedes its receipt by this party, and is recorded even if sending fails.
+func (rn *Recordnetwork) Send(data interface{}, receiver int) error {
+	rn.record(Sent, data, rn.handler.Index(), receiver)
+	return rn.inner.Send(data, receiver)
+}
+
+//Broadcast records a copy of data sent to every party and broadcasts it through the wrapped network,
+//so a wrapped network running a broadcast protocol is not bypassed
+func (rn *Recordnetwork) Broadcast(data interface{}) error {
+	for _, receiver := range rn.Parties() {
+		rn.record(Sent, data, rn.handler.Index(), receiver)
+	}
+	return rn.inner.Broadcast(data)
+}
+
+//Parties of the wrapped network
+func (rn *Recordnetwork) Parties() []int {
+	return rn.inner.Parties()
+}
+
+//ReliableBroadcast tells whether the broadcast of the wrapped network is reliable
+func (rn *Recordnetwork) ReliableBroadcast() bool {
+	return network.IsReliable(rn.inner)
+}
+
+//RegisterHandler ...
+func (rn *Recordnetwork) RegisterHandler(handler network.Handler) {
+	rn.handler = handler
+	rn.inner.RegisterHandler(rn)
+	handler.RegisterNetwork(rn)
+}
+
+//Flush flushes the wrapped network if it holds data back
+func (rn *Recordnetwork) Flush() error {
+	if flusher, isFlusher := rn.inner.(network.Flusher); isFlusher {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+//Close closes the wrapped network and returns the first error of writing the transcript
+func (rn *Recordnetwork) Close() error {
+	err := rn.inner.Close()
+	rn.lock.Lock()
+	defer rn.lock.Unlock()
+	if rn.err != nil {
+		return rn.err
+	}
+	return err
+}
+
+//Handle records data and passes it to the handler
+func (rn *Recordnetwork) Handle(data interface{}, sender int) {
+	rn.record(Received, data, sender, rn.handler.Index())
+	rn.handler.Handle(data, sender)
+}
+
+//HandleError ...
+func (rn *Recordnetwork) HandleError(err error) {
+	rn.handler.HandleError(err)
+}
+
+//RegisterNetwork sets the wrapped network
+func (rn *Recordnetwork) RegisterNetwork(network network.Network) {
+	rn.inner = network
+}
+
+//Index of the handler
+func (rn *Recordnetwork) Index() int {
+	return rn.handler.Index()
+}
+
+//ReadTranscript reads the events of a transcript written by a Recordnetwork
+func ReadTranscript(r io.Reader) ([]Event, error) {
+	var events []Event
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, 1<<26)
+	for scanner.Scan() {
+		var event Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("recordnetwork: event %d: %w", len(events)+1, err)
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
@@ -0,0 +1,199 @@
+package recordnetwork
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	".."
+)
+
+type stringCodec struct{}
+
+func (stringCodec) Encode(data interface{}) ([]byte, error) {
+	s, ok := data.(string)
+	if !ok {
+		return nil, errors.New("not a string")
+	}
+	return []byte(s), nil
+}
+
+func (stringCodec) Decode(b []byte) (interface{}, error) {
+	return string(b), nil
+}
+
+//loopback hands data sent to this party straight back to its handler
+type loopback struct {
+	handler    network.Handler
+	broadcasts int
+}
+
+func (l *loopback) Send(data interface{}, receiver int) error {
+	if receiver == 3 {
+		return &network.PeerError{Peer: 3, Err: errors.New("unreachable")}
+	}
+	if receiver == l.handler.Index() {
+		l.handler.Handle(data, receiver)
+	}
+	return nil
+}
+
+func (l *loopback) Broadcast(data interface{}) error {
+	l.broadcasts++
+	return network.SendToAll(l.Send, data, l.Parties())
+}
+
+func (l *loopback) Parties() []int {
+	return []int{1, 2}
+}
+
+func (l *loopback) RegisterHandler(handler network.Handler) {
+	l.handler = handler
+	handler.RegisterNetwork(l)
+}
+
+func (l *loopback) Close() error {
+	return nil
+}
+
+type message struct {
+	data   interface{}
+	sender int
+}
+
+type handler struct {
+	index    int
+	network  network.Network
+	received []message
+}
+
+func (h *handler) Handle(data interface{}, sender int) {
+	h.received = append(h.received, message{data: data, sender: sender})
+}
+
+func (h *handler) HandleError(err error) {}
+
+func (h *handler) RegisterNetwork(network network.Network) {
+	h.network = network
+}
+
+func (h *handler) Index() int {
+	return h.index
+}
+
+func TestRecord(t *testing.T) {
+	var transcript bytes.Buffer
+	inner := new(loopback)
+	rn := New(inner, stringCodec{}, &transcript)
+	rn.Describe = func(data interface{}) string { return "<" + data.(string) + ">" }
+	h := &handler{index: 1}
+	rn.RegisterHandler(h)
+
+	h.network.Broadcast("a")
+	if inner.broadcasts != 1 {
+		t.Errorf("Broadcast reached the wrapped network %d times", inner.broadcasts)
+	}
+	rn.Handle("b", 2)
+	if err := h.network.Send("c", 3); err == nil {
+		t.Error("Expected error of wrapped network")
+	}
+	if err := rn.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := ReadTranscript(&transcript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Event{
+		{Time: 1, Type: Sent, Sender: 1, Receiver: 1, Payload: "<a>", Data: []byte("a")},
+		{Time: 2, Type: Sent, Sender: 1, Receiver: 2, Payload: "<a>", Data: []byte("a")},
+		{Time: 3, Type: Received, Sender: 1, Receiver: 1, Payload: "<a>", Data: []byte("a")},
+		{Time: 4, Type: Received, Sender: 2, Receiver: 1, Payload: "<b>", Data: []byte("b")},
+		{Time: 5, Type: Sent, Sender: 1, Receiver: 3, Payload: "<c>", Data: []byte("c")},
+	}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("Recorded %+v", events)
+	}
+	if !reflect.DeepEqual(h.received, []message{{"a", 1}, {"b", 2}}) {
+		t.Errorf("Handler received %v", h.received)
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestRecordErrors(t *testing.T) {
+	rn := New(new(loopback), stringCodec{}, failingWriter{})
+	h := &handler{index: 1}
+	rn.RegisterHandler(h)
+	if err := h.network.Send("a", 2); err != nil {
+		t.Fatal("Failing to record should not fail sending")
+	}
+	if err := rn.Close(); err == nil {
+		t.Error("Expected Close to return the error of writing the transcript")
+	}
+
+	var transcript bytes.Buffer
+	rn = New(new(loopback), stringCodec{}, &transcript)
+	rn.RegisterHandler(h)
+	h.network.Send(1, 2)
+	if err := rn.Close(); err == nil {
+		t.Error("Expected Close to return the error of encoding data")
+	}
+}
+
+func TestReadTranscriptRejectsGarbage(t *testing.T) {
+	if _, err := ReadTranscript(bytes.NewBufferString("{\"time\":1}\nnot json\n")); err == nil {
+		t.Error("Read transcript with invalid event")
+	}
+}
+
+func TestReplay(t *testing.T) {
+	events := []Event{
+		{Time: 1, Type: Sent, Sender: 2, Receiver: 3, Data: []byte("x")},
+		{Time: 2, Type: Received, Sender: 3, Receiver: 2, Data: []byte("a")},
+		{Time: 3, Type: Received, Sender: 1, Receiver: 2, Data: []byte("b")},
+		{Time: 4, Type: Received, Sender: 3, Receiver: 2, Data: []byte("c")},
+	}
+	rn := NewReplay(events, stringCodec{})
+	h := &handler{index: 2}
+	rn.RegisterHandler(h)
+	if !reflect.DeepEqual(rn.Parties(), []int{1, 2, 3}) {
+		t.Errorf("Parties %v", rn.Parties())
+	}
+
+	if err := rn.Replay(); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(h.received, []message{{"a", 3}, {"b", 1}, {"c", 3}}) {
+		t.Errorf("Handler received %v", h.received)
+	}
+
+	h.network.Send("y", 3)
+	h.network.Broadcast("z")
+	sent := rn.Sent()
+	if len(sent) != 4 || sent[0].Receiver != 3 || string(sent[0].Data) != "y" || sent[3].Time != 4 {
+		t.Errorf("Sent %+v", sent)
+	}
+
+	rn.Close()
+	if err := rn.Replay(); err != network.ErrClosed {
+		t.Errorf("Replay on closed network returned %v", err)
+	}
+	if err := h.network.Send("y", 3); err != network.ErrClosed {
+		t.Errorf("Sending on closed network returned %v", err)
+	}
+}
+
+func TestReplayOfOtherParty(t *testing.T) {
+	rn := NewReplay([]Event{{Time: 1, Type: Received, Sender: 1, Receiver: 3, Data: []byte("a")}}, stringCodec{})
+	rn.RegisterHandler(&handler{index: 2})
+	if err := rn.Replay(); err == nil {
+		t.Error("Replayed data received by another party")
+	}
+}
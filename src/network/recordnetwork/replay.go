@@ -0,0 +1,136 @@
+package recordnetwork
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	".."
+)
+
+//Replaynetwork feeds a handler the data it received in a recorded run, in the recorded order.
+//Nothing is sent: data the handler sends is kept and can be compared with the transcript.
+//
+//As a player receives all its data, including its own shares, through the network,
+//a replayed player computes the same values as in the recorded run and can be run under a debugger.
+type Replaynetwork struct {
+	codec    network.Codec
+	handler  network.Handler
+	received []Event
+	parties  []int
+
+	//Describe writes data in a readable form for the payload of events sent. By default it uses fmt.
+	Describe func(data interface{}) string
+
+	lock   sync.Mutex
+	time   uint64
+	sent   []Event
+	closed bool
+	done   chan struct{}
+}
+
+//NewReplay creates a network replaying the data received by the party that recorded events
+func NewReplay(events []Event, codec network.Codec) *Replaynetwork {
+	rn := &Replaynetwork{
+		codec:    codec,
+		Describe: func(data interface{}) string { return fmt.Sprintf("%v", data) },
+		done:     make(chan struct{}),
+	}
+	seen := make(map[int]bool)
+	for _, event := range events {
+		if event.Type == Received {
+			rn.received = append(rn.received, event)
+		}
+		for _, index := range []int{event.Sender, event.Receiver} {
+			if !seen[index] {
+				seen[index] = true
+				rn.parties = append(rn.parties, index)
+			}
+		}
+	}
+	sort.Ints(rn.parties)
+	return rn
+}
+
+//ReliableBroadcast is true, as broadcasts are replayed as they were delivered in the recorded run
+func (rn *Replaynetwork) ReliableBroadcast() bool {
+	return true
+}
+
+//RegisterHandler ...
+func (rn *Replaynetwork) RegisterHandler(handler network.Handler) {
+	rn.handler = handler
+	rn.handler.RegisterNetwork(rn)
+}
+
+//Replay hands the recorded data to the handler one by one and returns once all of it has been handled,
+//or when the network is closed
+func (rn *Replaynetwork) Replay() error {
+	for _, event := range rn.received {
+		if event.Receiver != rn.handler.Index() {
+			return fmt.Errorf("recordnetwork: event %d was received by party %d, not %d", event.Time, event.Receiver, rn.handler.Index())
+		}
+		data, err := rn.codec.Decode(event.Data)
+		if err != nil {
+			return fmt.Errorf("recordnetwork: event %d: %w", event.Time, err)
+		}
+		select {
+		case <-rn.done:
+			return network.ErrClosed
+		default:
+		}
+		rn.handler.Handle(data, event.Sender)
+	}
+	return nil
+}
+
+//Send keeps data as sent
+func (rn *Replaynetwork) Send(data interface{}, receiver int) error {
+	encoded, err := rn.codec.Encode(data)
+	if err != nil {
+		return err
+	}
+	rn.lock.Lock()
+	defer rn.lock.Unlock()
+	if rn.closed {
+		return network.ErrClosed
+	}
+	rn.time++
+	rn.sent = append(rn.sent, Event{
+		Time:     rn.time,
+		Type:     Sent,
+		Sender:   rn.handler.Index(),
+		Receiver: receiver,
+		Payload:  rn.Describe(data),
+		Data:     encoded,
+	})
+	return nil
+}
+
+//Broadcast keeps a copy of data for every party
+func (rn *Replaynetwork) Broadcast(data interface{}) error {
+	return network.SendToAll(rn.Send, data, rn.Parties())
+}
+
+//Parties are the parties appearing in the transcript
+func (rn *Replaynetwork) Parties() []int {
+	return append([]int(nil), rn.parties...)
+}
+
+//Sent is the data sent so far, numbered in the order it was sent
+func (rn *Replaynetwork) Sent() []Event {
+	rn.lock.Lock()
+	defer rn.lock.Unlock()
+	return append([]Event(nil), rn.sent...)
+}
+
+//Close stops the replay
+func (rn *Replaynetwork) Close() error {
+	rn.lock.Lock()
+	defer rn.lock.Unlock()
+	if !rn.closed {
+		rn.closed = true
+		close(rn.done)
+	}
+	return nil
+}
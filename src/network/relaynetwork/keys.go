@@ -0,0 +1,143 @@
+package relaynetwork
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//sessionSize is the size of the random id a party picks for the frames it sends to a peer
+const sessionSize = 16
+
+//Keys are the X25519 keys that protect data between parties from the relay.
+//Every pair of parties derives its keys from its static keys, so a frame that decrypts
+//with the keys of a pair was sent by the other party of the pair.
+type Keys struct {
+	//Private is the key of this party
+	Private *ecdh.PrivateKey
+	//Peers holds the public key of every party by index, including this one
+	Peers map[int]*ecdh.PublicKey
+}
+
+//GenerateKey creates a new private key
+func GenerateKey() (*ecdh.PrivateKey, error) {
+	return ecdh.X25519().GenerateKey(rand.Reader)
+}
+
+//LoadKeys reads hex encoded keys, the private key of this party and the public keys of all parties
+func LoadKeys(privateFile string, publicFiles map[int]string) (Keys, error) {
+	var keys Keys
+	b, err := readHex(privateFile)
+	if err != nil {
+		return keys, err
+	}
+	keys.Private, err = ecdh.X25519().NewPrivateKey(b)
+	if err != nil {
+		return keys, fmt.Errorf("relaynetwork: %s: %w", privateFile, err)
+	}
+
+	keys.Peers = make(map[int]*ecdh.PublicKey, len(publicFiles))
+	for index, path := range publicFiles {
+		b, err := readHex(path)
+		if err != nil {
+			return keys, err
+		}
+		keys.Peers[index], err = ecdh.X25519().NewPublicKey(b)
+		if err != nil {
+			return keys, fmt.Errorf("relaynetwork: %s: %w", path, err)
+		}
+	}
+	return keys, nil
+}
+
+func readHex(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return hex.DecodeString(strings.TrimSpace(string(content)))
+}
+
+//link encrypts the frames from one party to another within a session
+type link struct {
+	session [sessionSize]byte
+	aead    cipher.AEAD
+	//counter is the number of the last frame sealed or opened
+	counter uint64
+}
+
+//newLink derives the key of the frames from sender to receiver in session.
+//peer is the one of them that is not this party.
+func newLink(keys Keys, sender, receiver, peer int, session [sessionSize]byte) (*link, error) {
+	public, exists := keys.Peers[peer]
+	if !exists {
+		return nil, errors.New("no public key")
+	}
+	secret, err := keys.Private.ECDH(public)
+	if err != nil {
+		return nil, err
+	}
+	key, err := hkdf.Key(sha256.New, secret, session[:], "relaynetwork "+strconv.Itoa(sender)+" to "+strconv.Itoa(receiver), 32)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &link{session: session, aead: aead}, nil
+}
+
+//header is authenticated along with the data, so the relay cannot redirect frames
+func header(sender, receiver int, session [sessionSize]byte, counter uint64) []byte {
+	h := make([]byte, 8, 8+sessionSize+8)
+	binary.BigEndian.PutUint32(h, uint32(sender))
+	binary.BigEndian.PutUint32(h[4:], uint32(receiver))
+	h = append(h, session[:]...)
+	return binary.BigEndian.AppendUint64(h, counter)
+}
+
+//seal returns the session, the counter and the encrypted data
+func (l *link) seal(sender, receiver int, data []byte) []byte {
+	l.counter++
+	h := header(sender, receiver, l.session, l.counter)
+	nonce := make([]byte, l.aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], l.counter)
+	payload := make([]byte, 0, sessionSize+8+len(data)+l.aead.Overhead())
+	payload = append(payload, h[8:]...)
+	return l.aead.Seal(payload, nonce, data, h)
+}
+
+//open decrypts a frame sealed in the session of the link. Frames must arrive in the order they were sealed.
+func (l *link) open(sender, receiver int, payload []byte) ([]byte, error) {
+	if len(payload) < sessionSize+8 {
+		return nil, errors.New("frame too short")
+	}
+	counter := binary.BigEndian.Uint64(payload[sessionSize:])
+	if counter <= l.counter {
+		return nil, errors.New("frame replayed")
+	}
+	h := header(sender, receiver, l.session, counter)
+	nonce := make([]byte, l.aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], counter)
+	data, err := l.aead.Open(nil, nonce, payload[sessionSize+8:], h)
+	if err != nil {
+		return nil, err
+	}
+	l.counter = counter
+	return data, nil
+}
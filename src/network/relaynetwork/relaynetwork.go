@@ -0,0 +1,255 @@
+package relaynetwork
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	".."
+)
+
+//Relaynetwork connects a party to its peers through a relay, which the party dials out to,
+//so parties do not need to accept connections.
+//Data is encrypted and authenticated between the parties with keys derived from Keys,
+//so the relay only sees the indexes of the parties and the size of the frames.
+//
+//Every frame carries the random session the sender picked for the receiver and a counter,
+//and a receiver rejects frames that are replayed within a session or from an earlier session.
+//The relay can still drop frames, which makes the protocol wait until the relay closes the connection.
+//A connection to the relay that stays silent for HeartbeatTimeout is reported as broken.
+type Relaynetwork struct {
+	codec   network.Codec
+	handler network.Handler
+	keys    Keys
+	//Heartbeat settings when the network was created
+	heartbeatInterval time.Duration
+	heartbeatTimeout  time.Duration
+
+	//lock guards the connection and the outgoing links, so frames are written in the order they are sealed
+	lock     sync.Mutex
+	conn     net.Conn
+	outgoing map[int]*link
+	closed   bool
+
+	incomingLock sync.Mutex
+	incoming     map[int]*link
+	//sessions are all sessions seen from each sender
+	sessions map[int]map[[sessionSize]byte]bool
+}
+
+var errNotConnected = errors.New("relaynetwork: not connected to the relay")
+
+//New creates a network using codec to serialize data and keys to protect it
+func New(codec network.Codec, keys Keys) *Relaynetwork {
+	return &Relaynetwork{
+		codec:             codec,
+		keys:              keys,
+		heartbeatInterval: HeartbeatInterval,
+		heartbeatTimeout:  HeartbeatTimeout,
+		outgoing:          make(map[int]*link),
+		incoming:          make(map[int]*link),
+		sessions:          make(map[int]map[[sessionSize]byte]bool),
+	}
+}
+
+//RegisterHandler ...
+func (rn *Relaynetwork) RegisterHandler(handler network.Handler) {
+	rn.handler = handler
+	rn.handler.RegisterNetwork(rn)
+}
+
+//Connect dials the relay at address and registers the index of the handler with it
+func (rn *Relaynetwork) Connect(address string) error {
+	conn, err := net.Dial("tcp", address)
+	if err != nil {
+		return err
+	}
+	hello := make([]byte, 4)
+	binary.BigEndian.PutUint32(hello, uint32(rn.handler.Index()))
+	if _, err := conn.Write(hello); err != nil {
+		conn.Close()
+		return err
+	}
+
+	rn.lock.Lock()
+	if rn.closed {
+		rn.lock.Unlock()
+		conn.Close()
+		return network.ErrClosed
+	}
+	rn.conn = conn
+	rn.lock.Unlock()
+	go rn.receive(conn)
+	if rn.heartbeatInterval > 0 {
+		go rn.heartbeat(conn)
+	}
+	return nil
+}
+
+//heartbeat writes a heartbeat to the relay every heartbeat interval until conn is closed
+func (rn *Relaynetwork) heartbeat(conn net.Conn) {
+	ticker := time.NewTicker(rn.heartbeatInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		rn.lock.Lock()
+		if rn.closed || rn.conn != conn {
+			rn.lock.Unlock()
+			return
+		}
+		err := writeFrame(conn, heartbeat)
+		rn.lock.Unlock()
+		if err != nil {
+			//receive reports the broken connection
+			return
+		}
+	}
+}
+
+//Send encrypts data for receiver and writes it to the relay
+func (rn *Relaynetwork) Send(data interface{}, receiver int) error {
+	b, err := rn.codec.Encode(data)
+	if err != nil {
+		return err
+	}
+
+	rn.lock.Lock()
+	defer rn.lock.Unlock()
+	if rn.closed {
+		return network.ErrClosed
+	}
+	if rn.conn == nil {
+		return errNotConnected
+	}
+	l, exists := rn.outgoing[receiver]
+	if !exists {
+		var session [sessionSize]byte
+		if _, err := rand.Read(session[:]); err != nil {
+			return err
+		}
+		l, err = newLink(rn.keys, rn.handler.Index(), receiver, receiver, session)
+		if err != nil {
+			return &network.PeerError{Peer: receiver, Err: err}
+		}
+		rn.outgoing[receiver] = l
+	}
+
+	payload := l.seal(rn.handler.Index(), receiver, b)
+	frame := make([]byte, 4, 4+len(payload))
+	binary.BigEndian.PutUint32(frame, uint32(receiver))
+	frame = append(frame, payload...)
+	if len(frame) > MaxFrameSize {
+		return fmt.Errorf("relaynetwork: frame of %d bytes exceeds MaxFrameSize", len(frame))
+	}
+	if err := writeFrame(rn.conn, frame); err != nil {
+		return &network.PeerError{Peer: receiver, Err: err}
+	}
+	return nil
+}
+
+//Broadcast sends data to every party with a public key, including this one
+func (rn *Relaynetwork) Broadcast(data interface{}) error {
+	return network.SendToAll(rn.Send, data, rn.Parties())
+}
+
+//Parties are the parties with a public key
+func (rn *Relaynetwork) Parties() []int {
+	parties := make([]int, 0, len(rn.keys.Peers))
+	for index := range rn.keys.Peers {
+		parties = append(parties, index)
+	}
+	sort.Ints(parties)
+	return parties
+}
+
+//Close disconnects from the relay
+func (rn *Relaynetwork) Close() error {
+	rn.lock.Lock()
+	defer rn.lock.Unlock()
+	rn.closed = true
+	if rn.conn == nil {
+		return nil
+	}
+	return rn.conn.Close()
+}
+
+func (rn *Relaynetwork) isClosed() bool {
+	rn.lock.Lock()
+	defer rn.lock.Unlock()
+	return rn.closed
+}
+
+//receive decrypts the frames forwarded by the relay and passes their data to the handler
+func (rn *Relaynetwork) receive(conn net.Conn) {
+	defer conn.Close()
+	for {
+		if rn.heartbeatInterval > 0 {
+			conn.SetReadDeadline(time.Now().Add(rn.heartbeatTimeout))
+		}
+		frame, err := readFrame(conn)
+		if err != nil {
+			if !rn.isClosed() {
+				rn.handler.HandleError(fmt.Errorf("relaynetwork: connection to the relay: %w", err))
+			}
+			return
+		}
+		if len(frame) < 4 {
+			rn.handler.HandleError(errors.New("relaynetwork: frame without sender"))
+			continue
+		}
+		sender := int(binary.BigEndian.Uint32(frame))
+		if sender == 0 {
+			//Answer to a heartbeat
+			continue
+		}
+		b, err := rn.open(sender, frame[4:])
+		if err != nil {
+			rn.handler.HandleError(&network.PeerError{Peer: sender, Err: err})
+			continue
+		}
+		data, err := rn.codec.Decode(b)
+		if err != nil {
+			rn.handler.HandleError(&network.PeerError{Peer: sender, Err: err})
+			continue
+		}
+		rn.handler.Handle(data, sender)
+	}
+}
+
+//open decrypts payload from sender. A new session replaces the current one
+//once a frame of it decrypts, and sessions seen before are rejected.
+func (rn *Relaynetwork) open(sender int, payload []byte) ([]byte, error) {
+	if len(payload) < sessionSize {
+		return nil, errors.New("frame too short")
+	}
+	var session [sessionSize]byte
+	copy(session[:], payload)
+
+	rn.incomingLock.Lock()
+	defer rn.incomingLock.Unlock()
+	l, exists := rn.incoming[sender]
+	if exists && l.session == session {
+		return l.open(sender, rn.handler.Index(), payload)
+	}
+	if rn.sessions[sender][session] {
+		return nil, errors.New("frame from an earlier session")
+	}
+	l, err := newLink(rn.keys, sender, rn.handler.Index(), sender, session)
+	if err != nil {
+		return nil, err
+	}
+	b, err := l.open(sender, rn.handler.Index(), payload)
+	if err != nil {
+		return nil, err
+	}
+	if rn.sessions[sender] == nil {
+		rn.sessions[sender] = make(map[[sessionSize]byte]bool)
+	}
+	rn.sessions[sender][session] = true
+	rn.incoming[sender] = l
+	return b, nil
+}
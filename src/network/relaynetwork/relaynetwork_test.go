@@ -0,0 +1,368 @@
+package relaynetwork
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	".."
+)
+
+type stringCodec struct{}
+
+func (stringCodec) Encode(data interface{}) ([]byte, error) {
+	s, ok := data.(string)
+	if !ok {
+		return nil, errors.New("not a string")
+	}
+	return []byte(s), nil
+}
+
+func (stringCodec) Decode(b []byte) (interface{}, error) {
+	return string(b), nil
+}
+
+type message struct {
+	data   interface{}
+	sender int
+}
+
+type recorder struct {
+	index    int
+	network  network.Network
+	lock     sync.Mutex
+	received []message
+	errors   []error
+}
+
+func (r *recorder) Handle(data interface{}, sender int) {
+	r.lock.Lock()
+	r.received = append(r.received, message{data: data, sender: sender})
+	r.lock.Unlock()
+}
+
+func (r *recorder) HandleError(err error) {
+	r.lock.Lock()
+	r.errors = append(r.errors, err)
+	r.lock.Unlock()
+}
+
+func (r *recorder) RegisterNetwork(network network.Network) {
+	r.network = network
+}
+
+func (r *recorder) Index() int {
+	return r.index
+}
+
+func (r *recorder) messages() []message {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]message(nil), r.received...)
+}
+
+func (r *recorder) failures() []error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]error(nil), r.errors...)
+}
+
+func waitFor(condition func() bool, t *testing.T) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func startRelay(t *testing.T) *Server {
+	server := NewServer()
+	if err := server.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	return server
+}
+
+func generateKeys(n int, t *testing.T) []Keys {
+	privates := make([]*ecdh.PrivateKey, n)
+	publics := make(map[int]*ecdh.PublicKey, n)
+	for i := range privates {
+		var err error
+		privates[i], err = GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		publics[i+1] = privates[i].PublicKey()
+	}
+	keys := make([]Keys, n)
+	for i := range keys {
+		keys[i] = Keys{Private: privates[i], Peers: publics}
+	}
+	return keys
+}
+
+//setting creates a party for each of keys, connected to server unless listed in offline
+func setting(server *Server, keys []Keys, offline ...int) ([]*Relaynetwork, []*recorder) {
+	networks := make([]*Relaynetwork, len(keys))
+	recorders := make([]*recorder, len(keys))
+	for i := range keys {
+		recorders[i] = &recorder{index: i + 1}
+		networks[i] = New(stringCodec{}, keys[i])
+		networks[i].RegisterHandler(recorders[i])
+		connect := true
+		for _, index := range offline {
+			connect = connect && index != i+1
+		}
+		if connect {
+			networks[i].Connect(server.Addr().String())
+		}
+	}
+	return networks, recorders
+}
+
+//rawConnect registers a plain connection with the relay as party index
+func rawConnect(server *Server, index int, t *testing.T) net.Conn {
+	conn, err := net.Dial("tcp", server.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hello := make([]byte, 4)
+	binary.BigEndian.PutUint32(hello, uint32(index))
+	conn.Write(hello)
+	return conn
+}
+
+func TestSend(t *testing.T) {
+	server := startRelay(t)
+	defer server.Close()
+	networks, recorders := setting(server, generateKeys(3, t))
+	defer func() {
+		for _, rn := range networks {
+			rn.Close()
+		}
+	}()
+
+	for i := 0; i < 100; i++ {
+		if err := recorders[0].network.Send(string(rune('a'+i%26)), 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+	recorders[2].network.Broadcast("all")
+
+	waitFor(func() bool { return len(recorders[1].messages()) == 101 }, t)
+	next := 0
+	for _, m := range recorders[1].messages() {
+		if m.sender == 3 {
+			if m.data != "all" {
+				t.Errorf("Received %v from party 3", m.data)
+			}
+			continue
+		}
+		if m.sender != 1 || m.data != string(rune('a'+next%26)) {
+			t.Fatalf("Expected %c from party 1, got %v from party %d", 'a'+next%26, m.data, m.sender)
+		}
+		next++
+	}
+	for _, r := range []*recorder{recorders[0], recorders[2]} {
+		waitFor(func() bool { return len(r.messages()) == 1 }, t)
+		if m := r.messages()[0]; m != (message{"all", 3}) {
+			t.Errorf("Party %d received %v", r.index, m)
+		}
+	}
+}
+
+func TestHeldUntilConnected(t *testing.T) {
+	server := startRelay(t)
+	defer server.Close()
+	keys := generateKeys(2, t)
+	networks, recorders := setting(server, keys, 2)
+	defer networks[0].Close()
+	defer networks[1].Close()
+
+	recorders[0].network.Send("early", 2)
+	time.Sleep(10 * time.Millisecond)
+	if err := networks[1].Connect(server.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(func() bool { return len(recorders[1].messages()) == 1 }, t)
+}
+
+func TestRelaySeesOnlyCiphertext(t *testing.T) {
+	server := startRelay(t)
+	defer server.Close()
+	networks, recorders := setting(server, generateKeys(2, t), 2)
+	defer networks[0].Close()
+
+	//The relay does not authenticate parties, so anyone can receive the frames for party 2
+	conn := rawConnect(server, 2, t)
+	defer conn.Close()
+	secret := "the secret share"
+	recorders[0].network.Send(secret, 2)
+
+	frame, err := readFrame(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if binary.BigEndian.Uint32(frame) != 1 {
+		t.Errorf("Frame from %d, expected 1", binary.BigEndian.Uint32(frame))
+	}
+	if bytes.Contains(frame, []byte(secret)) {
+		t.Error("Relay forwarded the plaintext")
+	}
+
+	//Nor can the frame be read with the keys of other parties
+	other := generateKeys(2, t)[1]
+	other.Peers = networks[0].keys.Peers
+	receiver := New(stringCodec{}, other)
+	receiver.RegisterHandler(&recorder{index: 2})
+	if _, err := receiver.open(1, frame[4:]); err == nil {
+		t.Error("Opened frame without the key of the receiver")
+	}
+}
+
+func TestForgedAndReplayedFramesAreRejected(t *testing.T) {
+	server := startRelay(t)
+	defer server.Close()
+	networks, recorders := setting(server, generateKeys(3, t), 2)
+	defer networks[0].Close()
+	defer networks[2].Close()
+
+	conn := rawConnect(server, 2, t)
+	recorders[0].network.Send("a", 2)
+	frame, err := readFrame(conn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	//Frame from party 1 to party 2 replayed by party 2 to party 3
+	replayed := append([]byte{0, 0, 0, 3}, frame[4:]...)
+	forged := append([]byte{0, 0, 0, 3}, bytes.Repeat([]byte{1}, 64)...)
+	conn = rawConnect(server, 2, t)
+	defer conn.Close()
+	writeFrame(conn, replayed)
+	writeFrame(conn, forged)
+
+	waitFor(func() bool { return len(recorders[2].failures()) == 2 }, t)
+	if len(recorders[2].messages()) != 0 {
+		t.Errorf("Party 3 handled %v", recorders[2].messages())
+	}
+	var peerErr *network.PeerError
+	for _, err := range recorders[2].failures() {
+		if !errors.As(err, &peerErr) || peerErr.Peer != 2 {
+			t.Errorf("Expected error naming party 2, got %v", err)
+		}
+	}
+}
+
+func TestReplayWithinSession(t *testing.T) {
+	keys := generateKeys(2, t)
+	sender := New(stringCodec{}, keys[0])
+	sender.RegisterHandler(&recorder{index: 1})
+	receiver := New(stringCodec{}, keys[1])
+	receiver.RegisterHandler(&recorder{index: 2})
+
+	var session [sessionSize]byte
+	l, err := newLink(keys[0], 1, 2, 2, session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := l.seal(1, 2, []byte("first"))
+	second := l.seal(1, 2, []byte("second"))
+
+	if b, err := receiver.open(1, first); err != nil || string(b) != "first" {
+		t.Fatalf("Opened %q, %v", b, err)
+	}
+	if b, err := receiver.open(1, second); err != nil || string(b) != "second" {
+		t.Fatalf("Opened %q, %v", b, err)
+	}
+	if _, err := receiver.open(1, first); err == nil {
+		t.Error("Opened a replayed frame")
+	}
+
+	//A new session replaces the old one, which cannot be used again
+	session[0] = 1
+	l, _ = newLink(keys[0], 1, 2, 2, session)
+	if _, err := receiver.open(1, l.seal(1, 2, []byte("new"))); err != nil {
+		t.Fatal(err)
+	}
+	previous, _ := newLink(keys[0], 1, 2, 2, [sessionSize]byte{})
+	previous.counter = 2
+	if _, err := receiver.open(1, previous.seal(1, 2, []byte("old"))); err == nil {
+		t.Error("Opened a frame of an earlier session")
+	}
+}
+
+func TestSendErrors(t *testing.T) {
+	server := startRelay(t)
+	networks, recorders := setting(server, generateKeys(2, t))
+	if err := recorders[0].network.Send("x", 5); err == nil {
+		t.Error("Sent to party without public key")
+	}
+
+	unconnected := New(stringCodec{}, generateKeys(1, t)[0])
+	unconnected.RegisterHandler(&recorder{index: 1})
+	if err := unconnected.Send("x", 1); err == nil {
+		t.Error("Sent without connecting to the relay")
+	}
+
+	//Losing the relay is reported to the handler
+	server.Close()
+	waitFor(func() bool { return len(recorders[1].failures()) > 0 }, t)
+
+	networks[0].Close()
+	if err := recorders[0].network.Send("x", 2); err != network.ErrClosed {
+		t.Errorf("Sending on closed network returned %v", err)
+	}
+}
+
+func TestHeartbeats(t *testing.T) {
+	defer func(interval, timeout time.Duration) {
+		HeartbeatInterval, HeartbeatTimeout = interval, timeout
+	}(HeartbeatInterval, HeartbeatTimeout)
+	HeartbeatInterval, HeartbeatTimeout = 10*time.Millisecond, 50*time.Millisecond
+
+	server := startRelay(t)
+	defer server.Close()
+	networks, recorders := setting(server, generateKeys(2, t))
+	defer networks[0].Close()
+	defer networks[1].Close()
+	time.Sleep(300 * time.Millisecond)
+	recorders[0].network.Send("after idling", 2)
+	waitFor(func() bool { return len(recorders[1].messages()) == 1 }, t)
+	for _, r := range recorders {
+		if len(r.failures()) != 0 {
+			t.Errorf("Party %d failed with %v", r.index, r.failures())
+		}
+	}
+
+	//A relay that accepts the party but never answers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			io.Copy(io.Discard, conn)
+		}
+	}()
+	silent := New(stringCodec{}, generateKeys(1, t)[0])
+	r := &recorder{index: 1}
+	silent.RegisterHandler(r)
+	if err := silent.Connect(listener.Addr().String()); err != nil {
+		t.Fatal(err)
+	}
+	defer silent.Close()
+	waitFor(func() bool { return len(r.failures()) > 0 }, t)
+}
@@ -0,0 +1,231 @@
+package relaynetwork
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+)
+
+//MaxFrameSize is the largest frame the relay and parties accept
+const MaxFrameSize = 1 << 26
+
+//MaxPending is the number of frames the relay holds for a party that is not connected.
+//Further frames for it are dropped.
+const MaxPending = 1 << 16
+
+var (
+	//HeartbeatInterval is how often a party checks that its connection to the relay is alive. Zero disables heartbeats.
+	//Heartbeat settings apply to networks created after they are set.
+	HeartbeatInterval = 5 * time.Second
+	//HeartbeatTimeout bounds how long a connection to the relay may stay silent before it is considered broken
+	HeartbeatTimeout = 15 * time.Second
+)
+
+//heartbeat is the frame a party sends to check its connection. As no party has index 0 the relay answers it itself.
+var heartbeat = []byte{0, 0, 0, 0}
+
+//Server forwards frames between parties that connect to it.
+//A party connects by sending its index as a 4 byte big endian integer and then sends frames,
+//each prefixed by its length as a 4 byte big endian integer and starting with the index of the receiver.
+//The relay replaces the index of the receiver by the index of the sender and writes the frame to the receiver.
+//Frames for receiver 0 are heartbeats, which the relay writes back to the sender.
+//
+//The relay does not authenticate parties, so it can be trusted with availability only:
+//parties encrypt and authenticate the data in the frames end to end.
+type Server struct {
+	listener net.Listener
+
+	lock    sync.Mutex
+	clients map[int]*client
+	pending map[int][][]byte
+	conns   map[net.Conn]bool
+	closed  bool
+}
+
+type client struct {
+	lock sync.Mutex
+	conn net.Conn
+}
+
+//NewServer ...
+func NewServer() *Server {
+	return &Server{
+		clients: make(map[int]*client),
+		pending: make(map[int][][]byte),
+		conns:   make(map[net.Conn]bool),
+	}
+}
+
+//Listen starts accepting parties on address
+func (s *Server) Listen(address string) error {
+	listener, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	s.listener = listener
+	go s.accept()
+	return nil
+}
+
+//Addr is the address the relay listens on
+func (s *Server) Addr() net.Addr {
+	return s.listener.Addr()
+}
+
+//Close stops listening and disconnects all parties
+func (s *Server) Close() error {
+	s.lock.Lock()
+	s.closed = true
+	for conn := range s.conns {
+		conn.Close()
+	}
+	s.conns = nil
+	s.lock.Unlock()
+	if s.listener != nil {
+		return s.listener.Close()
+	}
+	return nil
+}
+
+func (s *Server) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.lock.Lock()
+		if s.closed {
+			s.lock.Unlock()
+			conn.Close()
+			return
+		}
+		s.conns[conn] = true
+		s.lock.Unlock()
+		go s.serve(conn)
+	}
+}
+
+//serve registers the party on conn and forwards its frames
+func (s *Server) serve(conn net.Conn) {
+	defer s.disconnect(conn)
+	hello := make([]byte, 4)
+	if _, err := io.ReadFull(conn, hello); err != nil {
+		return
+	}
+	index := int(binary.BigEndian.Uint32(hello))
+	c := s.register(index, conn)
+	defer s.unregister(index, c)
+
+	for {
+		frame, err := readFrame(conn)
+		if err != nil {
+			return
+		}
+		if len(frame) < 4 {
+			log.Println("relaynetwork: frame without receiver from party", index)
+			return
+		}
+		receiver := int(binary.BigEndian.Uint32(frame))
+		if receiver == 0 {
+			c.write(heartbeat)
+			continue
+		}
+		binary.BigEndian.PutUint32(frame, uint32(index))
+		s.forward(receiver, frame)
+	}
+}
+
+//register makes conn the connection of party index and writes the frames held for it.
+//A party that connects again replaces its previous connection.
+func (s *Server) register(index int, conn net.Conn) *client {
+	c := &client{conn: conn}
+	//Frames forwarded meanwhile wait for the client, so they are written after those held
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	s.lock.Lock()
+	if previous, exists := s.clients[index]; exists {
+		previous.conn.Close()
+	}
+	s.clients[index] = c
+	held := s.pending[index]
+	delete(s.pending, index)
+	s.lock.Unlock()
+
+	for _, frame := range held {
+		if err := writeFrame(conn, frame); err != nil {
+			conn.Close()
+			return c
+		}
+	}
+	return c
+}
+
+func (s *Server) unregister(index int, c *client) {
+	s.lock.Lock()
+	if s.clients[index] == c {
+		delete(s.clients, index)
+	}
+	s.lock.Unlock()
+}
+
+func (s *Server) disconnect(conn net.Conn) {
+	conn.Close()
+	s.lock.Lock()
+	delete(s.conns, conn)
+	s.lock.Unlock()
+}
+
+//forward writes frame to receiver, or holds it until receiver connects
+func (s *Server) forward(receiver int, frame []byte) {
+	s.lock.Lock()
+	c, exists := s.clients[receiver]
+	if !exists {
+		if len(s.pending[receiver]) < MaxPending {
+			s.pending[receiver] = append(s.pending[receiver], frame)
+		} else {
+			log.Println("relaynetwork: dropped frame for party", receiver)
+		}
+		s.lock.Unlock()
+		return
+	}
+	s.lock.Unlock()
+	c.write(frame)
+}
+
+func (c *client) write(frame []byte) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if err := writeFrame(c.conn, frame); err != nil {
+		//The receiver notices when its connection breaks
+		c.conn.Close()
+	}
+}
+
+func writeFrame(w io.Writer, frame []byte) error {
+	buf := make([]byte, 4+len(frame))
+	binary.BigEndian.PutUint32(buf, uint32(len(frame)))
+	copy(buf[4:], frame)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readFrame(r io.Reader) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header)
+	if length > MaxFrameSize {
+		return nil, fmt.Errorf("relaynetwork: frame of %d bytes exceeds MaxFrameSize", length)
+	}
+	frame := make([]byte, length)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
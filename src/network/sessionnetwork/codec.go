@@ -0,0 +1,55 @@
+package sessionnetwork
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	".."
+)
+
+//Codec encodes messages using an inner codec for their data
+type Codec struct {
+	inner network.Codec
+}
+
+//NewCodec ...
+func NewCodec(inner network.Codec) *Codec {
+	return &Codec{inner: inner}
+}
+
+//Encode writes the length of the session id as 2 bytes, the id and the encoded data
+func (c *Codec) Encode(data interface{}) ([]byte, error) {
+	message, isMessage := data.(Message)
+	if !isMessage {
+		return nil, errors.New("sessionnetwork: data is not a message")
+	}
+	if len(message.Session) > math.MaxUint16 {
+		return nil, errors.New("sessionnetwork: session id too long")
+	}
+	encoded, err := c.inner.Encode(message.Data)
+	if err != nil {
+		return nil, err
+	}
+
+	buf := make([]byte, 2, 2+len(message.Session)+len(encoded))
+	binary.BigEndian.PutUint16(buf, uint16(len(message.Session)))
+	buf = append(buf, message.Session...)
+	return append(buf, encoded...), nil
+}
+
+//Decode ...
+func (c *Codec) Decode(b []byte) (interface{}, error) {
+	if len(b) < 2 {
+		return nil, errors.New("sessionnetwork: message too short")
+	}
+	length := int(binary.BigEndian.Uint16(b))
+	if len(b) < 2+length {
+		return nil, errors.New("sessionnetwork: message too short")
+	}
+	data, err := c.inner.Decode(b[2+length:])
+	if err != nil {
+		return nil, err
+	}
+	return Message{Session: string(b[2 : 2+length]), Data: data}, nil
+}
@@ -0,0 +1,43 @@
+package sessionnetwork
+
+import (
+	"reflect"
+	"testing"
+)
+
+type stringCodec struct{}
+
+func (stringCodec) Encode(data interface{}) ([]byte, error) {
+	return []byte(data.(string)), nil
+}
+
+func (stringCodec) Decode(b []byte) (interface{}, error) {
+	return string(b), nil
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	codec := NewCodec(stringCodec{})
+	for _, message := range []Message{{"job-1", "data"}, {"", ""}, {"ü", "x"}} {
+		b, err := codec.Encode(message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := codec.Decode(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(message, decoded) {
+			t.Errorf("Expected %v after round trip, got %v", message, decoded)
+		}
+	}
+}
+
+func TestCodecRejectsTruncatedMessage(t *testing.T) {
+	codec := NewCodec(stringCodec{})
+	b, _ := codec.Encode(Message{"session", "x"})
+	for length := 0; length < 2+len("session"); length++ {
+		if _, err := codec.Decode(b[:length]); err == nil {
+			t.Errorf("Decoded message truncated to %d bytes", length)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+package sessionnetwork
+
+import (
+	"sync"
+
+	".."
+)
+
+//Message is data sent within a session
+type Message struct {
+	Session string
+	Data    interface{}
+}
+
+//Mux runs many sessions over one network.
+//It is the handler of the shared network and hands every message to the handler of its session.
+//Messages for a session that has not been registered yet are held until it is,
+//up to a limit on the sessions and on the messages per session, beyond which they are dropped.
+type Mux struct {
+	index int
+	inner network.Network
+
+	lock     sync.Mutex
+	sessions map[string]*Session
+	pending  map[string][]pending
+
+	maxSessions int
+	maxMessages int
+}
+
+//Default limits of the data held for sessions without a handler.
+//A party can start a session only after all parties agreed on it, so the data of a session arriving early
+//is what the other parties send before this party catches up, while data for sessions nobody registers is junk.
+const (
+	DefaultMaxPendingSessions = 64
+	DefaultMaxPendingMessages = 1 << 16
+)
+
+type pending struct {
+	data   interface{}
+	sender int
+}
+
+//New creates a mux for the party with the given index
+func New(index int) *Mux {
+	return &Mux{
+		index:    index,
+		sessions: make(map[string]*Session),
+		pending:  make(map[string][]pending),
+
+		maxSessions: DefaultMaxPendingSessions,
+		maxMessages: DefaultMaxPendingMessages,
+	}
+}
+
+//SetPendingLimits limits the data held for sessions without a handler to messages per session in at most sessions sessions
+func (m *Mux) SetPendingLimits(sessions, messages int) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.maxSessions = sessions
+	m.maxMessages = messages
+}
+
+//Session returns the network of session id, creating it if needed.
+//Ids are not reused: once closed, a session stays closed.
+func (m *Mux) Session(id string) *Session {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	s, exists := m.sessions[id]
+	if !exists {
+		s = &Session{id: id, mux: m}
+		m.sessions[id] = s
+	}
+	return s
+}
+
+//Handle passes data to the handler of its session
+func (m *Mux) Handle(data interface{}, sender int) {
+	message, isMessage := data.(Message)
+	if !isMessage {
+		return
+	}
+
+	m.lock.Lock()
+	s, exists := m.sessions[message.Session]
+	if exists && s.isClosed() {
+		m.lock.Unlock()
+		return
+	}
+	if !exists || s.handler == nil {
+		m.hold(message, sender)
+		m.lock.Unlock()
+		return
+	}
+	m.lock.Unlock()
+
+	s.handler.Handle(message.Data, sender)
+}
+
+//hold keeps data for a session without a handler unless the limits are reached. It must be called with the lock held.
+func (m *Mux) hold(message Message, sender int) {
+	held, isHeld := m.pending[message.Session]
+	if !isHeld && len(m.pending) >= m.maxSessions {
+		return
+	}
+	if len(held) >= m.maxMessages {
+		return
+	}
+	m.pending[message.Session] = append(held, pending{data: message.Data, sender: sender})
+}
+
+//HandleError passes errors of the shared network to every session with a handler
+func (m *Mux) HandleError(err error) {
+	m.lock.Lock()
+	var handlers []network.Handler
+	for _, s := range m.sessions {
+		if s.handler != nil {
+			handlers = append(handlers, s.handler)
+		}
+	}
+	m.lock.Unlock()
+
+	for _, handler := range handlers {
+		handler.HandleError(err)
+	}
+}
+
+//RegisterNetwork sets the shared network
+func (m *Mux) RegisterNetwork(network network.Network) {
+	m.inner = network
+}
+
+//Index ...
+func (m *Mux) Index() int {
+	return m.index
+}
+
+//Close closes the shared network and thereby every session
+func (m *Mux) Close() error {
+	m.lock.Lock()
+	for _, s := range m.sessions {
+		s.close()
+	}
+	m.lock.Unlock()
+	return m.inner.Close()
+}
+
+//Session is the network of a single session
+type Session struct {
+	id      string
+	mux     *Mux
+	handler network.Handler
+
+	closeLock sync.RWMutex
+	closed    bool
+}
+
+//ID of the session
+func (s *Session) ID() string {
+	return s.id
+}
+
+//Send sends data to the session with the same id at receiver
+func (s *Session) Send(data interface{}, receiver int) error {
+	if s.isClosed() {
+		return network.ErrClosed
+	}
+	return s.mux.inner.Send(Message{Session: s.id, Data: data}, receiver)
+}
+
+//Broadcast sends data to the session with the same id at every party
+func (s *Session) Broadcast(data interface{}) error {
+	return network.SendToAll(s.Send, data, s.Parties())
+}
+
+//Parties of the shared network
+func (s *Session) Parties() []int {
+	return s.mux.inner.Parties()
+}
+
+//RegisterHandler sets the handler of the session and hands it the data received for the session so far
+func (s *Session) RegisterHandler(handler network.Handler) {
+	handler.RegisterNetwork(s)
+
+	//Messages arriving meanwhile wait for the lock, so they are handled after those held
+	s.mux.lock.Lock()
+	defer s.mux.lock.Unlock()
+	for _, p := range s.mux.pending[s.id] {
+		handler.Handle(p.data, p.sender)
+	}
+	delete(s.mux.pending, s.id)
+	s.handler = handler
+}
+
+//Flush flushes the shared network if it holds data back
+func (s *Session) Flush() error {
+	if flusher, isFlusher := s.mux.inner.(network.Flusher); isFlusher {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+//Close ends the session without closing the shared network.
+//Data for the session arriving later is dropped.
+func (s *Session) Close() error {
+	s.mux.lock.Lock()
+	delete(s.mux.pending, s.id)
+	s.mux.lock.Unlock()
+	s.close()
+	return nil
+}
+
+func (s *Session) close() {
+	s.closeLock.Lock()
+	s.closed = true
+	s.closeLock.Unlock()
+}
+
+func (s *Session) isClosed() bool {
+	s.closeLock.RLock()
+	defer s.closeLock.RUnlock()
+	return s.closed
+}
@@ -0,0 +1,173 @@
+package sessionnetwork
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	".."
+)
+
+//loopback delivers data to the handler of the receiver as soon as it is sent
+type loopback struct {
+	handler     network.Handler
+	connections map[int]network.Handler
+}
+
+func (l *loopback) Send(data interface{}, receiver int) error {
+	l.connections[receiver].Handle(data, l.handler.Index())
+	return nil
+}
+
+func (l *loopback) Broadcast(data interface{}) error {
+	return network.SendToAll(l.Send, data, l.Parties())
+}
+
+func (l *loopback) Parties() []int {
+	var parties []int
+	for index := range l.connections {
+		parties = append(parties, index)
+	}
+	sort.Ints(parties)
+	return parties
+}
+
+func (l *loopback) RegisterHandler(handler network.Handler) {
+	l.handler = handler
+	handler.RegisterNetwork(l)
+}
+
+func (l *loopback) Close() error {
+	return nil
+}
+
+type handler struct {
+	index    int
+	network  network.Network
+	received []interface{}
+	errors   []error
+}
+
+func (h *handler) Handle(data interface{}, sender int) {
+	h.received = append(h.received, data)
+}
+
+func (h *handler) HandleError(err error) {
+	h.errors = append(h.errors, err)
+}
+
+func (h *handler) RegisterNetwork(network network.Network) {
+	h.network = network
+}
+
+func (h *handler) Index() int {
+	return h.index
+}
+
+func setting(n int) []*Mux {
+	muxes := make([]*Mux, n)
+	connections := make(map[int]network.Handler, n)
+	for i := range muxes {
+		muxes[i] = New(i + 1)
+		connections[i+1] = muxes[i]
+	}
+	for _, mux := range muxes {
+		l := &loopback{connections: connections}
+		l.RegisterHandler(mux)
+	}
+	return muxes
+}
+
+//join registers a handler for session id at every mux
+func join(muxes []*Mux, id string) []*handler {
+	handlers := make([]*handler, len(muxes))
+	for i, mux := range muxes {
+		handlers[i] = &handler{index: i + 1}
+		mux.Session(id).RegisterHandler(handlers[i])
+	}
+	return handlers
+}
+
+func TestSessionsAreSeparate(t *testing.T) {
+	muxes := setting(2)
+	a := join(muxes, "a")
+	b := join(muxes, "b")
+
+	a[0].network.Send("to a", 2)
+	b[0].network.Send("to b", 2)
+	if !reflect.DeepEqual(a[1].received, []interface{}{"to a"}) {
+		t.Errorf("Session a received %v", a[1].received)
+	}
+	if !reflect.DeepEqual(b[1].received, []interface{}{"to b"}) {
+		t.Errorf("Session b received %v", b[1].received)
+	}
+}
+
+func TestDataIsHeldUntilSessionIsRegistered(t *testing.T) {
+	muxes := setting(2)
+	sender := &handler{index: 1}
+	muxes[0].Session("late").RegisterHandler(sender)
+	sender.network.Send("x", 2)
+	sender.network.Send("y", 2)
+
+	receiver := &handler{index: 2}
+	muxes[1].Session("late").RegisterHandler(receiver)
+	sender.network.Send("z", 2)
+	if !reflect.DeepEqual(receiver.received, []interface{}{"x", "y", "z"}) {
+		t.Errorf("Expected data in the order it was sent, got %v", receiver.received)
+	}
+}
+
+func TestPendingDataIsLimited(t *testing.T) {
+	muxes := setting(2)
+	muxes[1].SetPendingLimits(2, 3)
+	for _, id := range []string{"a", "b", "c"} {
+		sender := &handler{index: 1}
+		muxes[0].Session(id).RegisterHandler(sender)
+		for _, data := range []string{"w", "x", "y", "z"} {
+			sender.network.Send(data, 2)
+		}
+	}
+
+	a := &handler{index: 2}
+	muxes[1].Session("a").RegisterHandler(a)
+	if !reflect.DeepEqual(a.received, []interface{}{"w", "x", "y"}) {
+		t.Errorf("Expected the first 3 messages of session a, got %v", a.received)
+	}
+	c := &handler{index: 2}
+	muxes[1].Session("c").RegisterHandler(c)
+	if len(c.received) != 0 {
+		t.Errorf("Expected the data of a third session to be dropped, got %v", c.received)
+	}
+}
+
+func TestClose(t *testing.T) {
+	muxes := setting(2)
+	handlers := join(muxes, "a")
+
+	handlers[1].network.Close()
+	if err := handlers[1].network.Send("x", 1); err != network.ErrClosed {
+		t.Errorf("Sending in closed session returned %v", err)
+	}
+	handlers[0].network.Send("x", 2)
+	if len(handlers[1].received) != 0 {
+		t.Errorf("Closed session received %v", handlers[1].received)
+	}
+	//Other sessions are not affected
+	b := join(muxes, "b")
+	if err := b[1].network.Send("y", 1); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestHandleError(t *testing.T) {
+	muxes := setting(1)
+	a := join(muxes, "a")
+	b := join(muxes, "b")
+	failure := errors.New("broken")
+	muxes[0].HandleError(failure)
+	if len(a[0].errors) != 1 || len(b[0].errors) != 1 {
+		t.Errorf("Expected the error to reach every session, got %v and %v", a[0].errors, b[0].errors)
+	}
+}
@@ -0,0 +1,182 @@
+package simnetwork
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	".."
+)
+
+//Link describes the simulated connection from one party to another
+type Link struct {
+	//Latency is the time from a message leaving the sender until it arrives
+	Latency time.Duration
+	//Bandwidth in bytes per second. Zero means unlimited.
+	Bandwidth int
+	//Jitter adds a uniformly random delay in [0, Jitter) to every message.
+	//Messages on a link with jitter may arrive out of order.
+	Jitter time.Duration
+}
+
+type link struct {
+	sender, receiver int
+}
+
+//Simulation holds the links between a set of parties.
+//A message occupies its link for size/Bandwidth, so messages sent
+//back to back on the same link queue behind each other.
+type Simulation struct {
+	defaultLink Link
+	codec       network.Codec
+
+	lock      sync.Mutex
+	links     map[link]Link
+	busyUntil map[link]time.Time
+	random    *rand.Rand
+}
+
+//NewSimulation creates a simulation where every link behaves as defaultLink.
+//codec determines the size of messages and may be nil if no link limits bandwidth.
+func NewSimulation(defaultLink Link, codec network.Codec) *Simulation {
+	return &Simulation{
+		defaultLink: defaultLink,
+		codec:       codec,
+		links:       make(map[link]Link),
+		busyUntil:   make(map[link]time.Time),
+		random:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+//SetLink overrides the link from sender to receiver
+func (s *Simulation) SetLink(sender, receiver int, l Link) {
+	s.lock.Lock()
+	s.links[link{sender, receiver}] = l
+	s.lock.Unlock()
+}
+
+//Seed makes the jitter of the simulation reproducible
+func (s *Simulation) Seed(seed int64) {
+	s.lock.Lock()
+	s.random = rand.New(rand.NewSource(seed))
+	s.lock.Unlock()
+}
+
+//delay computes how long from now data sent from sender arrives at receiver
+func (s *Simulation) delay(data interface{}, sender, receiver int) time.Duration {
+	key := link{sender, receiver}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	l, exists := s.links[key]
+	if !exists {
+		l = s.defaultLink
+	}
+
+	now := time.Now()
+	sent := now
+	if l.Bandwidth > 0 {
+		start := now
+		if s.busyUntil[key].After(start) {
+			start = s.busyUntil[key]
+		}
+		transmission := time.Duration(int64(s.size(data)) * int64(time.Second) / int64(l.Bandwidth))
+		sent = start.Add(transmission)
+		s.busyUntil[key] = sent
+	}
+
+	arrival := sent.Add(l.Latency)
+	if l.Jitter > 0 {
+		arrival = arrival.Add(time.Duration(s.random.Int63n(int64(l.Jitter))))
+	}
+	return arrival.Sub(now)
+}
+
+func (s *Simulation) size(data interface{}) int {
+	if s.codec == nil {
+		return 0
+	}
+	b, err := s.codec.Encode(data)
+	if err != nil {
+		return 0
+	}
+	return len(b)
+}
+
+//Simnetwork delivers messages after the delay given by its simulation
+type Simnetwork struct {
+	simulation  *Simulation
+	connections map[int]network.Handler
+	handler     network.Handler
+
+	closeLock sync.RWMutex
+	closed    bool
+}
+
+//Send delivers data to receiver once it has crossed the simulated link
+func (sn *Simnetwork) Send(data interface{}, receiver int) error {
+	sn.closeLock.RLock()
+	defer sn.closeLock.RUnlock()
+	if sn.closed {
+		return network.ErrClosed
+	}
+	handler, exists := sn.connections[receiver]
+	if !exists {
+		return &network.PeerError{Peer: receiver, Err: errors.New("not connected")}
+	}
+	sender := sn.handler.Index()
+	delay := sn.simulation.delay(data, sender, receiver)
+	time.AfterFunc(delay, func() {
+		handler.Handle(data, sender)
+	})
+	return nil
+}
+
+//Broadcast sends data to every connected party, each copy crossing its own link
+func (sn *Simnetwork) Broadcast(data interface{}) error {
+	return network.SendToAll(sn.Send, data, sn.Parties())
+}
+
+//Parties ...
+func (sn *Simnetwork) Parties() []int {
+	parties := make([]int, 0, len(sn.connections))
+	for index := range sn.connections {
+		parties = append(parties, index)
+	}
+	sort.Ints(parties)
+	return parties
+}
+
+//Close makes further sends fail. Data already sent still crosses its link.
+func (sn *Simnetwork) Close() error {
+	sn.closeLock.Lock()
+	sn.closed = true
+	sn.closeLock.Unlock()
+	return nil
+}
+
+//RegisterHandler ...
+func (sn *Simnetwork) RegisterHandler(handler network.Handler) {
+	sn.handler = handler
+	sn.handler.RegisterNetwork(sn)
+}
+
+//SetConnections ...
+func (sn *Simnetwork) SetConnections(handlers ...network.Handler) {
+	sn.connections = make(map[int]network.Handler)
+	for _, handler := range handlers {
+		sn.connections[handler.Index()] = handler
+	}
+}
+
+//Networks creates a network for each of numberOfNetworks parties in the simulation
+func (s *Simulation) Networks(numberOfNetworks int) (networks []*Simnetwork) {
+	networks = make([]*Simnetwork, numberOfNetworks)
+	for i := range networks {
+		networks[i] = &Simnetwork{simulation: s}
+	}
+	return
+}
@@ -0,0 +1,147 @@
+package simnetwork
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	".."
+)
+
+type bytesCodec struct{}
+
+func (bytesCodec) Encode(data interface{}) ([]byte, error) {
+	return data.([]byte), nil
+}
+
+func (bytesCodec) Decode(b []byte) (interface{}, error) {
+	return b, nil
+}
+
+type arrival struct {
+	data   interface{}
+	sender int
+	at     time.Time
+}
+
+type recorder struct {
+	index    int
+	network  network.Network
+	lock     sync.Mutex
+	arrivals []arrival
+}
+
+func (r *recorder) Handle(data interface{}, sender int) {
+	r.lock.Lock()
+	r.arrivals = append(r.arrivals, arrival{data: data, sender: sender, at: time.Now()})
+	r.lock.Unlock()
+}
+
+func (r *recorder) HandleError(err error) {}
+
+func (r *recorder) RegisterNetwork(network network.Network) {
+	r.network = network
+}
+
+func (r *recorder) Index() int {
+	return r.index
+}
+
+func (r *recorder) waitFor(count int, t *testing.T) []arrival {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		r.lock.Lock()
+		if len(r.arrivals) >= count {
+			arrivals := append([]arrival(nil), r.arrivals...)
+			r.lock.Unlock()
+			return arrivals
+		}
+		r.lock.Unlock()
+		time.Sleep(time.Millisecond)
+	}
+	t.Fatal("timed out")
+	return nil
+}
+
+func setting(simulation *Simulation, n int) []*recorder {
+	handlers := make([]network.Handler, n)
+	recorders := make([]*recorder, n)
+	for i := range recorders {
+		recorders[i] = &recorder{index: i + 1}
+		handlers[i] = recorders[i]
+	}
+	for i, sn := range simulation.Networks(n) {
+		sn.RegisterHandler(recorders[i])
+		sn.SetConnections(handlers...)
+	}
+	return recorders
+}
+
+func TestLatency(t *testing.T) {
+	latency := 50 * time.Millisecond
+	parties := setting(NewSimulation(Link{Latency: latency}, nil), 2)
+
+	start := time.Now()
+	parties[0].network.Send([]byte("hello"), 2)
+	arrivals := parties[1].waitFor(1, t)
+	if elapsed := arrivals[0].at.Sub(start); elapsed < latency {
+		t.Errorf("Message arrived after %v, expected at least %v", elapsed, latency)
+	}
+	if arrivals[0].sender != 1 {
+		t.Errorf("Message from 1 arrived from %d", arrivals[0].sender)
+	}
+}
+
+func TestBandwidth(t *testing.T) {
+	//10 messages of 1000 bytes at 100 kB/s occupy the link for 100ms
+	parties := setting(NewSimulation(Link{Bandwidth: 100000}, bytesCodec{}), 2)
+
+	start := time.Now()
+	for i := 0; i < 10; i++ {
+		parties[0].network.Send(make([]byte, 1000), 2)
+	}
+	arrivals := parties[1].waitFor(10, t)
+	if elapsed := arrivals[9].at.Sub(start); elapsed < 100*time.Millisecond {
+		t.Errorf("Last message arrived after %v, expected at least 100ms", elapsed)
+	}
+	for i := 1; i < len(arrivals); i++ {
+		if arrivals[i].at.Sub(arrivals[i-1].at) < 5*time.Millisecond {
+			t.Errorf("Messages %d and %d were not spread out by the bandwidth", i-1, i)
+		}
+	}
+}
+
+func TestSetLink(t *testing.T) {
+	simulation := NewSimulation(Link{}, nil)
+	simulation.SetLink(1, 3, Link{Latency: 100 * time.Millisecond})
+	parties := setting(simulation, 3)
+
+	start := time.Now()
+	parties[0].network.Send([]byte("slow"), 3)
+	parties[0].network.Send([]byte("fast"), 2)
+	fast := parties[1].waitFor(1, t)
+	slow := parties[2].waitFor(1, t)
+	if fast[0].at.Sub(start) > 50*time.Millisecond {
+		t.Errorf("Default link was delayed by %v", fast[0].at.Sub(start))
+	}
+	if slow[0].at.Sub(start) < 100*time.Millisecond {
+		t.Errorf("Slow link was only delayed by %v", slow[0].at.Sub(start))
+	}
+}
+
+func TestJitter(t *testing.T) {
+	simulation := NewSimulation(Link{Latency: 10 * time.Millisecond, Jitter: 20 * time.Millisecond}, nil)
+	simulation.Seed(1)
+	parties := setting(simulation, 2)
+
+	start := time.Now()
+	for i := 0; i < 20; i++ {
+		parties[0].network.Send([]byte{byte(i)}, 2)
+	}
+	for _, a := range parties[1].waitFor(20, t) {
+		elapsed := a.at.Sub(start)
+		if elapsed < 10*time.Millisecond {
+			t.Errorf("Message arrived after %v, before the latency", elapsed)
+		}
+	}
+}
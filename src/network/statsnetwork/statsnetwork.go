@@ -0,0 +1,213 @@
+package statsnetwork
+
+import (
+	"fmt"
+	"sync"
+
+	".."
+)
+
+//Counter counts messages and their size in bytes
+type Counter struct {
+	Messages int
+	Bytes    int
+}
+
+func (c *Counter) add(bytes int) {
+	c.Messages++
+	c.Bytes += bytes
+}
+
+//Stats is the traffic of a party, in total and broken down by peer and by kind of message
+type Stats struct {
+	Sent     Counter
+	Received Counter
+
+	SentTo       map[int]Counter
+	ReceivedFrom map[int]Counter
+
+	SentByKind     map[string]Counter
+	ReceivedByKind map[string]Counter
+
+	//Rounds is the number of times the party waited for data after sending.
+	//Steps running in parallel count once.
+	Rounds int
+}
+
+func (s Stats) String() string {
+	return fmt.Sprintf("sent %d messages (%d bytes), received %d messages (%d bytes) in %d rounds",
+		s.Sent.Messages, s.Sent.Bytes, s.Received.Messages, s.Received.Bytes, s.Rounds)
+}
+
+//Recorder accumulates stats and is safe for concurrent use
+type Recorder struct {
+	lock  sync.Mutex
+	stats Stats
+}
+
+//NewRecorder ...
+func NewRecorder() *Recorder {
+	return &Recorder{stats: Stats{
+		SentTo:         make(map[int]Counter),
+		ReceivedFrom:   make(map[int]Counter),
+		SentByKind:     make(map[string]Counter),
+		ReceivedByKind: make(map[string]Counter),
+	}}
+}
+
+//RecordSent counts a message of the given kind and size sent to peer
+func (r *Recorder) RecordSent(peer int, kind string, bytes int) {
+	r.lock.Lock()
+	r.stats.Sent.add(bytes)
+	c := r.stats.SentTo[peer]
+	c.add(bytes)
+	r.stats.SentTo[peer] = c
+	c = r.stats.SentByKind[kind]
+	c.add(bytes)
+	r.stats.SentByKind[kind] = c
+	r.lock.Unlock()
+}
+
+//RecordReceived counts a message of the given kind and size received from peer
+func (r *Recorder) RecordReceived(peer int, kind string, bytes int) {
+	r.lock.Lock()
+	r.stats.Received.add(bytes)
+	c := r.stats.ReceivedFrom[peer]
+	c.add(bytes)
+	r.stats.ReceivedFrom[peer] = c
+	c = r.stats.ReceivedByKind[kind]
+	c.add(bytes)
+	r.stats.ReceivedByKind[kind] = c
+	r.lock.Unlock()
+}
+
+//RecordRound counts a round
+func (r *Recorder) RecordRound() {
+	r.lock.Lock()
+	r.stats.Rounds++
+	r.lock.Unlock()
+}
+
+//Stats returns a copy of the stats recorded so far
+func (r *Recorder) Stats() Stats {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	s := r.stats
+	s.SentTo = make(map[int]Counter, len(r.stats.SentTo))
+	for peer, c := range r.stats.SentTo {
+		s.SentTo[peer] = c
+	}
+	s.ReceivedFrom = make(map[int]Counter, len(r.stats.ReceivedFrom))
+	for peer, c := range r.stats.ReceivedFrom {
+		s.ReceivedFrom[peer] = c
+	}
+	s.SentByKind = make(map[string]Counter, len(r.stats.SentByKind))
+	for kind, c := range r.stats.SentByKind {
+		s.SentByKind[kind] = c
+	}
+	s.ReceivedByKind = make(map[string]Counter, len(r.stats.ReceivedByKind))
+	for kind, c := range r.stats.ReceivedByKind {
+		s.ReceivedByKind[kind] = c
+	}
+	return s
+}
+
+//Statsnetwork wraps a network and counts the messages sent and received through it.
+//It is the handler of the wrapped network, so with networks that are connected to
+//the handlers of other parties, such as localnetwork, connect them to the stats networks.
+type Statsnetwork struct {
+	inner   network.Network
+	handler network.Handler
+	codec   network.Codec
+
+	//Kind names the kind of a message. By default it is the type of the message.
+	Kind func(data interface{}) string
+
+	recorder *Recorder
+}
+
+//New wraps inner. codec determines the size of messages and may be nil, in which case only messages are counted.
+func New(inner network.Network, codec network.Codec) *Statsnetwork {
+	return &Statsnetwork{
+		inner:    inner,
+		codec:    codec,
+		Kind:     func(data interface{}) string { return fmt.Sprintf("%T", data) },
+		recorder: NewRecorder(),
+	}
+}
+
+//Stats returns the traffic so far. Rounds are not known to the network and are zero.
+func (sn *Statsnetwork) Stats() Stats {
+	return sn.recorder.Stats()
+}
+
+func (sn *Statsnetwork) size(data interface{}) int {
+	if sn.codec == nil {
+		return 0
+	}
+	b, err := sn.codec.Encode(data)
+	if err != nil {
+		return 0
+	}
+	return len(b)
+}
+
+//Send counts data and sends it through the wrapped network. Data that could not be sent is not counted.
+func (sn *Statsnetwork) Send(data interface{}, receiver int) error {
+	if err := sn.inner.Send(data, receiver); err != nil {
+		return err
+	}
+	sn.recorder.RecordSent(receiver, sn.Kind(data), sn.size(data))
+	return nil
+}
+
+//Broadcast counts every copy of data as it is sent
+func (sn *Statsnetwork) Broadcast(data interface{}) error {
+	return network.SendToAll(sn.Send, data, sn.Parties())
+}
+
+//Parties of the wrapped network
+func (sn *Statsnetwork) Parties() []int {
+	return sn.inner.Parties()
+}
+
+//RegisterHandler ...
+func (sn *Statsnetwork) RegisterHandler(handler network.Handler) {
+	sn.handler = handler
+	sn.inner.RegisterHandler(sn)
+	handler.RegisterNetwork(sn)
+}
+
+//Flush flushes the wrapped network if it holds data back
+func (sn *Statsnetwork) Flush() error {
+	if flusher, isFlusher := sn.inner.(network.Flusher); isFlusher {
+		return flusher.Flush()
+	}
+	return nil
+}
+
+//Close ...
+func (sn *Statsnetwork) Close() error {
+	return sn.inner.Close()
+}
+
+//Handle counts data and passes it to the handler
+func (sn *Statsnetwork) Handle(data interface{}, sender int) {
+	sn.recorder.RecordReceived(sender, sn.Kind(data), sn.size(data))
+	sn.handler.Handle(data, sender)
+}
+
+//HandleError ...
+func (sn *Statsnetwork) HandleError(err error) {
+	sn.handler.HandleError(err)
+}
+
+//RegisterNetwork sets the wrapped network
+func (sn *Statsnetwork) RegisterNetwork(network network.Network) {
+	sn.inner = network
+}
+
+//Index of the handler
+func (sn *Statsnetwork) Index() int {
+	return sn.handler.Index()
+}
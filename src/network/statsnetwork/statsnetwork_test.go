@@ -0,0 +1,144 @@
+package statsnetwork
+
+import (
+	"errors"
+	"testing"
+
+	".."
+)
+
+type stringCodec struct{}
+
+func (stringCodec) Encode(data interface{}) ([]byte, error) {
+	s, ok := data.(string)
+	if !ok {
+		return nil, errors.New("not a string")
+	}
+	return []byte(s), nil
+}
+
+func (stringCodec) Decode(b []byte) (interface{}, error) {
+	return string(b), nil
+}
+
+//failingNetwork fails to send to party 3
+type failingNetwork struct{}
+
+func (failingNetwork) Send(data interface{}, receiver int) error {
+	if receiver == 3 {
+		return &network.PeerError{Peer: 3, Err: errors.New("unreachable")}
+	}
+	return nil
+}
+
+func (f failingNetwork) Broadcast(data interface{}) error {
+	return network.SendToAll(f.Send, data, f.Parties())
+}
+
+func (failingNetwork) Parties() []int {
+	return []int{1, 2, 3}
+}
+
+func (failingNetwork) RegisterHandler(handler network.Handler) {
+	handler.RegisterNetwork(failingNetwork{})
+}
+
+func (failingNetwork) Close() error {
+	return nil
+}
+
+type handler struct {
+	network  network.Network
+	received []interface{}
+}
+
+func (h *handler) Handle(data interface{}, sender int) {
+	h.received = append(h.received, data)
+}
+
+func (h *handler) HandleError(err error) {}
+
+func (h *handler) RegisterNetwork(network network.Network) {
+	h.network = network
+}
+
+func (h *handler) Index() int {
+	return 1
+}
+
+func setting() (*handler, *Statsnetwork) {
+	sn := New(failingNetwork{}, stringCodec{})
+	h := new(handler)
+	sn.RegisterHandler(h)
+	return h, sn
+}
+
+func TestSent(t *testing.T) {
+	h, sn := setting()
+	h.network.Send("abc", 2)
+	h.network.Send("de", 2)
+	h.network.Send("fgh", 4)
+	if err := h.network.Send("lost", 3); err == nil {
+		t.Error("Expected error of wrapped network")
+	}
+
+	stats := sn.Stats()
+	if stats.Sent != (Counter{Messages: 3, Bytes: 8}) {
+		t.Errorf("Sent %+v", stats.Sent)
+	}
+	if stats.SentTo[2] != (Counter{Messages: 2, Bytes: 5}) || stats.SentTo[4] != (Counter{Messages: 1, Bytes: 3}) {
+		t.Errorf("Sent to peers %+v", stats.SentTo)
+	}
+	if _, exists := stats.SentTo[3]; exists {
+		t.Error("Counted data that could not be sent")
+	}
+	if stats.SentByKind["string"] != stats.Sent {
+		t.Errorf("Sent by kind %+v", stats.SentByKind)
+	}
+}
+
+func TestReceived(t *testing.T) {
+	h, sn := setting()
+	sn.Kind = func(data interface{}) string { return data.(string)[:1] }
+	sn.Handle("abc", 2)
+	sn.Handle("a", 3)
+	sn.Handle("xy", 3)
+
+	if len(h.received) != 3 {
+		t.Errorf("Handler received %v", h.received)
+	}
+	stats := sn.Stats()
+	if stats.Received != (Counter{Messages: 3, Bytes: 6}) {
+		t.Errorf("Received %+v", stats.Received)
+	}
+	if stats.ReceivedFrom[3] != (Counter{Messages: 2, Bytes: 3}) {
+		t.Errorf("Received from peers %+v", stats.ReceivedFrom)
+	}
+	if stats.ReceivedByKind["a"] != (Counter{Messages: 2, Bytes: 4}) || stats.ReceivedByKind["x"] != (Counter{Messages: 1, Bytes: 2}) {
+		t.Errorf("Received by kind %+v", stats.ReceivedByKind)
+	}
+}
+
+func TestStatsIsACopy(t *testing.T) {
+	h, sn := setting()
+	h.network.Send("a", 2)
+	stats := sn.Stats()
+	h.network.Send("b", 2)
+	if stats.SentTo[2].Messages != 1 {
+		t.Errorf("Stats changed after it was returned: %+v", stats.SentTo)
+	}
+}
+
+func TestBroadcast(t *testing.T) {
+	h, sn := setting()
+	if err := h.network.Broadcast("abc"); err == nil {
+		t.Error("Expected error of wrapped network")
+	}
+	stats := sn.Stats()
+	if stats.Sent != (Counter{Messages: 2, Bytes: 6}) {
+		t.Errorf("Sent %+v", stats.Sent)
+	}
+	if stats.SentTo[1].Messages != 1 || stats.SentTo[2].Messages != 1 {
+		t.Errorf("Sent to peers %+v", stats.SentTo)
+	}
+}
@@ -0,0 +1,616 @@
+package tcpnetwork
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	".."
+)
+
+//MaxFrameSize is the largest frame a party accepts from a peer
+const MaxFrameSize = 1 << 26
+
+//ackInterval is the number of frames after which a receiver acknowledges even if more frames are waiting
+const ackInterval = 64
+
+var (
+	//DialTimeout bounds how long Send keeps retrying to reach a peer that is not listening yet
+	DialTimeout = 30 * time.Second
+	//ReconnectTimeout bounds how long a broken connection is retried before the peer is considered failed
+	ReconnectTimeout = 30 * time.Second
+	//HeartbeatInterval is how often a sender checks that an idle connection is alive. Zero disables heartbeats.
+	//Heartbeat settings apply to networks created after they are set.
+	HeartbeatInterval = 5 * time.Second
+	//HeartbeatTimeout bounds how long a connection may stay silent before it is considered broken
+	HeartbeatTimeout = 15 * time.Second
+	//WriteTimeout bounds how long writing to a connection may block, e.g. on a peer that stopped reading,
+	//before the connection is considered broken. Like heartbeat settings it applies to networks created after it is set.
+	WriteTimeout = 15 * time.Second
+	dialBackoff  = 50 * time.Millisecond
+)
+
+//Tcpnetwork connects a party to its peers over TCP.
+//Every frame is prefixed by its length as a 4 byte big endian integer.
+//A connection is opened by the sending party, which starts by sending its index.
+//The receiving party answers with the sequence number of the last frame it has handled from the sender.
+//
+//Every frame carries a sequence number as an 8 byte big endian integer and is kept by the sender
+//until the receiver acknowledges it by sending back the sequence number of the last frame it handled.
+//If a connection breaks, the sender reconnects and resends the frames the receiver has not handled,
+//so frames are handled exactly once and in the order they were sent.
+//
+//Senders write an empty frame every HeartbeatInterval, which the receiver acknowledges like any other frame.
+//A connection that stays silent for HeartbeatTimeout is considered broken, so a peer that hangs
+//or becomes unreachable is reconnected and, failing that, reported even if no data is waiting for it.
+type Tcpnetwork struct {
+	codec     network.Codec
+	handler   network.Handler
+	addresses map[int]string
+	//Heartbeat settings when the network was created
+	heartbeatInterval time.Duration
+	heartbeatTimeout  time.Duration
+	writeTimeout      time.Duration
+
+	listener    net.Listener
+	credentials *Credentials
+
+	peerLock sync.Mutex
+	peers    map[int]*peer
+
+	inboundLock sync.Mutex
+	inbound     map[int]*inbound
+
+	acceptedLock sync.Mutex
+	accepted     []net.Conn
+	closed       bool
+}
+
+//peer is the outgoing link to another party
+type peer struct {
+	lock sync.Mutex
+	conn net.Conn
+	//sequence is the sequence number of the last frame sent
+	sequence uint64
+	//unacked holds the frames not acknowledged yet in the order they were sent
+	unacked      []sentFrame
+	reconnecting bool
+	//err is set when the peer could not be reached, after which sending to it fails
+	err error
+}
+
+type sentFrame struct {
+	sequence uint64
+	frame    []byte
+}
+
+//inbound is the incoming link from another party
+type inbound struct {
+	lock sync.Mutex
+	conn net.Conn
+	//handled is the sequence number of the last frame handed to the handler
+	handled uint64
+}
+
+//New creates a network using codec to serialize data
+func New(codec network.Codec) *Tcpnetwork {
+	tn := new(Tcpnetwork)
+	tn.codec = codec
+	tn.addresses = make(map[int]string)
+	tn.peers = make(map[int]*peer)
+	tn.inbound = make(map[int]*inbound)
+	tn.heartbeatInterval = HeartbeatInterval
+	tn.heartbeatTimeout = HeartbeatTimeout
+	tn.writeTimeout = WriteTimeout
+	return tn
+}
+
+//SetConnections sets the listening address of every party, including this one
+func (tn *Tcpnetwork) SetConnections(addresses map[int]string) {
+	tn.peerLock.Lock()
+	tn.addresses = make(map[int]string, len(addresses))
+	for index, address := range addresses {
+		tn.addresses[index] = address
+	}
+	tn.peerLock.Unlock()
+}
+
+//RegisterHandler ...
+func (tn *Tcpnetwork) RegisterHandler(handler network.Handler) {
+	tn.handler = handler
+	tn.handler.RegisterNetwork(tn)
+}
+
+//Listen starts accepting connections on the address of the registered handler
+func (tn *Tcpnetwork) Listen() error {
+	tn.peerLock.Lock()
+	address, exists := tn.addresses[tn.handler.Index()]
+	tn.peerLock.Unlock()
+	if !exists {
+		return fmt.Errorf("tcpnetwork: no address for party %d", tn.handler.Index())
+	}
+
+	listener, err := tn.listen(address)
+	if err != nil {
+		return err
+	}
+	tn.listener = listener
+	go tn.accept()
+	return nil
+}
+
+//Addr is the address the network listens on
+func (tn *Tcpnetwork) Addr() net.Addr {
+	return tn.listener.Addr()
+}
+
+//Close stops listening and closes all connections once the frames being written have been written
+func (tn *Tcpnetwork) Close() error {
+	tn.acceptedLock.Lock()
+	tn.closed = true
+	tn.acceptedLock.Unlock()
+
+	var err error
+	if tn.listener != nil {
+		err = tn.listener.Close()
+	}
+
+	tn.peerLock.Lock()
+	peers := make([]*peer, 0, len(tn.peers))
+	for _, p := range tn.peers {
+		peers = append(peers, p)
+	}
+	tn.peerLock.Unlock()
+	for _, p := range peers {
+		p.lock.Lock()
+		if p.conn != nil {
+			p.conn.Close()
+			p.conn = nil
+		}
+		p.lock.Unlock()
+	}
+
+	tn.acceptedLock.Lock()
+	for _, conn := range tn.accepted {
+		conn.Close()
+	}
+	tn.accepted = nil
+	tn.acceptedLock.Unlock()
+
+	return err
+}
+
+//Send encodes data and writes it to the connection to receiver.
+//Frames to the same receiver are handled in the order Send is called.
+//Once the frame is written Send returns, and the frame is resent if the connection breaks before it is acknowledged.
+func (tn *Tcpnetwork) Send(data interface{}, receiver int) error {
+	if receiver == tn.handler.Index() {
+		//Data for this party is handed to the handler without a connection
+		if tn.isClosed() {
+			return network.ErrClosed
+		}
+		go tn.handler.Handle(data, receiver)
+		return nil
+	}
+	encoded, err := tn.codec.Encode(data)
+	if err != nil {
+		return err
+	}
+
+	p := tn.peer(receiver)
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if tn.isClosed() {
+		return network.ErrClosed
+	}
+	if p.err != nil {
+		return &network.PeerError{Peer: receiver, Err: p.err}
+	}
+
+	p.sequence++
+	frame := make([]byte, 8+len(encoded))
+	binary.BigEndian.PutUint64(frame, p.sequence)
+	copy(frame[8:], encoded)
+	p.unacked = append(p.unacked, sentFrame{sequence: p.sequence, frame: frame})
+
+	if p.reconnecting {
+		//Sent once the connection is resumed
+		return nil
+	}
+	if p.conn == nil {
+		return tn.connect(receiver, p)
+	}
+
+	if err := tn.writeFrame(p.conn, frame); err != nil {
+		tn.broken(receiver, p, p.conn, isTimeout(err))
+	}
+	return nil
+}
+
+//connect dials receiver and resumes the link. The caller holds p.lock, which is released while dialing,
+//so frames sent to receiver meanwhile wait to be resent like while reconnecting.
+func (tn *Tcpnetwork) connect(receiver int, p *peer) error {
+	p.reconnecting = true
+	p.lock.Unlock()
+	conn, handled, err := tn.dial(receiver, time.Now().Add(DialTimeout))
+	p.lock.Lock()
+	p.reconnecting = false
+	if err != nil {
+		p.err = err
+		return &network.PeerError{Peer: receiver, Err: err}
+	}
+	if tn.isClosed() {
+		conn.Close()
+		return network.ErrClosed
+	}
+	tn.resume(receiver, p, conn, handled)
+	return nil
+}
+
+//Broadcast sends data to every party
+func (tn *Tcpnetwork) Broadcast(data interface{}) error {
+	return network.SendToAll(tn.Send, data, tn.Parties())
+}
+
+//Parties are the parties with an address
+func (tn *Tcpnetwork) Parties() []int {
+	tn.peerLock.Lock()
+	defer tn.peerLock.Unlock()
+	parties := make([]int, 0, len(tn.addresses))
+	for index := range tn.addresses {
+		parties = append(parties, index)
+	}
+	sort.Ints(parties)
+	return parties
+}
+
+func (tn *Tcpnetwork) isClosed() bool {
+	tn.acceptedLock.Lock()
+	defer tn.acceptedLock.Unlock()
+	return tn.closed
+}
+
+func (tn *Tcpnetwork) peer(index int) *peer {
+	tn.peerLock.Lock()
+	p, exists := tn.peers[index]
+	if !exists {
+		p = new(peer)
+		tn.peers[index] = p
+	}
+	tn.peerLock.Unlock()
+	return p
+}
+
+//resume makes conn the connection to receiver and resends the frames receiver has not handled.
+//The caller holds p.lock.
+func (tn *Tcpnetwork) resume(receiver int, p *peer, conn net.Conn, handled uint64) {
+	p.acknowledge(handled)
+	p.conn = conn
+	go tn.readAcks(receiver, p, conn)
+	if tn.heartbeatInterval > 0 {
+		go tn.heartbeat(receiver, p, conn)
+	}
+	for _, f := range p.unacked {
+		if err := tn.writeFrame(conn, f.frame); err != nil {
+			tn.broken(receiver, p, conn, isTimeout(err))
+			return
+		}
+	}
+}
+
+//broken closes conn if it is still the connection to receiver. If frames are waiting
+//to be acknowledged or the peer went silent it reconnects in the background, otherwise the next Send reconnects.
+//The caller holds p.lock.
+func (tn *Tcpnetwork) broken(receiver int, p *peer, conn net.Conn, silent bool) {
+	if p.conn != conn {
+		return
+	}
+	conn.Close()
+	p.conn = nil
+	if (len(p.unacked) > 0 || silent) && !p.reconnecting && !tn.isClosed() {
+		p.reconnecting = true
+		go tn.reconnect(receiver, p)
+	}
+}
+
+//reconnect redials receiver until ReconnectTimeout and resumes the link.
+//If receiver cannot be reached the handler is told, as the frames waiting for it are lost.
+func (tn *Tcpnetwork) reconnect(receiver int, p *peer) {
+	deadline := time.Now().Add(ReconnectTimeout)
+	for {
+		conn, handled, err := tn.dial(receiver, deadline)
+
+		p.lock.Lock()
+		if tn.isClosed() {
+			if conn != nil {
+				conn.Close()
+			}
+			p.reconnecting = false
+			p.lock.Unlock()
+			return
+		}
+		if err == nil {
+			p.reconnecting = false
+			tn.resume(receiver, p, conn, handled)
+			p.lock.Unlock()
+			return
+		}
+		if time.Now().After(deadline) {
+			p.reconnecting = false
+			p.err = err
+			p.lock.Unlock()
+			tn.handler.HandleError(&network.PeerError{Peer: receiver, Err: err})
+			return
+		}
+		p.lock.Unlock()
+		time.Sleep(dialBackoff)
+	}
+}
+
+//heartbeat writes an empty frame to conn every heartbeat interval until conn is no longer the connection to receiver
+func (tn *Tcpnetwork) heartbeat(receiver int, p *peer, conn net.Conn) {
+	ticker := time.NewTicker(tn.heartbeatInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		p.lock.Lock()
+		if p.conn != conn {
+			p.lock.Unlock()
+			return
+		}
+		if err := tn.writeFrame(conn, nil); err != nil {
+			tn.broken(receiver, p, conn, isTimeout(err))
+		}
+		p.lock.Unlock()
+	}
+}
+
+//readAcks removes the frames receiver acknowledges on conn from the frames waiting to be acknowledged
+func (tn *Tcpnetwork) readAcks(receiver int, p *peer, conn net.Conn) {
+	ack := make([]byte, 8)
+	for {
+		if tn.heartbeatInterval > 0 {
+			conn.SetReadDeadline(time.Now().Add(tn.heartbeatTimeout))
+		}
+		_, err := io.ReadFull(conn, ack)
+		p.lock.Lock()
+		if err != nil {
+			tn.broken(receiver, p, conn, isTimeout(err))
+			p.lock.Unlock()
+			return
+		}
+		p.acknowledge(binary.BigEndian.Uint64(ack))
+		p.lock.Unlock()
+	}
+}
+
+func (p *peer) acknowledge(sequence uint64) {
+	i := 0
+	for i < len(p.unacked) && p.unacked[i].sequence <= sequence {
+		i++
+	}
+	p.unacked = p.unacked[i:]
+}
+
+//dial connects to receiver, retrying until deadline as the peer may not have started yet.
+//It returns the sequence number of the last frame receiver has handled from this party.
+func (tn *Tcpnetwork) dial(receiver int, deadline time.Time) (net.Conn, uint64, error) {
+	tn.peerLock.Lock()
+	address, exists := tn.addresses[receiver]
+	tn.peerLock.Unlock()
+	if !exists {
+		return nil, 0, fmt.Errorf("tcpnetwork: no address for party %d", receiver)
+	}
+
+	for {
+		conn, err := net.Dial("tcp", address)
+		if err == nil {
+			//The peer is up, so do not retry if it cannot be authenticated or refuses this party
+			conn, err = tn.handshake(conn, receiver)
+			if err != nil {
+				return nil, 0, err
+			}
+			handled, err := tn.hello(conn)
+			if err != nil {
+				conn.Close()
+				return nil, 0, err
+			}
+			return conn, handled, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, 0, err
+		}
+		if tn.isClosed() {
+			return nil, 0, network.ErrClosed
+		}
+		time.Sleep(dialBackoff)
+	}
+}
+
+//hello sends the index of this party and reads the sequence number of the last frame the peer has handled from it
+func (tn *Tcpnetwork) hello(conn net.Conn) (uint64, error) {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint32(buf, uint32(tn.handler.Index()))
+	conn.SetDeadline(time.Now().Add(DialTimeout))
+	defer conn.SetDeadline(time.Time{})
+	if _, err := conn.Write(buf[:4]); err != nil {
+		return 0, err
+	}
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		return 0, err
+	}
+	return binary.BigEndian.Uint64(buf), nil
+}
+
+func (tn *Tcpnetwork) accept() {
+	for {
+		conn, err := tn.listener.Accept()
+		if err != nil {
+			return //listener closed
+		}
+		tn.acceptedLock.Lock()
+		if tn.closed {
+			tn.acceptedLock.Unlock()
+			conn.Close()
+			return
+		}
+		tn.accepted = append(tn.accepted, conn)
+		tn.acceptedLock.Unlock()
+		go tn.receive(conn)
+	}
+}
+
+func (tn *Tcpnetwork) inboundFrom(sender int) *inbound {
+	tn.inboundLock.Lock()
+	in, exists := tn.inbound[sender]
+	if !exists {
+		in = new(inbound)
+		tn.inbound[sender] = in
+	}
+	tn.inboundLock.Unlock()
+	return in
+}
+
+//receive reads frames from a peer and hands them to the handler in the order they were sent
+func (tn *Tcpnetwork) receive(conn net.Conn) {
+	defer conn.Close()
+
+	hello := make([]byte, 4)
+	if _, err := io.ReadFull(conn, hello); err != nil {
+		return
+	}
+	sender := int(binary.BigEndian.Uint32(hello))
+	if err := tn.authenticate(conn, sender); err != nil {
+		//Not reported to the handler, as the connection is not from a party
+		log.Println("tcpnetwork: rejected connection claiming to be party", sender, ":", err)
+		return
+	}
+
+	//A new connection from sender means it has given up on the old one
+	in := tn.inboundFrom(sender)
+	in.lock.Lock()
+	if in.conn != nil {
+		in.conn.Close()
+	}
+	in.conn = conn
+	handled := in.handled
+	in.lock.Unlock()
+	if err := tn.writeSequence(conn, handled); err != nil {
+		return
+	}
+
+	reader := bufio.NewReader(conn)
+	for {
+		if tn.heartbeatInterval > 0 {
+			conn.SetReadDeadline(time.Now().Add(tn.heartbeatTimeout))
+		}
+		frame, err := readFrame(reader)
+		if err != nil {
+			//If the connection broke the sender reconnects, so it is not a failure
+			return
+		}
+		if len(frame) == 0 {
+			//Heartbeat
+			in.lock.Lock()
+			handled = in.handled
+			in.lock.Unlock()
+			if err := tn.writeSequence(conn, handled); err != nil {
+				return
+			}
+			continue
+		}
+		if len(frame) < 8 {
+			tn.handler.HandleError(&network.PeerError{Peer: sender, Err: errors.New("tcpnetwork: frame without sequence number")})
+			return
+		}
+		sequence := binary.BigEndian.Uint64(frame)
+
+		in.lock.Lock()
+		if in.conn != conn || sequence > in.handled+1 {
+			//Replaced by a newer connection, or frames are missing and are resent after reconnecting
+			in.lock.Unlock()
+			return
+		}
+		if sequence == in.handled+1 {
+			data, err := tn.codec.Decode(frame[8:])
+			if err != nil {
+				in.lock.Unlock()
+				tn.handler.HandleError(&network.PeerError{Peer: sender, Err: err})
+				return
+			}
+			in.handled = sequence
+			tn.handler.Handle(data, sender)
+		}
+		//Frames handled before are resent if their acknowledgement was lost and are skipped
+		handled = in.handled
+		in.lock.Unlock()
+
+		if reader.Buffered() == 0 || handled%ackInterval == 0 {
+			if err := tn.writeSequence(conn, handled); err != nil {
+				return
+			}
+		}
+	}
+}
+
+//writeFrame writes frame to conn, giving up after the write timeout
+func (tn *Tcpnetwork) writeFrame(conn net.Conn, frame []byte) error {
+	tn.setWriteDeadline(conn)
+	return writeFrame(conn, frame)
+}
+
+//writeSequence writes an acknowledgement to conn, giving up after the write timeout
+func (tn *Tcpnetwork) writeSequence(conn net.Conn, sequence uint64) error {
+	tn.setWriteDeadline(conn)
+	return writeSequence(conn, sequence)
+}
+
+func (tn *Tcpnetwork) setWriteDeadline(conn net.Conn) {
+	if tn.writeTimeout > 0 {
+		conn.SetWriteDeadline(time.Now().Add(tn.writeTimeout))
+	}
+}
+
+//isTimeout tells whether err is a connection that timed out, which means the peer went silent
+func isTimeout(err error) bool {
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+func writeSequence(w io.Writer, sequence uint64) error {
+	buf := make([]byte, 8)
+	binary.BigEndian.PutUint64(buf, sequence)
+	_, err := w.Write(buf)
+	return err
+}
+
+func writeFrame(w io.Writer, frame []byte) error {
+	buf := make([]byte, 4+len(frame))
+	binary.BigEndian.PutUint32(buf, uint32(len(frame)))
+	copy(buf[4:], frame)
+	_, err := w.Write(buf)
+	return err
+}
+
+func readFrame(r io.Reader) ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	length := binary.BigEndian.Uint32(header)
+	if length > MaxFrameSize {
+		return nil, fmt.Errorf("tcpnetwork: frame of %d bytes exceeds MaxFrameSize", length)
+	}
+	frame := make([]byte, length)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
@@ -0,0 +1,442 @@
+package tcpnetwork
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	".."
+)
+
+type stringCodec struct{}
+
+func (stringCodec) Encode(data interface{}) ([]byte, error) {
+	s, ok := data.(string)
+	if !ok {
+		return nil, errors.New("not a string")
+	}
+	return []byte(s), nil
+}
+
+func (stringCodec) Decode(b []byte) (interface{}, error) {
+	return string(b), nil
+}
+
+type message struct {
+	data   interface{}
+	sender int
+}
+
+type recorder struct {
+	index    int
+	network  network.Network
+	lock     sync.Mutex
+	received []message
+	errors   []error
+}
+
+func (r *recorder) Handle(data interface{}, sender int) {
+	r.lock.Lock()
+	r.received = append(r.received, message{data: data, sender: sender})
+	r.lock.Unlock()
+}
+
+func (r *recorder) HandleError(err error) {
+	r.lock.Lock()
+	r.errors = append(r.errors, err)
+	r.lock.Unlock()
+}
+
+func (r *recorder) failures() []error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]error(nil), r.errors...)
+}
+
+func (r *recorder) RegisterNetwork(network network.Network) {
+	r.network = network
+}
+
+func (r *recorder) Index() int {
+	return r.index
+}
+
+func (r *recorder) messages() []message {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return append([]message(nil), r.received...)
+}
+
+func setting(n int, t *testing.T) ([]*recorder, []*Tcpnetwork) {
+	handlers := make([]*recorder, n)
+	networks := make([]*Tcpnetwork, n)
+	addresses := make(map[int]string, n)
+	for i := range handlers {
+		handlers[i] = &recorder{index: i + 1}
+		networks[i] = New(stringCodec{})
+		networks[i].RegisterHandler(handlers[i])
+		networks[i].SetConnections(map[int]string{i + 1: "127.0.0.1:0"})
+		if err := networks[i].Listen(); err != nil {
+			t.Fatal(err)
+		}
+		addresses[i+1] = networks[i].Addr().String()
+	}
+	for _, tn := range networks {
+		tn.SetConnections(addresses)
+	}
+	return handlers, networks
+}
+
+func waitFor(condition func() bool, t *testing.T) {
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSend(t *testing.T) {
+	n := 3
+	handlers, networks := setting(n, t)
+	defer func() {
+		for _, tn := range networks {
+			tn.Close()
+		}
+	}()
+
+	for _, handler := range handlers {
+		for receiver := 1; receiver <= n; receiver++ {
+			if receiver != handler.index {
+				if err := handler.network.Send("hello", receiver); err != nil {
+					t.Error(err)
+				}
+			}
+		}
+	}
+
+	for _, handler := range handlers {
+		waitFor(func() bool { return len(handler.messages()) == n-1 }, t)
+		if len(handler.failures()) != 0 {
+			t.Errorf("Party %d failed with %v", handler.index, handler.failures())
+		}
+		senders := make(map[int]bool)
+		for _, m := range handler.messages() {
+			if m.data != "hello" {
+				t.Errorf("Party %d received %v", handler.index, m.data)
+			}
+			senders[m.sender] = true
+		}
+		if len(senders) != n-1 || senders[handler.index] {
+			t.Errorf("Party %d received from wrong senders %v", handler.index, senders)
+		}
+	}
+}
+
+func TestSendPreservesOrder(t *testing.T) {
+	handlers, networks := setting(2, t)
+	defer func() {
+		for _, tn := range networks {
+			tn.Close()
+		}
+	}()
+
+	messages := []string{"a", "b", "c", "d", "e"}
+	for _, m := range messages {
+		handlers[0].network.Send(m, 2)
+	}
+
+	waitFor(func() bool { return len(handlers[1].messages()) == len(messages) }, t)
+	for i, m := range handlers[1].messages() {
+		if m.data != messages[i] || m.sender != 1 {
+			t.Errorf("Message %d was %v from %d", i, m.data, m.sender)
+		}
+	}
+}
+
+func TestSendErrors(t *testing.T) {
+	handlers, networks := setting(2, t)
+	defer networks[1].Close()
+
+	if err := handlers[0].network.Send(1, 2); err == nil {
+		t.Error("Sending data the codec cannot encode succeeded")
+	}
+	var peerErr *network.PeerError
+	if err := handlers[0].network.Send("hello", 3); !errors.As(err, &peerErr) || peerErr.Peer != 3 {
+		t.Errorf("Sending to party without address returned %v", err)
+	}
+	if err := handlers[0].network.Send("hello", 2); err != nil {
+		t.Error(err)
+	}
+	networks[0].Close()
+	if err := handlers[0].network.Send("hello", 2); err != network.ErrClosed {
+		t.Errorf("Sending on closed network returned %v", err)
+	}
+}
+
+//rawFrame is a frame as written by a sender
+func rawFrame(sequence uint64, data string) []byte {
+	buf := new(bytes.Buffer)
+	frame := make([]byte, 8, 8+len(data))
+	binary.BigEndian.PutUint64(frame, sequence)
+	writeFrame(buf, append(frame, data...))
+	return buf.Bytes()
+}
+
+//rawConnect connects to tn as party sender and returns the sequence number tn resumes from
+func rawConnect(tn *Tcpnetwork, sender int, t *testing.T) (net.Conn, uint64) {
+	conn, err := net.Dial("tcp", tn.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	hello := make([]byte, 8)
+	binary.BigEndian.PutUint32(hello, uint32(sender))
+	conn.Write(hello[:4])
+	if _, err := io.ReadFull(conn, hello); err != nil {
+		t.Fatal(err)
+	}
+	return conn, binary.BigEndian.Uint64(hello)
+}
+
+func TestResume(t *testing.T) {
+	handlers, networks := setting(1, t)
+	defer networks[0].Close()
+
+	conn, resumed := rawConnect(networks[0], 2, t)
+	if resumed != 0 {
+		t.Errorf("Resumed new link from %d", resumed)
+	}
+	//The connection breaks in the middle of the second frame
+	conn.Write(rawFrame(1, "a"))
+	conn.Write(rawFrame(2, "b")[:10])
+	conn.Close()
+	waitFor(func() bool { return len(handlers[0].messages()) == 1 }, t)
+
+	conn, resumed = rawConnect(networks[0], 2, t)
+	defer conn.Close()
+	if resumed != 1 {
+		t.Errorf("Expected to resume from 1, resumed from %d", resumed)
+	}
+	//The first frame is resent, as its acknowledgement may have been lost
+	conn.Write(rawFrame(1, "a"))
+	conn.Write(rawFrame(2, "b"))
+	waitFor(func() bool { return len(handlers[0].messages()) == 2 }, t)
+	for i, m := range handlers[0].messages() {
+		if m.data != []string{"a", "b"}[i] || m.sender != 2 {
+			t.Errorf("Message %d was %v from %d", i, m.data, m.sender)
+		}
+	}
+	if len(handlers[0].failures()) != 0 {
+		t.Errorf("Broken connection was reported: %v", handlers[0].failures())
+	}
+}
+
+func TestReconnect(t *testing.T) {
+	handlers, networks := setting(2, t)
+	defer func() {
+		for _, tn := range networks {
+			tn.Close()
+		}
+	}()
+
+	count := 200
+	for i := 0; i < count; i++ {
+		if i == count/2 {
+			//Reset the connections from the receiving end
+			networks[1].acceptedLock.Lock()
+			for _, conn := range networks[1].accepted {
+				conn.Close()
+			}
+			networks[1].acceptedLock.Unlock()
+		}
+		if err := handlers[0].network.Send(strconv.Itoa(i), 2); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	waitFor(func() bool { return len(handlers[1].messages()) >= count }, t)
+	time.Sleep(50 * time.Millisecond)
+	messages := handlers[1].messages()
+	if len(messages) != count {
+		t.Errorf("Expected %d messages, got %d", count, len(messages))
+	}
+	for i, m := range messages {
+		if m.data != strconv.Itoa(i) {
+			t.Fatalf("Message %d was %v", i, m.data)
+		}
+	}
+	for _, handler := range handlers {
+		if len(handler.failures()) != 0 {
+			t.Errorf("Party %d failed with %v", handler.index, handler.failures())
+		}
+	}
+}
+
+func TestUnreachablePeerFails(t *testing.T) {
+	defer func(dial, reconnect time.Duration) {
+		DialTimeout, ReconnectTimeout = dial, reconnect
+	}(DialTimeout, ReconnectTimeout)
+	DialTimeout, ReconnectTimeout = 100*time.Millisecond, 100*time.Millisecond
+
+	handlers, networks := setting(2, t)
+	defer networks[0].Close()
+	if err := handlers[0].network.Send("hello", 2); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(func() bool { return len(handlers[1].messages()) == 1 }, t)
+	networks[1].Close()
+
+	//The failure is returned by Send or, if the frame was written before the connection broke, reported
+	var peerErr *network.PeerError
+	waitFor(func() bool {
+		err := handlers[0].network.Send("lost", 2)
+		return errors.As(err, &peerErr) || len(handlers[0].failures()) > 0
+	}, t)
+	if err := handlers[0].network.Send("lost", 2); !errors.As(err, &peerErr) || peerErr.Peer != 2 {
+		t.Errorf("Sending to failed party returned %v", err)
+	}
+}
+
+func shortHeartbeats() func() {
+	interval, timeout, reconnect := HeartbeatInterval, HeartbeatTimeout, ReconnectTimeout
+	HeartbeatInterval, HeartbeatTimeout, ReconnectTimeout = 10*time.Millisecond, 50*time.Millisecond, 100*time.Millisecond
+	return func() {
+		HeartbeatInterval, HeartbeatTimeout, ReconnectTimeout = interval, timeout, reconnect
+	}
+}
+
+func TestHeartbeatsKeepIdleLinkAlive(t *testing.T) {
+	defer shortHeartbeats()()
+	handlers, networks := setting(2, t)
+	defer networks[0].Close()
+	defer networks[1].Close()
+
+	handlers[0].network.Send("a", 2)
+	time.Sleep(300 * time.Millisecond)
+	handlers[0].network.Send("b", 2)
+	waitFor(func() bool { return len(handlers[1].messages()) == 2 }, t)
+
+	networks[1].acceptedLock.Lock()
+	accepted := len(networks[1].accepted)
+	networks[1].acceptedLock.Unlock()
+	if accepted != 1 {
+		t.Errorf("Idle link was reconnected, %d connections accepted", accepted)
+	}
+	for _, handler := range handlers {
+		if len(handler.failures()) != 0 {
+			t.Errorf("Party %d failed with %v", handler.index, handler.failures())
+		}
+	}
+}
+
+func TestHeartbeatDetectsSilentPeer(t *testing.T) {
+	defer shortHeartbeats()()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := &recorder{index: 1}
+	tn := New(stringCodec{})
+	tn.RegisterHandler(handler)
+	tn.SetConnections(map[int]string{1: "127.0.0.1:0", 2: listener.Addr().String()})
+	defer tn.Close()
+
+	//Party 2 handles the first frame and then hangs without closing the connection
+	go func() {
+		conn, err := listener.Accept()
+		listener.Close()
+		if err != nil {
+			return
+		}
+		buf := make([]byte, 8)
+		io.ReadFull(conn, buf[:4])
+		writeSequence(conn, 0)
+		readFrame(conn)
+		writeSequence(conn, 1)
+	}()
+	if err := handler.network.Send("a", 2); err != nil {
+		t.Fatal(err)
+	}
+
+	waitFor(func() bool { return len(handler.failures()) > 0 }, t)
+	var peerErr *network.PeerError
+	if err := handler.failures()[0]; !errors.As(err, &peerErr) || peerErr.Peer != 2 {
+		t.Errorf("Expected failure of party 2, got %v", err)
+	}
+}
+
+func TestStalledPeerDoesNotBlockSend(t *testing.T) {
+	defer func(write, reconnect time.Duration) {
+		WriteTimeout, ReconnectTimeout = write, reconnect
+	}(WriteTimeout, ReconnectTimeout)
+	WriteTimeout, ReconnectTimeout = 50*time.Millisecond, 100*time.Millisecond
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	handler := &recorder{index: 1}
+	tn := New(stringCodec{})
+	tn.RegisterHandler(handler)
+	tn.SetConnections(map[int]string{1: "127.0.0.1:0", 2: listener.Addr().String()})
+	defer tn.Close()
+
+	//Party 2 answers the hello and then stops reading without closing the connection
+	stalled := make(chan net.Conn, 1)
+	go func() {
+		conn, err := listener.Accept()
+		listener.Close()
+		if err != nil {
+			return
+		}
+		io.ReadFull(conn, make([]byte, 4))
+		writeSequence(conn, 0)
+		stalled <- conn
+	}()
+	defer func() {
+		if conn := <-stalled; conn != nil {
+			conn.Close()
+		}
+	}()
+
+	//More than the buffers of the connection hold
+	sent := make(chan struct{})
+	go func() {
+		defer close(sent)
+		frame := string(make([]byte, 1<<20))
+		for i := 0; i < 64; i++ {
+			handler.network.Send(frame, 2)
+		}
+	}()
+	select {
+	case <-sent:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Send blocked on a peer that stopped reading")
+	}
+	waitFor(func() bool { return len(handler.failures()) > 0 }, t)
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	buf := new(bytes.Buffer)
+	frames := [][]byte{{}, []byte("x"), make([]byte, 1000)}
+	for _, frame := range frames {
+		if err := writeFrame(buf, frame); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, frame := range frames {
+		read, err := readFrame(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(read) != len(frame) {
+			t.Errorf("Expected frame of length %d, got %d", len(frame), len(read))
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package tcpnetwork
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+
+	".."
+)
+
+//Credentials authenticate a party to its peers and its peers to it.
+//Every party's certificate is pinned to its index, so a peer claiming
+//to be party i must present exactly the certificate listed for party i.
+type Credentials struct {
+	//Certificate is the key pair of this party
+	Certificate tls.Certificate
+	//Peers holds the certificate of every party by index
+	Peers map[int]*x509.Certificate
+	//Roots are the authorities that issued the certificates
+	Roots *x509.CertPool
+}
+
+//NewTLS creates a network where all connections are mutually authenticated TLS channels
+func NewTLS(codec network.Codec, credentials Credentials) *Tcpnetwork {
+	tn := New(codec)
+	tn.credentials = &credentials
+	return tn
+}
+
+//LoadCredentials reads PEM encoded certificates and the key of this party
+func LoadCredentials(certFile, keyFile, caFile string, peerCertFiles map[int]string) (Credentials, error) {
+	var credentials Credentials
+	var err error
+	credentials.Certificate, err = tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return credentials, err
+	}
+
+	caPEM, err := os.ReadFile(caFile)
+	if err != nil {
+		return credentials, err
+	}
+	credentials.Roots = x509.NewCertPool()
+	if !credentials.Roots.AppendCertsFromPEM(caPEM) {
+		return credentials, fmt.Errorf("tcpnetwork: no certificates in %s", caFile)
+	}
+
+	credentials.Peers = make(map[int]*x509.Certificate, len(peerCertFiles))
+	for index, path := range peerCertFiles {
+		certPEM, err := os.ReadFile(path)
+		if err != nil {
+			return credentials, err
+		}
+		block, _ := pem.Decode(certPEM)
+		if block == nil || block.Type != "CERTIFICATE" {
+			return credentials, fmt.Errorf("tcpnetwork: no certificate in %s", path)
+		}
+		credentials.Peers[index], err = x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return credentials, err
+		}
+	}
+	return credentials, nil
+}
+
+func (c *Credentials) serverConfig() *tls.Config {
+	return &tls.Config{
+		Certificates: []tls.Certificate{c.Certificate},
+		ClientAuth:   tls.RequireAndVerifyClientCert,
+		ClientCAs:    c.Roots,
+		MinVersion:   tls.VersionTLS13,
+	}
+}
+
+//clientConfig verifies that the server is the party with the given index.
+//Host names are not checked as parties are identified by their pinned certificate.
+func (c *Credentials) clientConfig(index int) *tls.Config {
+	return &tls.Config{
+		Certificates:       []tls.Certificate{c.Certificate},
+		InsecureSkipVerify: true,
+		MinVersion:         tls.VersionTLS13,
+		VerifyConnection: func(state tls.ConnectionState) error {
+			return c.verify(state, index)
+		},
+	}
+}
+
+//verify checks that the peer presented the certificate pinned to index and that it is issued by a root
+func (c *Credentials) verify(state tls.ConnectionState, index int) error {
+	if len(state.PeerCertificates) == 0 {
+		return errors.New("tcpnetwork: peer presented no certificate")
+	}
+	leaf := state.PeerCertificates[0]
+	expected, exists := c.Peers[index]
+	if !exists {
+		return fmt.Errorf("tcpnetwork: no certificate pinned for party %d", index)
+	}
+	if !leaf.Equal(expected) {
+		return fmt.Errorf("tcpnetwork: peer did not present the certificate of party %d", index)
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := leaf.Verify(x509.VerifyOptions{
+		Roots:         c.Roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+	})
+	return err
+}
+
+func (tn *Tcpnetwork) listen(address string) (net.Listener, error) {
+	listener, err := net.Listen("tcp", address)
+	if err != nil || tn.credentials == nil {
+		return listener, err
+	}
+	return tls.NewListener(listener, tn.credentials.serverConfig()), nil
+}
+
+//handshake authenticates the party listening at the other end of conn as receiver
+func (tn *Tcpnetwork) handshake(conn net.Conn, receiver int) (net.Conn, error) {
+	if tn.credentials == nil {
+		return conn, nil
+	}
+	tlsConn := tls.Client(conn, tn.credentials.clientConfig(receiver))
+	if err := tlsConn.Handshake(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+//authenticate checks that a connection claiming to be from sender is from sender
+func (tn *Tcpnetwork) authenticate(conn net.Conn, sender int) error {
+	tlsConn, isTLS := conn.(*tls.Conn)
+	if tn.credentials == nil {
+		return nil
+	}
+	if !isTLS {
+		return errors.New("tcpnetwork: connection is not authenticated")
+	}
+	return tn.credentials.verify(tlsConn.ConnectionState(), sender)
+}
@@ -0,0 +1,185 @@
+package tcpnetwork
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"strconv"
+	"testing"
+	"time"
+)
+
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pool *x509.CertPool
+}
+
+//newAuthority creates a throwaway CA
+func newAuthority(t *testing.T) *authority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	return &authority{cert: cert, key: key, pool: pool}
+}
+
+func (a *authority) issue(index int, t *testing.T) tls.Certificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	serial, _ := rand.Int(rand.Reader, big.NewInt(1<<62))
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: "party " + strconv.Itoa(index)},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth, x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+//tlsSetting creates n parties where party i uses the key pair certificates[i-1]
+func tlsSetting(certificates []tls.Certificate, peers map[int]*x509.Certificate, roots *x509.CertPool, t *testing.T) ([]*recorder, []*Tcpnetwork) {
+	n := len(certificates)
+	handlers := make([]*recorder, n)
+	networks := make([]*Tcpnetwork, n)
+	addresses := make(map[int]string, n)
+	for i := range handlers {
+		handlers[i] = &recorder{index: i + 1}
+		networks[i] = NewTLS(stringCodec{}, Credentials{
+			Certificate: certificates[i],
+			Peers:       peers,
+			Roots:       roots,
+		})
+		networks[i].RegisterHandler(handlers[i])
+		networks[i].SetConnections(map[int]string{i + 1: "127.0.0.1:0"})
+		if err := networks[i].Listen(); err != nil {
+			t.Fatal(err)
+		}
+		addresses[i+1] = networks[i].Addr().String()
+	}
+	for _, tn := range networks {
+		tn.SetConnections(addresses)
+	}
+	return handlers, networks
+}
+
+func TestTLSSend(t *testing.T) {
+	ca := newAuthority(t)
+	n := 3
+	certificates := make([]tls.Certificate, n)
+	peers := make(map[int]*x509.Certificate, n)
+	for i := range certificates {
+		certificates[i] = ca.issue(i+1, t)
+		peers[i+1] = certificates[i].Leaf
+	}
+	handlers, networks := tlsSetting(certificates, peers, ca.pool, t)
+	defer func() {
+		for _, tn := range networks {
+			tn.Close()
+		}
+	}()
+
+	for _, handler := range handlers {
+		for receiver := 1; receiver <= n; receiver++ {
+			if receiver != handler.index {
+				handler.network.Send("hello", receiver)
+			}
+		}
+	}
+	for _, handler := range handlers {
+		waitFor(func() bool { return len(handler.messages()) == n-1 }, t)
+	}
+}
+
+func TestTLSRejectsWrongCertificate(t *testing.T) {
+	ca := newAuthority(t)
+	n := 3
+	certificates := make([]tls.Certificate, n)
+	peers := make(map[int]*x509.Certificate, n)
+	for i := range certificates {
+		certificates[i] = ca.issue(i+1, t)
+		peers[i+1] = certificates[i].Leaf
+	}
+	//Party 3 presents a certificate issued by the right CA, but not the one pinned for party 3
+	certificates[2] = ca.issue(3, t)
+
+	handlers, networks := tlsSetting(certificates, peers, ca.pool, t)
+	defer func() {
+		for _, tn := range networks {
+			tn.Close()
+		}
+	}()
+
+	//As a client party 3 is rejected by party 1
+	handlers[2].network.Send("impersonated", 1)
+	//As a server party 3 is rejected by party 2
+	handlers[1].network.Send("to impostor", 3)
+	//Party 2 is still accepted
+	handlers[1].network.Send("hello", 1)
+
+	waitFor(func() bool { return len(handlers[0].messages()) == 1 }, t)
+	time.Sleep(100 * time.Millisecond)
+	for _, m := range handlers[0].messages() {
+		if m.sender != 2 || m.data != "hello" {
+			t.Errorf("Party 1 accepted %v from %d", m.data, m.sender)
+		}
+	}
+	if len(handlers[2].messages()) != 0 {
+		t.Errorf("Party 3 received %v", handlers[2].messages())
+	}
+}
+
+func TestTLSRejectsUnknownAuthority(t *testing.T) {
+	ca := newAuthority(t)
+	rogue := newAuthority(t)
+	certificates := []tls.Certificate{ca.issue(1, t), rogue.issue(2, t)}
+	peers := map[int]*x509.Certificate{1: certificates[0].Leaf, 2: certificates[1].Leaf}
+
+	handlers, networks := tlsSetting(certificates, peers, ca.pool, t)
+	defer func() {
+		for _, tn := range networks {
+			tn.Close()
+		}
+	}()
+
+	handlers[1].network.Send("hello", 1)
+	time.Sleep(100 * time.Millisecond)
+	if len(handlers[0].messages()) != 0 {
+		t.Errorf("Party 1 accepted certificate from unknown authority")
+	}
+}
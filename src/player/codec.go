@@ -0,0 +1,214 @@
+package player
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"../bigshamir"
+	"../field"
+)
+
+//CodecVersion is the version of the wire format written by Codec
+const CodecVersion = 1
+
+//Message type tags
+const (
+	identifiedShareTag byte = iota + 1
+	reconstructionShareTag
+	multiplicationShareTag
+	localRandomFieldElementShareTag
+	aSquaredShareTag
+	configDigestTag
+	vssRowTag
+	vssCheckTag
+	vssComplaintTag
+	vssComplaintsTag
+	vssRevealTag
+	vssRevealsTag
+	vssVoteTag
+	packedMultiplicationShareTag
+	refreshShareTag
+	vssAnswerTag
+)
+
+//headerSize is the size of the envelope excluding the identifier and the field element:
+//version (1) | tag (1) | sender (4) | x (4) | iteration (4) | identifier length (2)
+const headerSize = 1 + 1 + 4 + 4 + 4 + 2
+
+//Codec encodes the messages players send each other.
+//Every message is written as a versioned envelope
+//followed by the identifier and a field element of fixed width.
+type Codec[E any] struct {
+	field       field.Field[E]
+	index       int
+	elementSize int
+}
+
+//NewCodec creates a codec for the player with the given index computing in the field f
+func NewCodec[E any](f field.Field[E], index int) *Codec[E] {
+	return &Codec[E]{
+		field:       f,
+		index:       index,
+		elementSize: f.Size(),
+	}
+}
+
+//Codec for the messages sent by p
+func (p *Player[E]) Codec() *Codec[E] {
+	return p.codec
+}
+
+//EncodedSize is the number of bytes of any message with an identifier of idLength bytes
+func (c *Codec[E]) EncodedSize(idLength int) int {
+	return headerSize + idLength + c.elementSize
+}
+
+//Encode ...
+func (c *Codec[E]) Encode(data interface{}) ([]byte, error) {
+	var (
+		tag       byte
+		id        string
+		point     bigshamir.SecretShare[E]
+		sender    = c.index
+		iteration int
+	)
+	switch t := data.(type) {
+	case identifiedShare[E]:
+		tag, id, point = identifiedShareTag, t.id, t.point
+	case reconstructionShare[E]:
+		tag, id, point = reconstructionShareTag, t.id, t.point
+	case multiplicationShare[E]:
+		tag, id, point = multiplicationShareTag, t.id, t.recombinationShare.SecretShare
+		sender = t.recombinationShare.Index
+	case localRandomFieldElementShare[E]:
+		tag, id, point = localRandomFieldElementShareTag, t.id, t.point
+		sender, iteration = t.index, t.iteration
+	case aSquaredShare[E]:
+		tag, id, point = aSquaredShareTag, t.id, t.point
+		iteration = t.iteration
+	case configDigest:
+		tag, id, point = configDigestTag, t.digest, bigshamir.SecretShare[E]{Y: c.field.Zero()}
+	case packedMultiplicationShare[E]:
+		tag, id, point = packedMultiplicationShareTag, t.id, t.recombinationShare.SecretShare
+		sender, iteration = t.recombinationShare.Index, t.k
+	case refreshShare[E]:
+		tag, id, point = refreshShareTag, t.id, t.point
+		sender = t.dealer
+	case vssRow[E]:
+		tag, id, point = vssRowTag, t.id, bigshamir.SecretShare[E]{Y: t.value}
+		iteration = t.coefficient
+	case vssCheck[E]:
+		tag, id, point = vssCheckTag, t.id, bigshamir.SecretShare[E]{Y: t.value}
+	case vssComplaint[E]:
+		tag, id, point = vssComplaintTag, t.id, bigshamir.SecretShare[E]{X: t.against, Y: t.value}
+	case vssComplaints:
+		tag, id, point = vssComplaintsTag, t.id, bigshamir.SecretShare[E]{X: t.count, Y: c.field.Zero()}
+	case vssReveal[E]:
+		tag, id, point = vssRevealTag, t.id, bigshamir.SecretShare[E]{Y: t.value}
+		sender, iteration = t.party, t.coefficient
+	case vssAnswer:
+		tag, id, point = vssAnswerTag, t.id, bigshamir.SecretShare[E]{X: t.complainant, Y: c.field.Zero()}
+	case vssReveals:
+		tag, id, point = vssRevealsTag, t.id, bigshamir.SecretShare[E]{X: t.count, Y: c.field.Zero()}
+		iteration = t.answers
+	case vssVote:
+		accept := 0
+		if t.accept {
+			accept = 1
+		}
+		tag, id, point = vssVoteTag, t.id, bigshamir.SecretShare[E]{X: accept, Y: c.field.Zero()}
+	default:
+		return nil, fmt.Errorf("codec: cannot encode %T", data)
+	}
+	if len(id) > 0xFFFF {
+		return nil, fmt.Errorf("codec: identifier of %d bytes is too long", len(id))
+	}
+
+	b := make([]byte, c.EncodedSize(len(id)))
+	b[0] = CodecVersion
+	b[1] = tag
+	binary.BigEndian.PutUint32(b[2:], uint32(sender))
+	binary.BigEndian.PutUint32(b[6:], uint32(point.X))
+	binary.BigEndian.PutUint32(b[10:], uint32(iteration))
+	binary.BigEndian.PutUint16(b[14:], uint16(len(id)))
+	copy(b[headerSize:], id)
+	c.field.Encode(b[headerSize+len(id):], point.Y)
+
+	return b, nil
+}
+
+//Decode ...
+func (c *Codec[E]) Decode(b []byte) (interface{}, error) {
+	if len(b) < headerSize {
+		return nil, errors.New("codec: message too short")
+	}
+	if b[0] != CodecVersion {
+		return nil, fmt.Errorf("codec: unsupported version %d", b[0])
+	}
+	idLength := int(binary.BigEndian.Uint16(b[14:]))
+	if len(b) != c.EncodedSize(idLength) {
+		return nil, fmt.Errorf("codec: message of %d bytes should be %d bytes", len(b), c.EncodedSize(idLength))
+	}
+
+	sender := int(binary.BigEndian.Uint32(b[2:]))
+	iteration := int(binary.BigEndian.Uint32(b[10:]))
+	id := string(b[headerSize : headerSize+idLength])
+	y, err := c.field.Decode(b[headerSize+idLength:])
+	if err != nil {
+		return nil, fmt.Errorf("codec: %w", err)
+	}
+	point := bigshamir.SecretShare[E]{
+		X: int(binary.BigEndian.Uint32(b[6:])),
+		Y: y,
+	}
+
+	switch b[1] {
+	case identifiedShareTag:
+		return identifiedShare[E]{point: point, id: id}, nil
+	case reconstructionShareTag:
+		return reconstructionShare[E]{point: point, id: id}, nil
+	case multiplicationShareTag:
+		return multiplicationShare[E]{
+			recombinationShare: bigshamir.RecombinationShare[E]{
+				SecretShare: point,
+				Index:       sender,
+			},
+			id: id,
+		}, nil
+	case localRandomFieldElementShareTag:
+		return localRandomFieldElementShare[E]{point: point, id: id, index: sender, iteration: iteration}, nil
+	case aSquaredShareTag:
+		return aSquaredShare[E]{point: point, id: id, iteration: iteration}, nil
+	case configDigestTag:
+		return configDigest{digest: id}, nil
+	case packedMultiplicationShareTag:
+		return packedMultiplicationShare[E]{
+			recombinationShare: bigshamir.RecombinationShare[E]{
+				SecretShare: point,
+				Index:       sender,
+			},
+			id: id,
+			k:  iteration,
+		}, nil
+	case refreshShareTag:
+		return refreshShare[E]{point: point, id: id, dealer: sender}, nil
+	case vssRowTag:
+		return vssRow[E]{id: id, coefficient: iteration, value: y}, nil
+	case vssCheckTag:
+		return vssCheck[E]{id: id, value: y}, nil
+	case vssComplaintTag:
+		return vssComplaint[E]{id: id, against: point.X, value: y}, nil
+	case vssComplaintsTag:
+		return vssComplaints{id: id, count: point.X}, nil
+	case vssRevealTag:
+		return vssReveal[E]{id: id, party: sender, coefficient: iteration, value: y}, nil
+	case vssAnswerTag:
+		return vssAnswer{id: id, complainant: point.X}, nil
+	case vssRevealsTag:
+		return vssReveals{id: id, count: point.X, answers: iteration}, nil
+	case vssVoteTag:
+		return vssVote{id: id, accept: point.X == 1}, nil
+	}
+	return nil, fmt.Errorf("codec: unknown message type %d", b[1])
+}
@@ -0,0 +1,113 @@
+package player
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"../bigshamir"
+	"../field"
+)
+
+func TestCodecRoundTrip(t *testing.T) {
+	codec := NewCodec(field.NewBig(big.NewInt(4001)), 2)
+	point := bigshamir.SecretShare[*big.Int]{X: 3, Y: big.NewInt(4000)}
+
+	messages := []interface{}{
+		identifiedShare[*big.Int]{point: point, id: "a"},
+		reconstructionShare[*big.Int]{point: point, id: "b"},
+		multiplicationShare[*big.Int]{
+			recombinationShare: bigshamir.RecombinationShare[*big.Int]{SecretShare: point, Index: 2},
+			id:                 "a*b",
+		},
+		localRandomFieldElementShare[*big.Int]{point: point, id: "r", index: 2, iteration: 7},
+		aSquaredShare[*big.Int]{point: point, id: "", iteration: 1},
+		configDigest{digest: "d1gest"},
+		packedMultiplicationShare[*big.Int]{
+			recombinationShare: bigshamir.RecombinationShare[*big.Int]{SecretShare: point, Index: 5},
+			id:                 "v",
+			k:                  3,
+		},
+		refreshShare[*big.Int]{point: point, id: "x_refresh_1", dealer: 3},
+		vssRow[*big.Int]{id: "x", coefficient: 2, value: big.NewInt(4000)},
+		vssCheck[*big.Int]{id: "x", value: big.NewInt(5)},
+		vssComplaint[*big.Int]{id: "x", against: 4, value: big.NewInt(6)},
+		vssComplaints{id: "x", count: 3},
+		vssReveal[*big.Int]{id: "x", party: 4, coefficient: 1, value: big.NewInt(7)},
+		vssAnswer{id: "x", complainant: 3},
+		vssReveals{id: "x", count: 1, answers: 2},
+		vssVote{id: "x", accept: true},
+		vssVote{id: "x", accept: false},
+	}
+
+	for _, message := range messages {
+		b, err := codec.Encode(message)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := codec.Decode(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(message, decoded) {
+			t.Errorf("Expected %v after round trip, got %v", message, decoded)
+		}
+	}
+}
+
+func TestCodecIsTheSameForEveryField(t *testing.T) {
+	mont64, _ := field.NewMont64(11)
+	b, err := NewCodec(mont64, 1).Encode(identifiedShare[field.Mont64Element]{
+		point: bigshamir.SecretShare[field.Mont64Element]{X: 1, Y: mont64.FromInt(-1)},
+		id:    "negative",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := NewCodec(field.NewBig(big.NewInt(11)), 1).Decode(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	shouldBe(10, decoded.(identifiedShare[*big.Int]).point.Y, "-1 mod 11", t)
+}
+
+func TestCodecEncodedSize(t *testing.T) {
+	test := func(prime *big.Int, elementSize int) {
+		codec := NewCodec(field.NewBig(prime), 1)
+		b, err := codec.Encode(identifiedShare[*big.Int]{
+			point: bigshamir.SecretShare[*big.Int]{X: 1, Y: big.NewInt(1)},
+			id:    "id",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(b) != codec.EncodedSize(2) || len(b) != headerSize+2+elementSize {
+			t.Errorf("Encoded %d bytes for %d bit prime, expected %d", len(b), prime.BitLen(), codec.EncodedSize(2))
+		}
+	}
+	test(big.NewInt(11), 1)
+	test(big.NewInt(4001), 2)
+	test(new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 127), big.NewInt(1)), 16)
+}
+
+func TestCodecRejectsMalformed(t *testing.T) {
+	codec := NewCodec(field.NewBig(big.NewInt(11)), 1)
+	b, _ := codec.Encode(identifiedShare[*big.Int]{point: bigshamir.SecretShare[*big.Int]{X: 1, Y: big.NewInt(1)}, id: "id"})
+
+	if _, err := codec.Decode(b[:len(b)-1]); err == nil {
+		t.Error("Decoded truncated message")
+	}
+	wrongVersion := append([]byte(nil), b...)
+	wrongVersion[0] = CodecVersion + 1
+	if _, err := codec.Decode(wrongVersion); err == nil {
+		t.Error("Decoded message of unknown version")
+	}
+	outOfRange := append([]byte(nil), b...)
+	outOfRange[len(outOfRange)-1] = 11
+	if _, err := codec.Decode(outOfRange); err == nil {
+		t.Error("Decoded field element out of range")
+	}
+	if _, err := codec.Encode("data"); err == nil {
+		t.Error("Encoded unknown type")
+	}
+}
@@ -0,0 +1,132 @@
+package player
+
+import (
+	"fmt"
+
+	"../bigshamir"
+)
+
+//packedMultiplicationShare is a share of the degree reduction of a party in a multiplication of packed sharings of k values
+type packedMultiplicationShare[E any] struct {
+	recombinationShare bigshamir.RecombinationShare[E]
+	id                 string
+	k                  int
+}
+
+//PackedMultiplicationKind is the kind of messages of multiplications of packed sharings
+const PackedMultiplicationKind = "packed multiplication"
+
+func (m packedMultiplicationShare[E]) kind() string       { return PackedMultiplicationKind }
+func (m packedMultiplicationShare[E]) identifier() string { return m.id }
+
+//packed is the scheme of packed sharings of k values
+func (p *Player[E]) packed(k int) *bigshamir.PackedSecretSharingScheme[E] {
+	p.packedLock.Lock()
+	defer p.packedLock.Unlock()
+	ss, exists := p.packedSchemes[k]
+	if !exists {
+		ss = new(bigshamir.PackedSecretSharingScheme[E])
+		*ss = bigshamir.NewPackedScheme(p.field, p.threshold, k, p.n)
+		p.packedSchemes[k] = ss
+	}
+	return ss
+}
+
+//SharePacked shares the values x in one packed sharing as identifier.
+//The sharing has degree t+k-1 for k values, so t+k shares open it.
+func (p *Player[E]) SharePacked(x []E, identifier string) {
+	for _, point := range p.packed(len(x)).Share(x) {
+		p.Send(identifiedShare[E]{point: point, id: identifier}, point.X)
+	}
+}
+
+//MultiplyPacked multiplies packed sharings of k values element-wise.
+//Parties 1 to 2(t+k-1)+1 reshare their local products, so there must be at least as many parties.
+func (p *Player[E]) MultiplyPacked(aID, bID, cID string, k int) {
+	ss := p.packed(k)
+	if ss.Multipliers() > p.n {
+		p.abort(fmt.Errorf("player: multiplying packed sharings of %d values needs %d parties, there are %d", k, ss.Multipliers(), p.n))
+		return
+	}
+	a, _ := p.getShareValue(aID)
+	b, _ := p.getShareValue(bID)
+
+	//Mark cID as being multiplied, so a timeout can name the parties whose shares are missing
+	p.multShareLock.Lock()
+	p.packedMultiplications[cID] = k
+	p.multShareLock.Unlock()
+	if p.index > ss.Multipliers() {
+		return
+	}
+	for _, share := range ss.ReshareProduct(p.index, p.field.Mul(a, b)) {
+		p.Send(packedMultiplicationShare[E]{
+			recombinationShare: bigshamir.RecombinationShare[E]{SecretShare: share, Index: p.index},
+			id:                 cID,
+			k:                  k,
+		}, share.X)
+	}
+
+	//Handle sums the shares once those of all multipliers have arrived
+}
+
+//handlePackedMultiplicationShare sums the shares of a packed multiplication once those of all multipliers have arrived
+func (p *Player[E]) handlePackedMultiplicationShare(share packedMultiplicationShare[E], sender int) {
+	if share.k < 1 || share.recombinationShare.Index != sender {
+		return
+	}
+	ss := p.packed(share.k)
+	if share.recombinationShare.Index < 1 || share.recombinationShare.Index > ss.Multipliers() {
+		return
+	}
+	p.multShareLock.Lock()
+	shares := p.packedMultShares[share.id]
+	for _, other := range shares {
+		if other.Index == share.recombinationShare.Index {
+			//Duplicate
+			p.multShareLock.Unlock()
+			return
+		}
+	}
+	shares = append(shares, share.recombinationShare)
+	p.packedMultShares[share.id] = shares
+	p.multShareLock.Unlock()
+	if len(shares) == ss.Multipliers() {
+		p.setShareValue(share.id, ss.RecombineMultiplicationShares(shares), true)
+	}
+}
+
+//ReconstructPacked extracts the k values of a packed sharing opened with Open.
+//It waits for t+k shares, or if p is robust decodes the shares like Reconstruct, correcting up to (n-t-k)/2 wrong shares.
+func (p *Player[E]) ReconstructPacked(identifier string, k int) []E {
+	ss := p.packed(k)
+	if p.robust {
+		var values []E
+		opened := p.openRobustly(identifier, ss.Degree(), func(shares []bigshamir.SecretShare[E]) (wrong []int, err error) {
+			values, wrong, err = ss.RobustReconstruct(shares)
+			return wrong, err
+		})
+		if !opened {
+			return p.zeros(k)
+		}
+		return values
+	}
+	points, opened := p.openedShares(identifier, ss.Degree()+1, p.deadline())
+	if !opened {
+		return p.zeros(k)
+	}
+	values, err := ss.Reconstruct(p.mapToPoints(points))
+	if err != nil {
+		p.abort(fmt.Errorf("player: opening %s: %w", identifier, err))
+		return p.zeros(k)
+	}
+	return values
+}
+
+//zeros are k zeros, the values of a packed sharing that could not be opened
+func (p *Player[E]) zeros(k int) []E {
+	zeros := make([]E, k)
+	for i := range zeros {
+		zeros[i] = p.field.Zero()
+	}
+	return zeros
+}
@@ -0,0 +1,117 @@
+package player
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"../network/faultnetwork"
+)
+
+func TestVectorInstructions(t *testing.T) {
+	//Multiplying packed sharings of 3 values with threshold 1 needs 2(1+3-1)+1 = 7 parties
+	parties := setting(big.NewInt(4001), 1, 7)
+	program := []instruction{
+		{"VINPUT", "1", "v", "a", "b", "c"},
+		{"VINPUT", "2", "w", "d", "e", "f"},
+		{"VMULTIPLY", "v", "w", "x"},
+		{"VPLUS", "x", "v", "y"},
+		{"VMINUS", "y", "w", "z"},
+		{"VOUTPUT", "z", "z1", "z2", "z3"},
+	}
+	for index, party := range parties {
+		party.instructions = program
+		switch index {
+		case 1:
+			party.setInput(map[string]*big.Int{"a": big.NewInt(1), "b": big.NewInt(2), "c": big.NewInt(3)})
+		case 2:
+			party.setInput(map[string]*big.Int{"d": big.NewInt(10), "e": big.NewInt(20), "f": big.NewInt(30)})
+		}
+	}
+	for index, output := range runParties(parties, t) {
+		//a*d + a - d, ...
+		shouldBe(1, output["z1"], "z1", t)
+		shouldBe(22, output["z2"], "z2", t)
+		shouldBe(63, output["z3"], "z3", t)
+		if inputs := parties[index].Stats().ReceivedByKind[InputKind].Messages; index > 2 && inputs != 2 {
+			t.Errorf("Party %d received %d input shares for 2 vectors", index, inputs)
+		}
+	}
+}
+
+func TestVectorMultiplyCostsLessThanMultiplyingEachValue(t *testing.T) {
+	sent := func(program []instruction, inputs map[string]*big.Int) int {
+		parties := setting(big.NewInt(4001), 1, 7)
+		for _, party := range parties {
+			party.instructions = program
+		}
+		parties[1].setInput(inputs)
+		runParties(parties, t)
+		messages := 0
+		for _, party := range parties {
+			stats := party.Stats()
+			messages += stats.SentByKind[MultiplicationKind].Messages + stats.SentByKind[PackedMultiplicationKind].Messages
+		}
+		return messages
+	}
+	inputs := map[string]*big.Int{"a": big.NewInt(1), "b": big.NewInt(2), "c": big.NewInt(3)}
+	scalar := sent([]instruction{
+		{"INPUT", "1", "a"}, {"INPUT", "1", "b"}, {"INPUT", "1", "c"},
+		{"MULTIPLY", "a", "a", "a2"}, {"MULTIPLY", "b", "b", "b2"}, {"MULTIPLY", "c", "c", "c2"},
+		{"OUTPUT", "a2", "a2"}, {"OUTPUT", "b2", "b2"}, {"OUTPUT", "c2", "c2"},
+	}, inputs)
+	packed := sent([]instruction{
+		{"VINPUT", "1", "v", "a", "b", "c"},
+		{"VMULTIPLY", "v", "v", "v2"},
+		{"VOUTPUT", "v2", "a2", "b2", "c2"},
+	}, inputs)
+	if packed >= scalar {
+		t.Errorf("Multiplying 3 packed values sent %d messages, multiplying them one by one %d", packed, scalar)
+	}
+}
+
+func TestVectorMultiplyNeedsEnoughParties(t *testing.T) {
+	parties := setting(big.NewInt(4001), 1, 3)
+	program := []instruction{{"VINPUT", "1", "v", "a", "b"}, {"VMULTIPLY", "v", "v", "w"}, {"VOUTPUT", "w", "a2", "b2"}}
+	for _, party := range parties {
+		party.instructions = program
+	}
+	parties[1].setInput(map[string]*big.Int{"a": big.NewInt(1), "b": big.NewInt(2)})
+	if _, err := parties[1].Run(); err == nil {
+		t.Error("Multiplied packed sharings of 2 values with 3 parties")
+	}
+}
+
+func TestVectorOperandsOfDifferentLength(t *testing.T) {
+	parties := setting(big.NewInt(4001), 1, 7)
+	parties[1].instructions = []instruction{
+		{"VINPUT", "1", "v", "a", "b"},
+		{"VINPUT", "1", "w", "a", "b", "c"},
+		{"VPLUS", "v", "w", "x"},
+	}
+	parties[1].setInput(map[string]*big.Int{"a": big.NewInt(1), "b": big.NewInt(2), "c": big.NewInt(3)})
+	if _, err := parties[1].Run(); err == nil {
+		t.Error("Added vectors of 2 and 3 values")
+	}
+}
+
+func TestVectorOutputRobustWithSilentParty(t *testing.T) {
+	faults := make(map[int]*faultnetwork.Faultnetwork)
+	parties := setting(big.NewInt(4001), 1, 7, withFaults(faults))
+	program := []instruction{{"VINPUT", "1", "v", "a", "b"}, {"VOUTPUT", "v", "a", "b"}}
+	for _, party := range parties {
+		party.instructions = program
+		party.SetRobust(true)
+		party.SetTimeout(5 * time.Second)
+	}
+	parties[1].setInput(map[string]*big.Int{"a": big.NewInt(1), "b": big.NewInt(2)})
+	//Party 7 never opens, so the others decode the shares of the 6 remaining parties
+	addRule(faults[7], faultnetwork.Rule{Kind: faultnetwork.Kind(reconstructionShare[*big.Int]{}), Action: faultnetwork.Drop}, t)
+	for index, output := range runParties(parties, t) {
+		if index == 7 {
+			continue
+		}
+		shouldBe(1, output["a"], "a", t)
+		shouldBe(2, output["b"], "b", t)
+	}
+}
@@ -2,24 +2,36 @@ package player
 
 import (
 	"bufio"
-	"crypto/rand"
+	"context"
 	"fmt"
+	"io"
 	"log"
 	"math/big"
 	"os"
+	"runtime"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"../bigshamir"
+	"../field"
 	"../network"
-	"../network/localnetwork"
+	"../network/batchnetwork"
+	"../network/broadcastnetwork"
+	"../network/channetwork"
+	"../network/recordnetwork"
+	"../network/relaynetwork"
+	"../network/sessionnetwork"
+	"../network/statsnetwork"
+	"../network/tcpnetwork"
 )
 
-//Player runs the protocol
-type Player struct {
+//Player runs the protocol, computing in a field with elements of type E
+type Player[E any] struct {
 	//Fixed after setup:
+	field     field.Field[E]
 	prime     *big.Int
 	threshold int
 	n         int
@@ -29,123 +41,184 @@ type Player struct {
 	bitLength    int
 	primeSharing []string
 
-	ss           *bigshamir.SecretSharingScheme
+	ss           *bigshamir.SecretSharingScheme[E]
 	network      network.Network
+	codec        *Codec[E]
+	pendingSends sync.WaitGroup
 	inputValues  map[string]*big.Int
 	instructions []instruction
 
+	//timeout bounds how long the player waits for data from other parties, zero waits forever
+	timeout time.Duration
+	//robust opens values from the shares of all parties, correcting wrong ones
+	robust bool
+	//verifiable shares inputs with verifiable secret sharing
+	verifiable bool
+
+	stats *statsnetwork.Recorder
+	//Set when data is sent and cleared when the player waits, which ends a round
+	sentSinceWait atomic.Bool
+
+	//Closed when the player is aborted, which releases all waiting routines
+	done      chan struct{}
+	abortOnce sync.Once
+	err       error
+
 	//Concurrently accessed:
 	//Regular shares
 	shareLock             sync.RWMutex
-	idVals                map[string]*big.Int
-	idValBlockingChannels map[string][]chan *big.Int
+	idVals                map[string]E
+	idValBlockingChannels map[string][]chan E
 	secrets               map[string]bool //subset of values
 
 	reconstructionShareLock             sync.RWMutex
-	reconstructionShares                map[string]map[int]*big.Int
-	reconstructionShareBlockingChannels map[string][]chan map[int]*big.Int
-
-	//Recombination shares for multiplication
-	multShareLock sync.RWMutex
-	multShares    map[string][]multiplicationShare
+	reconstructionShares                map[string]map[int]E
+	reconstructionShareBlockingChannels map[string][]openWaiter[E]
+	//Parties whose shares were wrong when a value was opened robustly
+	inconsistentShares map[string][]int
+
+	//Recombination shares for multiplication, of single values and of packed sharings
+	multShareLock    sync.RWMutex
+	multShares       map[string][]multiplicationShare[E]
+	packedMultShares map[string][]bigshamir.RecombinationShare[E]
+	//Number of values of the packed sharings multiplied, by identifier
+	packedMultiplications map[string]int
+
+	//Schemes of packed sharings by the number of values
+	packedLock    sync.Mutex
+	packedSchemes map[int]*bigshamir.PackedSecretSharingScheme[E]
 
 	//Random bit shares
 	randomBitLock           sync.RWMutex
-	randFieldElemShares     map[string][]localRandomFieldElementShare
-	randomBitASquaredShares map[string][]bigshamir.SecretShare
+	randFieldElemShares     map[string][]localRandomFieldElementShare[E]
+	randomBitASquaredShares map[string][]bigshamir.SecretShare[E]
+
+	//Shares of zero of refreshes by dealer, and the number of refreshes so far
+	refreshLock   sync.Mutex
+	refreshShares map[string]map[int]E
+	refreshes     int
+
+	//Verifiable sharings of inputs, and the dealers disqualified from them
+	vssLock      sync.Mutex
+	vssSharings  map[string]*vssSharing[E]
+	disqualified map[string]int
+
+	//Parties providing the inputs of the program
+	inputDealersOnce sync.Once
+	inputDealers     map[string]int
+
+	//Config digests of the parties, complete once all n have arrived
+	digestLock      sync.Mutex
+	digests         map[int]string
+	digestsComplete chan struct{}
 }
 
 type (
-	identifiedShare struct {
-		point bigshamir.SecretShare
+	identifiedShare[E any] struct {
+		point bigshamir.SecretShare[E]
 		id    string
 	}
-	reconstructionShare struct {
-		point bigshamir.SecretShare
+	reconstructionShare[E any] struct {
+		point bigshamir.SecretShare[E]
 		id    string
 	}
-	multiplicationShare struct {
-		recombinationShare bigshamir.RecombinationShare
+	multiplicationShare[E any] struct {
+		recombinationShare bigshamir.RecombinationShare[E]
 		id                 string
 	}
-	localRandomFieldElementShare struct {
-		point     bigshamir.SecretShare
+	localRandomFieldElementShare[E any] struct {
+		point     bigshamir.SecretShare[E]
 		id        string
 		index     int
 		iteration int
 	}
-	aSquaredShare struct {
-		point     bigshamir.SecretShare
+	aSquaredShare[E any] struct {
+		point     bigshamir.SecretShare[E]
 		id        string
 		iteration int
 	}
+	//configDigest identifies the config a party runs with
+	configDigest struct {
+		digest string
+	}
+	//openWaiter waits for count shares of an opened value
+	openWaiter[E any] struct {
+		count   int
+		channel chan map[int]E
+	}
 )
 
 //NewPlayer ...
-func NewPlayer(prime int64, threshold, n, index int) *Player {
-	p := new(Player)
-	p.prime = big.NewInt(prime)
+func NewPlayer[E any](f field.Field[E], threshold, n, index int) *Player[E] {
+	p := new(Player[E])
+	p.field = f
+	p.prime = f.Modulus()
 	p.threshold = threshold
 	p.n = n
 	p.l = p.prime.BitLen()
 	p.index = index
-	p.ss = new(bigshamir.SecretSharingScheme)
-	*p.ss = bigshamir.NewSS(prime, threshold, n)
-	p.idVals = make(map[string]*big.Int)
-	p.idValBlockingChannels = make(map[string][]chan *big.Int)
+	p.ss = new(bigshamir.SecretSharingScheme[E])
+	*p.ss = bigshamir.NewScheme(f, threshold, n)
+	p.idVals = make(map[string]E)
+	p.idValBlockingChannels = make(map[string][]chan E)
 	p.secrets = make(map[string]bool)
-	p.reconstructionShares = make(map[string]map[int]*big.Int)
-	p.reconstructionShareBlockingChannels = make(map[string][]chan map[int]*big.Int)
-	p.multShares = make(map[string][]multiplicationShare)
-	p.randFieldElemShares = make(map[string][]localRandomFieldElementShare)
-	p.randomBitASquaredShares = make(map[string][]bigshamir.SecretShare)
+	p.reconstructionShares = make(map[string]map[int]E)
+	p.reconstructionShareBlockingChannels = make(map[string][]openWaiter[E])
+	p.inconsistentShares = make(map[string][]int)
+	p.multShares = make(map[string][]multiplicationShare[E])
+	p.packedMultShares = make(map[string][]bigshamir.RecombinationShare[E])
+	p.packedMultiplications = make(map[string]int)
+	p.packedSchemes = make(map[int]*bigshamir.PackedSecretSharingScheme[E])
+	p.randFieldElemShares = make(map[string][]localRandomFieldElementShare[E])
+	p.randomBitASquaredShares = make(map[string][]bigshamir.SecretShare[E])
 	p.inputValues = make(map[string]*big.Int)
+	p.refreshShares = make(map[string]map[int]E)
+	p.vssSharings = make(map[string]*vssSharing[E])
+	p.disqualified = make(map[string]int)
+	p.digests = make(map[int]string)
+	p.digestsComplete = make(chan struct{})
+	p.done = make(chan struct{})
+	p.codec = NewCodec(f, p.index)
+	p.stats = statsnetwork.NewRecorder()
 
 	p.bitLength = p.prime.BitLen() + 1
 	p.primeSharing = make([]string, p.bitLength)
 	for i := range p.primeSharing {
 		id := "_primeSharing_" + strconv.Itoa(i)
 		p.primeSharing[i] = id
-		p.setShareValue(id, big.NewInt(int64(p.prime.Bit(i))), false)
+		p.setShareValue(id, f.FromInt(int64(p.prime.Bit(i))), false)
 	}
 
 	return p
 }
 
 //Share ...
-func (p *Player) Share(x *big.Int, identifier string) {
+func (p *Player[E]) Share(x E, identifier string) {
 	points := p.ss.Share(x)
 	for _, point := range points {
-		p.Send(identifiedShare{point: point, id: identifier}, point.X)
+		p.Send(identifiedShare[E]{point: point, id: identifier}, point.X)
 	}
 }
 
 //Open ...
-func (p *Player) Open(identifier string) {
+func (p *Player[E]) Open(identifier string) {
 	yValue, _ := p.getShareValue(identifier)
-	secretShare := bigshamir.SecretShare{
+	secretShare := bigshamir.SecretShare[E]{
 		X: p.index,
 		Y: yValue,
 	}
-	share := identifiedShare{
+	share := reconstructionShare[E]{
 		point: secretShare,
 		id:    identifier,
 	}
-	// p.reconstructionShareLock.Lock()
-	// p.reconstructionShares[identifier] = append(p.reconstructionShares[identifier], secretShare)
-	// p.reconstructionShareLock.Unlock()
-	for i := 1; i <= p.n; i++ {
-		if i == p.index {
-			continue
-		}
-		go p.Send(share, i)
-	}
+	//Our own share comes back through the network
+	p.Broadcast(share)
 }
 
-func (p *Player) mapToPoints(m map[int]*big.Int) (points []bigshamir.SecretShare) {
+func (p *Player[E]) mapToPoints(m map[int]E) (points []bigshamir.SecretShare[E]) {
 	p.reconstructionShareLock.RLock()
 	for x, y := range m {
-		share := bigshamir.SecretShare{
+		share := bigshamir.SecretShare[E]{
 			X: x,
 			Y: y,
 		}
@@ -155,33 +228,117 @@ func (p *Player) mapToPoints(m map[int]*big.Int) (points []bigshamir.SecretShare
 	return
 }
 
-//Reconstruct ...
-func (p *Player) Reconstruct(identifier string) *big.Int {
-	p.reconstructionShareLock.RLock()
-	points := p.reconstructionShares[identifier]
-	p.reconstructionShareLock.RUnlock()
+//Reconstruct extracts the value of identifier from the first t+1 shares, or decodes the shares if p is robust
+func (p *Player[E]) Reconstruct(identifier string) E {
+	if p.robust {
+		var secret E
+		opened := p.openRobustly(identifier, p.threshold, func(shares []bigshamir.SecretShare[E]) (wrong []int, err error) {
+			secret, wrong, err = p.ss.RobustReconstruct(shares)
+			return wrong, err
+		})
+		if !opened {
+			return p.field.Zero()
+		}
+		return secret
+	}
+	points, opened := p.openedShares(identifier, p.threshold+1, p.deadline())
+	if !opened {
+		return p.field.Zero()
+	}
+	secret, err := p.ss.Reconstruct(p.mapToPoints(points))
+	if err != nil {
+		p.abort(fmt.Errorf("player: opening %s: %w", identifier, err))
+		return p.field.Zero()
+	}
+	return secret
+}
 
-	if len(points) > p.threshold {
-		return p.ss.Reconstruct(p.mapToPoints(points))
+//openRobustly decodes the shares of identifier, a sharing of the given degree, with decode as soon as enough of them agree.
+//As t parties may never send their shares it starts once n-t shares have arrived, and accepts the decoding
+//if at least degree+1+t shares lie on it, t+1 of which are honest, or if the shares of all n parties are in.
+//Otherwise it waits for one more share and decodes again. The parties whose shares were wrong are recorded.
+//It returns false if more shares are wrong than can be corrected, in which case p is aborted, or if p is aborted meanwhile.
+func (p *Player[E]) openRobustly(identifier string, degree int, decode func(shares []bigshamir.SecretShare[E]) ([]int, error)) bool {
+	agreeing := degree + 1 + p.threshold
+	count := p.n - p.threshold
+	if count < degree+1 {
+		count = degree + 1
+	}
+	deadline := p.deadline()
+	for {
+		points, opened := p.openedShares(identifier, count, deadline)
+		if !opened {
+			return false
+		}
+		wrong, err := decode(p.mapToPoints(points))
+		if len(points) < p.n && (err != nil || len(points)-len(wrong) < agreeing) {
+			count = len(points) + 1
+			continue
+		}
+		if err != nil {
+			p.abort(fmt.Errorf("player: opening %s: %w", identifier, err))
+			return false
+		}
+		if len(wrong) > 0 {
+			p.reconstructionShareLock.Lock()
+			p.inconsistentShares[identifier] = wrong
+			p.reconstructionShareLock.Unlock()
+		}
+		return true
 	}
+}
 
+//openedShares waits until count shares of identifier have arrived and returns a copy of them,
+//or false if p is aborted meanwhile or the deadline passes
+func (p *Player[E]) openedShares(identifier string, count int, deadline <-chan time.Time) (map[int]E, bool) {
 	p.reconstructionShareLock.Lock()
-	points = p.reconstructionShares[identifier]
-	if len(points) > p.threshold {
+	if points := p.reconstructionShares[identifier]; len(points) >= count {
 		p.reconstructionShareLock.Unlock()
-		return p.ss.Reconstruct(p.mapToPoints(points))
+		return copyPoints(points), true
 	}
 
-	channel := make(chan map[int]*big.Int)
-	channels := p.reconstructionShareBlockingChannels[identifier]
-	p.reconstructionShareBlockingChannels[identifier] = append(channels, channel)
+	//Buffered, so Handle does not block on routines that stopped waiting
+	channel := make(chan map[int]E, 1)
+	waiters := p.reconstructionShareBlockingChannels[identifier]
+	p.reconstructionShareBlockingChannels[identifier] = append(waiters, openWaiter[E]{count: count, channel: channel})
 	p.reconstructionShareLock.Unlock()
-	points = <-channel
+	p.flush()
+	select {
+	case points := <-channel:
+		return points, true
+	case <-p.done:
+		return nil, false
+	case <-deadline:
+		p.reconstructionShareLock.RLock()
+		delivered := p.reconstructionShares[identifier]
+		missing := p.missingParties(func(index int) bool { _, exists := delivered[index]; return exists })
+		p.reconstructionShareLock.RUnlock()
+		p.timedOut(OpenKind, identifier, missing)
+		return nil, false
+	}
+}
 
-	return p.ss.Reconstruct(p.mapToPoints(points))
+//copyPoints copies shares by party, so they can be used while more shares arrive
+func copyPoints[E any](points map[int]E) map[int]E {
+	copied := make(map[int]E, len(points))
+	for x, y := range points {
+		copied[x] = y
+	}
+	return copied
 }
 
-func (p *Player) getShareValue(identifier string) (val *big.Int, isSecret bool) {
+//InconsistentShares lists, for every value opened robustly so far, the parties whose shares of it were wrong
+func (p *Player[E]) InconsistentShares() map[string][]int {
+	p.reconstructionShareLock.RLock()
+	defer p.reconstructionShareLock.RUnlock()
+	inconsistent := make(map[string][]int, len(p.inconsistentShares))
+	for identifier, parties := range p.inconsistentShares {
+		inconsistent[identifier] = parties
+	}
+	return inconsistent
+}
+
+func (p *Player[E]) getShareValue(identifier string) (val E, isSecret bool) {
 	p.shareLock.RLock()
 	val, exists := p.idVals[identifier]
 	isSecret = p.secrets[identifier]
@@ -198,11 +355,22 @@ func (p *Player) getShareValue(identifier string) (val *big.Int, isSecret bool)
 		p.shareLock.Unlock()
 		return
 	}
-	resultChannel := make(chan *big.Int)
+	//Buffered, so setShareValue does not block on routines that stopped waiting
+	resultChannel := make(chan E, 1)
 	channels := p.idValBlockingChannels[identifier]
 	p.idValBlockingChannels[identifier] = append(channels, resultChannel)
 	p.shareLock.Unlock()
-	val = <-resultChannel
+	p.flush()
+	select {
+	case val = <-resultChannel:
+	case <-p.done:
+		//The value is never used, as Run stops after the current instruction
+		return p.field.Zero(), false
+	case <-p.deadline():
+		kind, missing := p.pendingValue(identifier)
+		p.timedOut(kind, identifier, missing)
+		return p.field.Zero(), false
+	}
 
 	//todo send both over channel
 	p.shareLock.RLock()
@@ -212,8 +380,31 @@ func (p *Player) getShareValue(identifier string) (val *big.Int, isSecret bool)
 
 }
 
-func (p *Player) setShareValue(id string, val *big.Int, isSecret bool) {
+func (p *Player[E]) setShareValue(id string, val E, isSecret bool) {
+	p.shareLock.Lock()
+	p.storeShareValue(id, val, isSecret)
+	p.shareLock.Unlock()
+}
+
+//deleteShareValue forgets the value of id
+func (p *Player[E]) deleteShareValue(id string) {
+	p.shareLock.Lock()
+	delete(p.idVals, id)
+	delete(p.secrets, id)
+	p.shareLock.Unlock()
+}
+
+//setNewShareValue sets the value of id like setShareValue, unless id already has a value
+func (p *Player[E]) setNewShareValue(id string, val E, isSecret bool) {
 	p.shareLock.Lock()
+	if _, exists := p.idVals[id]; !exists {
+		p.storeShareValue(id, val, isSecret)
+	}
+	p.shareLock.Unlock()
+}
+
+//storeShareValue sets the value of id and hands it to the routines waiting for it, shareLock must be held
+func (p *Player[E]) storeShareValue(id string, val E, isSecret bool) {
 	p.idVals[id] = val
 	if isSecret {
 		p.secrets[id] = true
@@ -222,84 +413,71 @@ func (p *Player) setShareValue(id string, val *big.Int, isSecret bool) {
 		channel <- val
 	}
 	delete(p.idValBlockingChannels, id)
-	p.shareLock.Unlock()
 }
 
 //Add ...
-func (p *Player) Add(aID, bID, cID string) {
-	sum := new(big.Int)
+func (p *Player[E]) Add(aID, bID, cID string) {
 	a, aIsSecret := p.getShareValue(aID)
 	b, bIsSecret := p.getShareValue(bID)
-	sum.Add(a, b)
-	sum.Mod(sum, p.prime)
+	sum := p.field.Add(a, b)
 
 	secret := aIsSecret || bIsSecret
 	p.setShareValue(cID, sum, secret)
 }
 
 //AddConstant ...
-func (p *Player) AddConstant(aShare *big.Int, bID, cID string) {
-	sum := new(big.Int)
+func (p *Player[E]) AddConstant(aShare E, bID, cID string) {
 	b, bIsSecret := p.getShareValue(bID)
-	sum.Add(aShare, b)
-	sum.Mod(sum, p.prime)
+	sum := p.field.Add(aShare, b)
 
 	p.setShareValue(cID, sum, bIsSecret)
 }
 
 //Sub ...
-func (p *Player) Sub(aID, bID, cID string) {
+func (p *Player[E]) Sub(aID, bID, cID string) {
 	a, aIsSecret := p.getShareValue(aID)
 	b, bIsSecret := p.getShareValue(bID)
 
-	res := new(big.Int)
-	res.Sub(a, b)
-	res.Mod(res, p.prime)
+	res := p.field.Sub(a, b)
 
 	secret := aIsSecret || bIsSecret
 	p.setShareValue(cID, res, secret)
 }
 
 //SubFromConstant ...
-func (p *Player) SubFromConstant(aShare *big.Int, bID, cID string) {
+func (p *Player[E]) SubFromConstant(aShare E, bID, cID string) {
 	b, bIsSecret := p.getShareValue(bID)
 
-	res := new(big.Int)
-	res.Sub(aShare, b)
-	res.Mod(res, p.prime)
+	res := p.field.Sub(aShare, b)
 
 	p.setShareValue(cID, res, bIsSecret)
 }
 
 //SubConstant ...
-func (p *Player) SubConstant(aID string, bID *big.Int, cID string) {
+func (p *Player[E]) SubConstant(aID string, bID E, cID string) {
 	a, aIsSecret := p.getShareValue(aID)
 
-	res := new(big.Int)
-	res.Add(a, bID)
-	res.Mod(res, p.prime)
+	res := p.field.Add(a, bID)
 
 	p.setShareValue(cID, res, aIsSecret)
 }
 
 //Scale ...
-func (p *Player) Scale(scalar *big.Int, aID, bID string) {
+func (p *Player[E]) Scale(scalar E, aID, bID string) {
 	a, aIsSecret := p.getShareValue(aID)
 
-	res := new(big.Int)
-	res.Mul(a, scalar)
-	res.Mod(res, p.prime)
+	res := p.field.Mul(a, scalar)
 
 	p.setShareValue(bID, res, aIsSecret)
 }
 
 //Multiply ...
-func (p *Player) Multiply(aID, bID, cID string) {
+func (p *Player[E]) Multiply(aID, bID, cID string) {
 	a, aIsSecret := p.getShareValue(aID)
 	b, bIsSecret := p.getShareValue(bID)
 
 	//Compute and secret share product of local shares
-	localProduct := new(big.Int).Mul(a, b)
+	localProduct := p.field.Mul(a, b)
 
 	cIsSecret := aIsSecret || bIsSecret
 	shouldReconconstruct := aIsSecret && bIsSecret
@@ -309,29 +487,37 @@ func (p *Player) Multiply(aID, bID, cID string) {
 		return
 	}
 
-	//Secret multiplication
+	//Secret multiplication. Mark cID as being multiplied, so a timeout can name the parties whose shares are missing.
+	p.multShareLock.Lock()
+	if _, exists := p.multShares[cID]; !exists {
+		p.multShares[cID] = nil
+	}
+	p.multShareLock.Unlock()
+	if !p.reshares(p.index) {
+		return
+	}
 	for _, share := range p.ss.Share(localProduct) {
-		ms := multiplicationShare{
-			recombinationShare: bigshamir.RecombinationShare{
+		ms := multiplicationShare[E]{
+			recombinationShare: bigshamir.RecombinationShare[E]{
 				SecretShare: share,
 				Index:       p.index,
 			},
 			id: cID,
 		}
-		go p.Send(ms, share.X)
+		p.Send(ms, share.X)
 	}
 
-	//Wait for 2t+1 sharings of local products
-	p.multShareLock.RLock()
-	shares := p.multShares[cID]
-	p.multShareLock.RUnlock()
-	if len(shares) >= p.threshold*2+1 {
-		p.recombineMultiplicationShares(cID, shares)
-	}
+	//Handle recombines the product once 2t+1 sharings of local products have arrived
+}
+
+//reshares tells whether party index shares its local products in a multiplication.
+//Parties 1 to 2t+1 do, so every party recombines the same sharings even if there are more parties.
+func (p *Player[E]) reshares(index int) bool {
+	return index <= 2*p.threshold+1
 }
 
-func (p *Player) recombineMultiplicationShares(cID string, shares []multiplicationShare) {
-	multShares := make([]bigshamir.RecombinationShare, len(shares))
+func (p *Player[E]) recombineMultiplicationShares(cID string, shares []multiplicationShare[E]) {
+	multShares := make([]bigshamir.RecombinationShare[E], len(shares))
 	for i := range shares {
 		multShares[i] = shares[i].recombinationShare
 	}
@@ -341,7 +527,7 @@ func (p *Player) recombineMultiplicationShares(cID string, shares []multiplicati
 }
 
 //GreaterThan takes shares of aShare and b as input and outputs 1 iff a > b, and 0 otherwise
-func (p *Player) GreaterThan(aID, bID, cID string) {
+func (p *Player[E]) GreaterThan(aID, bID, cID string) {
 
 	//compute sharings of bits of aShare and b:
 	aBitIDs := make([]string, p.l+1)
@@ -359,14 +545,14 @@ func (p *Player) GreaterThan(aID, bID, cID string) {
 }
 
 //GreaterThanOrEqual takes shares of aShare and b as input and outputs 1 iff a >= b, and 0 otherwise
-func (p *Player) GreaterThanOrEqual(aID, bID, cID string) {
+func (p *Player[E]) GreaterThanOrEqual(aID, bID, cID string) {
 	bGreaterThanAID := cID + "_GreaterThanOrEqual_b>a"
 	p.GreaterThan(bID, aID, bGreaterThanAID)
-	p.SubFromConstant(big.NewInt(1), bGreaterThanAID, cID)
+	p.SubFromConstant(p.field.One(), bGreaterThanAID, cID)
 }
 
 //NotEqual takes shares of aShare and b as input and outputs 1 iff a != b, and 0 otherwise
-func (p *Player) NotEqual(aID, bID, cID string) {
+func (p *Player[E]) NotEqual(aID, bID, cID string) {
 	//todo temporary solution, should use Fermats little thm. and repeated squaring
 	aGreaterThanBID := cID + "_NotEqual_a>b"
 	p.GreaterThan(aID, bID, aGreaterThanBID)
@@ -380,15 +566,15 @@ func (p *Player) NotEqual(aID, bID, cID string) {
 }
 
 //Equal takes shares of aShare and b as input and outputs 1 iff a == b, and 0 otherwise
-func (p *Player) Equal(aID, bID, cID string) {
+func (p *Player[E]) Equal(aID, bID, cID string) {
 	notEqualID := cID + "_Equal_tmp"
 	p.NotEqual(aID, bID, notEqualID)
-	p.SubFromConstant(big.NewInt(1), notEqualID, cID)
+	p.SubFromConstant(p.field.One(), notEqualID, cID)
 }
 
 //For debugging
-func (p *Player) openBits(bitIDs []string) []*big.Int {
-	bits := make([]*big.Int, p.bitLength)
+func (p *Player[E]) openBits(bitIDs []string) []E {
+	bits := make([]E, p.bitLength)
 	for i, bit := range bitIDs {
 		p.Open(bit)
 		bits[p.bitLength-1-i] = p.Reconstruct(bit)
@@ -396,11 +582,11 @@ func (p *Player) openBits(bitIDs []string) []*big.Int {
 	return bits
 }
 
-func (p *Player) bits(ID string, resultBitIDs []string) {
+func (p *Player[E]) bits(ID string, resultBitIDs []string) {
 	rID, rBitIDs := p.randomSolvedBits(resultBitIDs[0])
 	p.Sub(ID, rID, resultBitIDs[0]+"_bits_c") //c = a - r
 	p.Open(resultBitIDs[0] + "_bits_c")
-	c := p.Reconstruct(resultBitIDs[0] + "_bits_c")
+	c := p.field.Big(p.Reconstruct(resultBitIDs[0] + "_bits_c"))
 
 	//Compute bit sharing of sum of r and c, i.e. bit sharing of ID
 	cBitIDs := make([]string, p.l+1)
@@ -415,7 +601,7 @@ func (p *Player) bits(ID string, resultBitIDs []string) {
 		cBitIDs[i] = resultBitIDs[i] + "_bits_cBits"
 		dBitIDs[i] = resultBitIDs[i] + "_bits_dBits"
 		epBitIDs[i] = resultBitIDs[i] + "_bits_cepBits"
-		go p.setShareValue(cBitIDs[i], big.NewInt(int64(c.Bit(i))), bitsAreSecret)
+		go p.setShareValue(cBitIDs[i], p.field.FromInt(int64(c.Bit(i))), bitsAreSecret)
 	}
 
 	p.bitAdd(rBitIDs, cBitIDs, dBitIDs)
@@ -423,7 +609,7 @@ func (p *Player) bits(ID string, resultBitIDs []string) {
 	e := resultBitIDs[0] + "_bits_compareBits_e"
 	p.bitCompare(p.primeSharing, dBitIDs, notE)
 	notEVal, notESecret := p.getShareValue(notE)
-	p.setShareValue(e, bitNot(notEVal), notESecret)
+	p.setShareValue(e, p.bitNot(notEVal), notESecret)
 
 	for i := range epBitIDs {
 		p.Multiply(e, p.primeSharing[i], epBitIDs[i])
@@ -432,7 +618,7 @@ func (p *Player) bits(ID string, resultBitIDs []string) {
 	p.bitSub(dBitIDs, epBitIDs, resultBitIDs)
 }
 
-func (p *Player) bitCompare(aBitIDs, bBitIDs []string, cBitID string) {
+func (p *Player[E]) bitCompare(aBitIDs, bBitIDs []string, cBitID string) {
 	//Compute sharing of XOR
 	xorShareIDs := make([]string, p.l+1)
 	for i := 0; i <= p.l; i++ {
@@ -448,12 +634,11 @@ func (p *Player) bitCompare(aBitIDs, bBitIDs []string, cBitID string) {
 		eBitIDs[i] = cBitID + "_bitCompare_e" + strconv.Itoa(i)
 		go p.Multiply(aBitIDs[i], dBitIDs[i], eBitIDs[i])
 	}
-	cShare := big.NewInt(0)
+	cShare := p.field.Zero()
 	for i := range eBitIDs {
 		eI, _ := p.getShareValue(eBitIDs[i])
-		cShare.Add(cShare, eI)
+		cShare = p.field.Add(cShare, eI)
 	}
-	cShare.Mod(cShare, p.prime)
 
 	p.shareLock.RLock()
 	resultIsSecret := p.secrets[aBitIDs[0]] || p.secrets[bBitIDs[0]]
@@ -462,36 +647,35 @@ func (p *Player) bitCompare(aBitIDs, bBitIDs []string, cBitID string) {
 	p.setShareValue(cBitID, cShare, resultIsSecret)
 }
 
-func (p *Player) bitXor(aBitID, bBitID, cBitID string) *big.Int {
+func (p *Player[E]) bitXor(aBitID, bBitID, cBitID string) E {
 	p.Multiply(aBitID, bBitID, cBitID+"_xor_tmp")
 	aShare, aIsSecret := p.getShareValue(aBitID)
 	bShare, bIsSecret := p.getShareValue(bBitID)
-	xorShare, _ := p.getShareValue(cBitID + "_xor_tmp") //ab
-	xorShare.Mul(big.NewInt(2), xorShare)               //2ab
-	xorShare.Neg(xorShare)                              //-2ab
-	xorShare.Add(xorShare, aShare)                      //a - 2ab
-	xorShare.Add(xorShare, bShare)                      //a+b-2ab
+	abShare, _ := p.getShareValue(cBitID + "_xor_tmp")     //ab
+	xorShare := p.field.Neg(p.field.Add(abShare, abShare)) //-2ab
+	xorShare = p.field.Add(xorShare, aShare)               //a - 2ab
+	xorShare = p.field.Add(xorShare, bShare)               //a+b-2ab
 
 	p.setShareValue(cBitID, xorShare, aIsSecret || bIsSecret)
 
 	return xorShare
 }
 
-func (p *Player) bitOr(aBitID, bBitID, cBitID string) *big.Int {
+func (p *Player[E]) bitOr(aBitID, bBitID, cBitID string) E {
 	p.Multiply(aBitID, bBitID, cBitID+"_or_tmp")
 	aShare, aIsSecret := p.getShareValue(aBitID)
 	bShare, bIsSecret := p.getShareValue(bBitID)
-	orShare, _ := p.getShareValue(cBitID + "_or_tmp") //ab
-	orShare.Neg(orShare)                              //-ab
-	orShare.Add(orShare, aShare)                      //a - ab
-	orShare.Add(orShare, bShare)                      //a + b - ab
+	abShare, _ := p.getShareValue(cBitID + "_or_tmp") //ab
+	orShare := p.field.Neg(abShare)                   //-ab
+	orShare = p.field.Add(orShare, aShare)            //a - ab
+	orShare = p.field.Add(orShare, bShare)            //a + b - ab
 
 	p.setShareValue(cBitID, orShare, aIsSecret || bIsSecret)
 
 	return orShare
 }
 
-func (p *Player) fullAdder(aBitID, bBitID, carryInBitID, carryOutBitID, cBitID string) {
+func (p *Player[E]) fullAdder(aBitID, bBitID, carryInBitID, carryOutBitID, cBitID string) {
 	//carry_out = (a & b) | (a & carry_in) | (b & carry_in)
 	//			= ! (!(a & b) & !(a & carry_in) & !(b & carry_in))
 	//			= 1 - ((1 - a * b) * (1 - a * carry_in) * (1 - b * carry_in))
@@ -502,43 +686,40 @@ func (p *Player) fullAdder(aBitID, bBitID, carryInBitID, carryOutBitID, cBitID s
 	ab, resultIsSecret := p.getShareValue(cBitID + "_a&b")
 	aCarryIn, _ := p.getShareValue(cBitID + "_a&carryIn")
 
-	p.setShareValue(cBitID+"_!a&b", bitNot(ab), resultIsSecret)
-	p.setShareValue(cBitID+"_!a&carryIn", bitNot(aCarryIn), resultIsSecret)
+	p.setShareValue(cBitID+"_!a&b", p.bitNot(ab), resultIsSecret)
+	p.setShareValue(cBitID+"_!a&carryIn", p.bitNot(aCarryIn), resultIsSecret)
 
 	p.Multiply(cBitID+"_!a&b", cBitID+"_!a&carryIn", cBitID+"_!a&b_&_!a&carryIn")
 
 	bCarryIn, _ := p.getShareValue(cBitID + "_b&carryIn")
-	p.setShareValue(cBitID+"_!b&carryIn", bitNot(bCarryIn), resultIsSecret)
+	p.setShareValue(cBitID+"_!b&carryIn", p.bitNot(bCarryIn), resultIsSecret)
 
 	p.Multiply(cBitID+"_!a&b_&_!a&carryIn", cBitID+"_!b&carryIn", cBitID+"_disjunct")
 
 	disjunctShare, _ := p.getShareValue(cBitID + "_disjunct")
-	carryOutShare := bitNot(disjunctShare)
-	carryOutShare.Mod(carryOutShare, p.prime)
+	carryOutShare := p.bitNot(disjunctShare)
 
 	p.setShareValue(carryOutBitID, carryOutShare, resultIsSecret)
 
 	//c = a + b + c - 2 * carry_out
-	resBitShare := new(big.Int).Mul(big.NewInt(2), carryOutShare)
-	resBitShare.Neg(resBitShare)
+	resBitShare := p.field.Neg(p.field.Add(carryOutShare, carryOutShare))
 	a, _ := p.getShareValue(aBitID)
 	b, _ := p.getShareValue(bBitID)
 	carryIn, _ := p.getShareValue(carryInBitID)
-	resBitShare.Add(resBitShare, a)
-	resBitShare.Add(resBitShare, b)
-	resBitShare.Add(resBitShare, carryIn)
-	resBitShare.Mod(resBitShare, p.prime)
+	resBitShare = p.field.Add(resBitShare, a)
+	resBitShare = p.field.Add(resBitShare, b)
+	resBitShare = p.field.Add(resBitShare, carryIn)
 
 	p.setShareValue(cBitID, resBitShare, resultIsSecret)
 }
 
-func bitNot(aBitShare *big.Int) *big.Int {
-	return new(big.Int).Sub(big.NewInt(1), aBitShare)
+func (p *Player[E]) bitNot(aBitShare E) E {
+	return p.field.Sub(p.field.One(), aBitShare)
 }
 
-func (p *Player) bitAdd(aBitIDs, bBitIDs, resBitIDs []string) {
+func (p *Player[E]) bitAdd(aBitIDs, bBitIDs, resBitIDs []string) {
 	if len(aBitIDs) != p.bitLength ||
-		len(aBitIDs) != p.bitLength ||
+		len(bBitIDs) != p.bitLength ||
 		len(resBitIDs) != p.bitLength { //todo + 1
 		panic("bit add different lengths")
 	}
@@ -546,7 +727,7 @@ func (p *Player) bitAdd(aBitIDs, bBitIDs, resBitIDs []string) {
 	for i := range resBitIDs {
 		if i == 0 {
 			//Carry in = 0
-			p.setShareValue(resBitIDs[i]+"_carry_in_0", big.NewInt(0), false)
+			p.setShareValue(resBitIDs[i]+"_carry_in_0", p.field.Zero(), false)
 			//initial carry is not secret
 			carryInID := resBitIDs[i] + "_carry_in_0"
 			carryOutID := resBitIDs[i] + "_carry_out_0"
@@ -561,7 +742,7 @@ func (p *Player) bitAdd(aBitIDs, bBitIDs, resBitIDs []string) {
 	//todo remove tmps?
 }
 
-func (p *Player) bitSub(aBitIDs, bBitIDs, resBitIDs []string) {
+func (p *Player[E]) bitSub(aBitIDs, bBitIDs, resBitIDs []string) {
 	if len(aBitIDs) != len(bBitIDs) || len(aBitIDs) != len(resBitIDs) {
 		panic("bit add different lengths")
 	}
@@ -569,14 +750,14 @@ func (p *Player) bitSub(aBitIDs, bBitIDs, resBitIDs []string) {
 	for i := range flippedBBitIDs {
 		flippedBBitIDs[i] = bBitIDs[i] + "_sub_flipped"
 		bit, isSecret := p.getShareValue(bBitIDs[i])
-		flippedBit := bitNot(bit)
+		flippedBit := p.bitNot(bit)
 		p.setShareValue(flippedBBitIDs[i], flippedBit, isSecret)
 	}
 
 	for i := range resBitIDs {
 		if i == 0 {
 			//Carry in = 1
-			p.setShareValue(resBitIDs[i]+"_carry_in_0", big.NewInt(1), false)
+			p.setShareValue(resBitIDs[i]+"_carry_in_0", p.field.One(), false)
 			//initial carry is not secret
 			carryInID := resBitIDs[i] + "_carry_in_0"
 			carryOutID := resBitIDs[i] + "_carry_out_0"
@@ -590,7 +771,7 @@ func (p *Player) bitSub(aBitIDs, bBitIDs, resBitIDs []string) {
 	//todo remove tmps?
 }
 
-func (p *Player) randomSolvedBits(identifier string) (fieldElemID string, bitIDs []string) {
+func (p *Player[E]) randomSolvedBits(identifier string) (fieldElemID string, bitIDs []string) {
 	fieldElemID = identifier + "_randBits_r"
 	bitIDs = make([]string, p.l+1)
 	xorBitIdentifiers := make([]string, p.l+1)
@@ -609,8 +790,11 @@ func (p *Player) randomSolvedBits(identifier string) (fieldElemID string, bitIDs
 		p.Open(comparisonID)
 
 		comparisonBit := p.Reconstruct(comparisonID)
+		if p.aborted() {
+			return
+		}
 
-		if comparisonBit.Sign() == 0 {
+		if p.field.IsZero(comparisonBit) {
 			iteration++
 			iterationString = "iteration" + strconv.Itoa(iteration)
 		} else {
@@ -618,37 +802,25 @@ func (p *Player) randomSolvedBits(identifier string) (fieldElemID string, bitIDs
 		}
 	}
 
-	fieldElementShare := big.NewInt(0)
+	fieldElementShare := p.field.Zero()
+	ithPowerOfTwo := p.field.One()
 	for i := range bitIDs {
 		bit, _ := p.getShareValue(bitIDs[i])
-		term := new(big.Int).Set(bit)
-		term.Lsh(term, uint(i))
-		fieldElementShare.Add(fieldElementShare, term)
+		fieldElementShare = p.field.Add(fieldElementShare, p.field.Mul(bit, ithPowerOfTwo))
+		ithPowerOfTwo = p.field.Add(ithPowerOfTwo, ithPowerOfTwo)
 	}
 
-	// ithPowerOfTwo := big.NewInt(1)
-	// two := big.NewInt(2)
-	// for i := range bitIDs {
-	// 	bit, _ := p.getShareValue(bitIDs[i])
-	// 	term := new(big.Int).Set(bit)
-	// 	term.Mul(term, ithPowerOfTwo)
-	// 	ithPowerOfTwo.Mul(ithPowerOfTwo, two)
-	// 	fieldElementShare.Add(fieldElementShare, term)
-	// }
-
-	fieldElementShare.Mod(fieldElementShare, p.prime)
-
 	p.setShareValue(fieldElemID, fieldElementShare, true)
 
 	return
 }
 
 //RandomBit stores a uniformly random bit as "identifier"
-func (p *Player) RandomBit(identifier string) {
+func (p *Player[E]) RandomBit(identifier string) {
 	iteration := 0
 	var (
-		aSquared *big.Int
-		aShare   *big.Int
+		aSquared E
+		aShare   E
 	)
 	for {
 		iterationIdentifier := identifier + "_iteration_" + strconv.Itoa(iteration)
@@ -656,11 +828,10 @@ func (p *Player) RandomBit(identifier string) {
 		p.RandomElement(iterationIdentifier)
 		aShare, _ = p.getShareValue(iterationIdentifier)
 		//Compute A = a^2
-		aSquaredShareVal := new(big.Int).Mul(aShare, aShare)
 		//suffices to multiply locally as we are immediately reconstructing
-		aSquaredShareVal.Mod(aSquaredShareVal, p.prime)
-		aSquaredShare := aSquaredShare{
-			point: bigshamir.SecretShare{
+		aSquaredShareVal := p.field.Mul(aShare, aShare)
+		aSquaredShare := aSquaredShare[E]{
+			point: bigshamir.SecretShare[E]{
 				X: p.index,
 				Y: aSquaredShareVal,
 			},
@@ -671,21 +842,44 @@ func (p *Player) RandomBit(identifier string) {
 		for i := 1; i <= p.n; i++ {
 			p.Send(aSquaredShare, i)
 		}
+		p.flush()
 
+		expired := p.deadline()
 		time.Sleep(time.Millisecond)
 		p.randomBitLock.RLock()
 		aSquaredShares := p.randomBitASquaredShares[iterationIdentifier]
 		p.randomBitLock.RUnlock()
 		for len(aSquaredShares) < p.n {
 			//todo seems like the time to use sync.Waitgroup
+			if p.aborted() {
+				return
+			}
+			select {
+			case <-expired:
+				p.timedOut(ASquaredKind, iterationIdentifier, p.missingParties(func(index int) bool {
+					for _, share := range aSquaredShares {
+						if share.X == index {
+							return true
+						}
+					}
+					return false
+				}))
+				return
+			default:
+			}
 			time.Sleep(time.Millisecond)
 			p.randomBitLock.RLock()
 			aSquaredShares = p.randomBitASquaredShares[iterationIdentifier]
 			p.randomBitLock.RUnlock()
 		}
 		//Reconstruct A
-		aSquared = p.ss.Reconstruct(aSquaredShares)
-		if aSquared.Sign() == 0 {
+		var err error
+		aSquared, err = p.ss.Reconstruct(aSquaredShares)
+		if err != nil {
+			p.abort(fmt.Errorf("player: opening %s: %w", iterationIdentifier, err))
+			return
+		}
+		if p.field.IsZero(aSquared) {
 			//The random field element was zero, try again
 			iteration++
 			//Some cleanup
@@ -698,53 +892,68 @@ func (p *Player) RandomBit(identifier string) {
 		}
 	}
 
-	b := new(big.Int).ModSqrt(aSquared, p.prime)
-	b.ModInverse(b, p.prime)
-	cShareVal := b.Mul(b, aShare) //c = b^-1 * a
-	cShareVal.Add(cShareVal, big.NewInt(1))
-	twoInverse := new(big.Int).ModInverse(big.NewInt(2), p.prime)
-	r := new(big.Int).Mul(twoInverse, cShareVal)
-	r.Mod(r, p.prime)
+	b, _ := p.field.Sqrt(aSquared)
+	cShareVal := p.field.Mul(p.field.Inverse(b), aShare) //c = b^-1 * a
+	cShareVal = p.field.Add(cShareVal, p.field.One())
+	twoInverse := p.field.Inverse(p.field.FromInt(2))
+	r := p.field.Mul(twoInverse, cShareVal)
 
 	p.setShareValue(identifier, r, true)
 }
 
-func (p *Player) RandomElement(id string) {
+func (p *Player[E]) RandomElement(id string) {
 	//todo
-	localRandomFieldElement, _ := rand.Int(rand.Reader, p.prime)
+	localRandomFieldElement := p.field.Random()
 	points := p.ss.Share(localRandomFieldElement)
 	for _, point := range points {
-		share := localRandomFieldElementShare{
+		share := localRandomFieldElementShare[E]{
 			point: point,
 			id:    id,
 			index: p.index,
 		}
 		p.Send(share, point.X)
 	}
+	p.flush()
 
 	//Add at least t+1 shares to have randomness
 	//in the passive corruption model we add all n shares
 	//to avoid having to agree on which t+1 shares
+	expired := p.deadline()
 	time.Sleep(time.Millisecond)
 	p.randomBitLock.RLock()
 	shares := p.randFieldElemShares[id]
 	p.randomBitLock.RUnlock()
 	for len(shares) < p.n {
+		if p.aborted() {
+			return
+		}
+		select {
+		case <-expired:
+			p.timedOut(RandomElementKind, id, p.missingParties(func(index int) bool {
+				for _, share := range shares {
+					if share.index == index {
+						return true
+					}
+				}
+				return false
+			}))
+			return
+		default:
+		}
 		time.Sleep(time.Millisecond)
 		p.randomBitLock.RLock()
 		shares = p.randFieldElemShares[id]
 		p.randomBitLock.RUnlock()
 	}
-	randomFieldElementShare := big.NewInt(0)
+	randomFieldElementShare := p.field.Zero()
 	for _, share := range shares {
-		randomFieldElementShare.Add(randomFieldElementShare, share.point.Y)
+		randomFieldElementShare = p.field.Add(randomFieldElementShare, share.point.Y)
 	}
-	randomFieldElementShare.Mod(randomFieldElementShare, p.prime)
 
 	p.setShareValue(id, randomFieldElementShare, true)
 }
 
-func (p *Player) mostSignificant1(bitIds []string) (resBitIds []string) {
+func (p *Player[E]) mostSignificant1(bitIds []string) (resBitIds []string) {
 	fBitIds := make([]string, p.l+1)
 	resBitIds = make([]string, p.l+1)
 	for i := range resBitIds {
@@ -753,13 +962,13 @@ func (p *Player) mostSignificant1(bitIds []string) (resBitIds []string) {
 	}
 	i := p.l
 	//f_l = 1 - c_l
-	p.SubFromConstant(big.NewInt(1), bitIds[i], fBitIds[i])
+	p.SubFromConstant(p.field.One(), bitIds[i], fBitIds[i])
 	//d_l = 1 - f_l
-	p.SubFromConstant(big.NewInt(1), fBitIds[i], resBitIds[i])
+	p.SubFromConstant(p.field.One(), fBitIds[i], resBitIds[i])
 	i--
 	for i >= 0 {
 		//f_i = f_i+1 * (1 - c_i)
-		p.SubFromConstant(big.NewInt(1), bitIds[i], fBitIds[i]+"tmp")
+		p.SubFromConstant(p.field.One(), bitIds[i], fBitIds[i]+"tmp")
 
 		p.Multiply(fBitIds[i+1], fBitIds[i]+"tmp", fBitIds[i])
 		//d_i = f_i+1 - f_i
@@ -771,74 +980,470 @@ func (p *Player) mostSignificant1(bitIds []string) (resBitIds []string) {
 
 //******************  NETWORK:  ****************
 
-//Send any type of data to party with index receiver
-func (p *Player) Send(data interface{}, receiver int) {
-	if receiver == p.index {
-		go p.Handle(data, p.index)
+//Send any type of data to party with index receiver.
+//Data for this party also goes through the network, so the network sees everything the player handles.
+//If the network fails the player is aborted, nothing is sent once it is aborted.
+func (p *Player[E]) Send(data interface{}, receiver int) {
+	if p.aborted() {
+		return
+	}
+	p.pendingSends.Add(1)
+	if err := p.network.Send(data, receiver); err != nil {
+		p.abort(err)
+	} else if receiver != p.index {
+		p.stats.RecordSent(receiver, Kind(data), p.size(data))
+		p.sentSinceWait.Store(true)
+	}
+	p.pendingSends.Done()
+}
+
+//Broadcast sends data to every party, including this one, with the broadcast of the network
+func (p *Player[E]) Broadcast(data interface{}) {
+	if p.aborted() {
+		return
+	}
+	p.pendingSends.Add(1)
+	if err := p.network.Broadcast(data); err != nil {
+		p.abort(err)
 	} else {
-		go p.network.Send(data, receiver)
+		size := p.size(data)
+		for _, receiver := range p.network.Parties() {
+			if receiver != p.index {
+				p.stats.RecordSent(receiver, Kind(data), size)
+			}
+		}
+		p.sentSinceWait.Store(true)
 	}
+	p.pendingSends.Done()
 }
 
-//Handle handles data from
-func (p *Player) Handle(data interface{}, sender int) {
-	switch t := data.(type) {
-	case identifiedShare:
-		if t.point.X == p.index {
-			//We have received aShare regular share
-			p.setShareValue(t.id, t.point.Y, true)
-		} else {
-			//We have received another party's share
-			p.reconstructionShareLock.Lock()
-			if p.reconstructionShares[t.id] == nil {
-				p.reconstructionShares[t.id] = make(map[int]*big.Int)
+//flush sends data held back by the network. It is called whenever
+//the player is about to wait for data, which ends the current step.
+func (p *Player[E]) flush() {
+	if p.sentSinceWait.Swap(false) {
+		p.stats.RecordRound()
+	}
+	if flusher, isFlusher := p.network.(network.Flusher); isFlusher {
+		//Let other routines of the step queue their data first
+		runtime.Gosched()
+		if err := flusher.Flush(); err != nil {
+			p.abort(err)
+		}
+	}
+}
+
+//Agree sends the digest of the config of p to every party and checks that all parties run with the same config.
+//It returns once every party's digest has arrived, or if p is aborted meanwhile.
+func (p *Player[E]) Agree(digest string) error {
+	p.Broadcast(configDigest{digest: digest})
+	p.flush()
+	select {
+	case <-p.digestsComplete:
+	case <-p.done:
+		return p.Err()
+	case <-p.deadline():
+		p.digestLock.Lock()
+		missing := p.missingParties(func(index int) bool { _, exists := p.digests[index]; return exists })
+		p.digestLock.Unlock()
+		p.timedOut(ConfigKind, digest, missing)
+		return p.Err()
+	}
+
+	p.digestLock.Lock()
+	defer p.digestLock.Unlock()
+	for i := 1; i <= p.n; i++ {
+		if p.digests[i] != digest {
+			return fmt.Errorf("player: party %d runs with config %s, party %d with %s", i, p.digests[i], p.index, digest)
+		}
+	}
+	return nil
+}
+
+//WaitForSends blocks until all data sent so far has been handed to the network
+func (p *Player[E]) WaitForSends() {
+	p.pendingSends.Wait()
+	p.flush()
+}
+
+//abort stops the player. Routines waiting for data return and Run returns err.
+//Only the first error is kept.
+func (p *Player[E]) abort(err error) {
+	p.abortOnce.Do(func() {
+		p.err = err
+		close(p.done)
+	})
+}
+
+func (p *Player[E]) aborted() bool {
+	select {
+	case <-p.done:
+		return true
+	default:
+		return false
+	}
+}
+
+//Err is the error the player was aborted with, or nil if it was not aborted
+func (p *Player[E]) Err() error {
+	if !p.aborted() {
+		return nil
+	}
+	return p.err
+}
+
+//SetRobust makes p open values from the shares of all parties, correcting up to (n-t-1)/2 wrong shares
+//and recording whose they were in InconsistentShares. It must be set before the player runs.
+func (p *Player[E]) SetRobust(robust bool) {
+	p.robust = robust
+}
+
+//SetTimeout bounds how long p waits for shares from other parties before it is aborted with a *TimeoutError.
+//Zero, the default, waits forever. It must be set before the player runs.
+func (p *Player[E]) SetTimeout(timeout time.Duration) {
+	p.timeout = timeout
+}
+
+//TimeoutError is the error a player is aborted with when shares it waits for do not arrive in time
+type TimeoutError struct {
+	//Kind of the shares waited for, as returned by Kind
+	Kind       string
+	Identifier string
+	//Missing are the parties that did not deliver their share, if they are known
+	Missing []int
+	Timeout time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	if len(e.Missing) == 0 {
+		return fmt.Sprintf("player: no value of %s after %v", e.Identifier, e.Timeout)
+	}
+	return fmt.Sprintf("player: %s shares of %s missing from parties %v after %v", e.Kind, e.Identifier, e.Missing, e.Timeout)
+}
+
+//deadline returns a channel that delivers once a wait started now has exceeded the timeout of p.
+//Without a timeout the channel never delivers.
+func (p *Player[E]) deadline() <-chan time.Time {
+	if p.timeout <= 0 {
+		return nil
+	}
+	return time.After(p.timeout)
+}
+
+func (p *Player[E]) timedOut(kind, identifier string, missing []int) {
+	p.abort(&TimeoutError{Kind: kind, Identifier: identifier, Missing: missing, Timeout: p.timeout})
+}
+
+//missingParties lists the parties that have not delivered
+func (p *Player[E]) missingParties(delivered func(index int) bool) []int {
+	var missing []int
+	for i := 1; i <= p.n; i++ {
+		if !delivered(i) {
+			missing = append(missing, i)
+		}
+	}
+	return missing
+}
+
+//pendingValue tells where the value of identifier comes from: the product shares of a multiplication, the shares of zero of a refresh,
+//or the share of an input from the party providing it. Other values are computed locally.
+func (p *Player[E]) pendingValue(identifier string) (kind string, missing []int) {
+	p.multShareLock.RLock()
+	shares, multiplying := p.multShares[identifier]
+	packedShares := p.packedMultShares[identifier]
+	k, multiplyingPacked := p.packedMultiplications[identifier]
+	p.multShareLock.RUnlock()
+	if multiplyingPacked {
+		return PackedMultiplicationKind, p.missingParties(func(index int) bool {
+			if index > p.packed(k).Multipliers() {
+				return true
 			}
-			p.reconstructionShares[t.id][t.point.X] = t.point.Y
-			if len(p.reconstructionShares[t.id]) == p.threshold+1 {
-				//Notify waiting routines
-				for _, channel := range p.reconstructionShareBlockingChannels[t.id] {
-					channel <- p.reconstructionShares[t.id]
+			for _, share := range packedShares {
+				if share.Index == index {
+					return true
 				}
-				delete(p.reconstructionShareBlockingChannels, t.id)
 			}
+			return false
+		})
+	}
+	if multiplying {
+		return MultiplicationKind, p.missingParties(func(index int) bool {
+			if !p.reshares(index) {
+				return true
+			}
+			for _, share := range shares {
+				if share.recombinationShare.Index == index {
+					return true
+				}
+			}
+			return false
+		})
+	}
+	if missing, refreshing := p.missingRefreshShares(identifier); refreshing {
+		return RefreshKind, missing
+	}
+	if dealer, isInput := p.inputDealer(identifier); isInput {
+		return InputKind, []int{dealer}
+	}
+	return "", nil
+}
 
-			p.reconstructionShareLock.Unlock()
+//inputDealer is the party providing the input identifier, or the vector input identifier, in the program of p.
+//The dealers are worked out from the program once, as every share and every message of verifiable input looks them up.
+func (p *Player[E]) inputDealer(identifier string) (int, bool) {
+	p.inputDealersOnce.Do(func() {
+		p.inputDealers = make(map[string]int)
+		for _, insn := range p.instructions {
+			isInput := len(insn) == 3 && insn[0] == "INPUT" || len(insn) >= 4 && insn[0] == "VINPUT"
+			if !isInput {
+				continue
+			}
+			if _, exists := p.inputDealers[insn[2]]; exists {
+				continue
+			}
+			if index, err := strconv.Atoi(insn[1]); err == nil {
+				p.inputDealers[insn[2]] = index
+			}
 		}
-	case multiplicationShare:
-		p.multShareLock.Lock()
-		shares := append(p.multShares[t.id], t)
-		if len(shares) < p.threshold*2+1 {
-			p.multShares[t.id] = shares
-			p.multShareLock.Unlock()
+	})
+	dealer, isInput := p.inputDealers[identifier]
+	return dealer, isInput
+}
+
+//Close aborts the player and closes its network. A closed player cannot run again.
+func (p *Player[E]) Close() error {
+	p.abort(network.ErrClosed)
+	if p.network == nil {
+		return nil
+	}
+	return p.network.Close()
+}
+
+//HandleError aborts the player, as a failed peer means data it is waiting for may never arrive
+func (p *Player[E]) HandleError(err error) {
+	p.abort(err)
+}
+
+//Handle handles data from
+func (p *Player[E]) Handle(data interface{}, sender int) {
+	if sender != p.index {
+		p.stats.RecordReceived(sender, Kind(data), p.size(data))
+	}
+	//Shares of other parties are kept under the party that sent them,
+	//and dropped if they claim to be of another party, so no party can pass off its shares as another's
+	switch t := data.(type) {
+	case identifiedShare[E]:
+		//We have received a regular share. Shares of an input are only taken from its dealer,
+		//and not at all if inputs are shared verifiably. The first share counts.
+		if dealer, isInput := p.inputDealer(t.id); isInput && (dealer != sender || p.verifiable) {
+			return
+		}
+		p.setNewShareValue(t.id, t.point.Y, true)
+	case reconstructionShare[E]:
+		//We have received another party's share
+		if t.point.X != sender {
+			return
+		}
+		p.reconstructionShareLock.Lock()
+		if p.reconstructionShares[t.id] == nil {
+			p.reconstructionShares[t.id] = make(map[int]E)
+		}
+		p.reconstructionShares[t.id][sender] = t.point.Y
+		//Notify routines waiting for as many shares as there are now
+		var waiting []openWaiter[E]
+		for _, waiter := range p.reconstructionShareBlockingChannels[t.id] {
+			if len(p.reconstructionShares[t.id]) >= waiter.count {
+				waiter.channel <- copyPoints(p.reconstructionShares[t.id])
+			} else {
+				waiting = append(waiting, waiter)
+			}
+		}
+		if len(waiting) == 0 {
+			delete(p.reconstructionShareBlockingChannels, t.id)
 		} else {
-			p.multShareLock.Unlock()
+			p.reconstructionShareBlockingChannels[t.id] = waiting
+		}
+
+		p.reconstructionShareLock.Unlock()
+	case multiplicationShare[E]:
+		if t.recombinationShare.Index != sender || !p.reshares(sender) {
+			return
+		}
+		p.multShareLock.Lock()
+		shares := p.multShares[t.id]
+		for _, share := range shares {
+			if share.recombinationShare.Index == sender {
+				//Duplicate
+				p.multShareLock.Unlock()
+				return
+			}
+		}
+		shares = append(shares, t)
+		p.multShares[t.id] = shares
+		p.multShareLock.Unlock()
+		if len(shares) == p.threshold*2+1 {
 			p.recombineMultiplicationShares(t.id, shares)
 		}
-	case localRandomFieldElementShare:
+	case localRandomFieldElementShare[E]:
+		if t.index != sender {
+			return
+		}
 		p.randomBitLock.Lock()
-		p.randFieldElemShares[t.id] = append(p.randFieldElemShares[t.id], t)
+		shares := p.randFieldElemShares[t.id]
+		for _, share := range shares {
+			if share.index == sender {
+				//Duplicate
+				p.randomBitLock.Unlock()
+				return
+			}
+		}
+		p.randFieldElemShares[t.id] = append(shares, t)
 		p.randomBitLock.Unlock()
-	case aSquaredShare:
+	case aSquaredShare[E]:
+		if t.point.X != sender {
+			return
+		}
 		p.randomBitLock.Lock()
-		p.randomBitASquaredShares[t.id] = append(p.randomBitASquaredShares[t.id], t.point)
+		shares := p.randomBitASquaredShares[t.id]
+		for _, share := range shares {
+			if share.X == sender {
+				//Duplicate
+				p.randomBitLock.Unlock()
+				return
+			}
+		}
+		p.randomBitASquaredShares[t.id] = append(shares, t.point)
 		p.randomBitLock.Unlock()
+	case configDigest:
+		p.digestLock.Lock()
+		if _, exists := p.digests[sender]; !exists {
+			p.digests[sender] = t.digest
+			if len(p.digests) == p.n {
+				close(p.digestsComplete)
+			}
+		}
+		p.digestLock.Unlock()
+	case packedMultiplicationShare[E]:
+		p.handlePackedMultiplicationShare(t, sender)
+	case refreshShare[E]:
+		p.handleRefreshShare(t, sender)
+	case vssRow[E], vssCheck[E], vssComplaint[E], vssComplaints, vssReveal[E], vssAnswer, vssReveals, vssVote:
+		p.handleVSS(t, sender)
+	}
+}
+
+//Kinds of messages players send each other
+const (
+	InputKind          = "input"
+	OpenKind           = "open"
+	MultiplicationKind = "multiplication"
+	RandomElementKind  = "random element"
+	ASquaredKind       = "a squared"
+	ConfigKind         = "config"
+)
+
+//message is implemented by every message players send each other, whatever field they compute in
+type message interface {
+	kind() string
+	identifier() string
+}
+
+func (m identifiedShare[E]) kind() string              { return InputKind }
+func (m reconstructionShare[E]) kind() string          { return OpenKind }
+func (m multiplicationShare[E]) kind() string          { return MultiplicationKind }
+func (m localRandomFieldElementShare[E]) kind() string { return RandomElementKind }
+func (m aSquaredShare[E]) kind() string                { return ASquaredKind }
+func (m configDigest) kind() string                    { return ConfigKind }
+
+func (m identifiedShare[E]) identifier() string              { return m.id }
+func (m reconstructionShare[E]) identifier() string          { return m.id }
+func (m multiplicationShare[E]) identifier() string          { return m.id }
+func (m localRandomFieldElementShare[E]) identifier() string { return m.id }
+func (m aSquaredShare[E]) identifier() string                { return m.id }
+func (m configDigest) identifier() string                    { return m.digest }
+
+//Kind names the kind of a message sent by a player. It can be used as the Kind of a statsnetwork.
+func Kind(data interface{}) string {
+	if m, isMessage := data.(message); isMessage {
+		return m.kind()
+	}
+	return fmt.Sprintf("%T", data)
+}
+
+//Describe writes a message sent by p in a readable form, e.g. for a transcript of a recordnetwork
+func (p *Player[E]) Describe(data interface{}) string {
+	share := func(point bigshamir.SecretShare[E]) string {
+		return fmt.Sprintf("share (%d, %s)", point.X, p.field.String(point.Y))
 	}
+	switch t := data.(type) {
+	case identifiedShare[E]:
+		return fmt.Sprintf("%s %s: %s", InputKind, t.id, share(t.point))
+	case reconstructionShare[E]:
+		return fmt.Sprintf("%s %s: %s", OpenKind, t.id, share(t.point))
+	case multiplicationShare[E]:
+		return fmt.Sprintf("%s %s: %s of the product of party %d",
+			MultiplicationKind, t.id, share(t.recombinationShare.SecretShare), t.recombinationShare.Index)
+	case localRandomFieldElementShare[E]:
+		return fmt.Sprintf("%s %s: %s of the element of party %d in iteration %d",
+			RandomElementKind, t.id, share(t.point), t.index, t.iteration)
+	case aSquaredShare[E]:
+		return fmt.Sprintf("%s %s: %s in iteration %d", ASquaredKind, t.id, share(t.point), t.iteration)
+	case configDigest:
+		return fmt.Sprintf("%s %s", ConfigKind, t.digest)
+	case packedMultiplicationShare[E]:
+		return fmt.Sprintf("%s %s: %s of the %d products of party %d",
+			PackedMultiplicationKind, t.id, share(t.recombinationShare.SecretShare), t.k, t.recombinationShare.Index)
+	case refreshShare[E]:
+		return fmt.Sprintf("%s %s: %s of zero of party %d", RefreshKind, t.id, share(t.point), t.dealer)
+	case vssRow[E]:
+		return fmt.Sprintf("%s %s: coefficient %d is %s", VSSRowKind, t.id, t.coefficient, p.field.String(t.value))
+	case vssCheck[E]:
+		return fmt.Sprintf("%s %s: %s", VSSCheckKind, t.id, p.field.String(t.value))
+	case vssComplaint[E]:
+		return fmt.Sprintf("%s %s: party %d disagrees with %s", VSSComplaintKind, t.id, t.against, p.field.String(t.value))
+	case vssComplaints:
+		return fmt.Sprintf("%s %s: %d complaints", VSSComplaintsKind, t.id, t.count)
+	case vssReveal[E]:
+		return fmt.Sprintf("%s %s: coefficient %d of the row of party %d is %s",
+			VSSRevealKind, t.id, t.coefficient, t.party, p.field.String(t.value))
+	case vssAnswer:
+		return fmt.Sprintf("%s %s: complaints of party %d", VSSAnswerKind, t.id, t.complainant)
+	case vssReveals:
+		return fmt.Sprintf("%s %s: %d rows, %d complainants", VSSRevealsKind, t.id, t.count, t.answers)
+	case vssVote:
+		return fmt.Sprintf("%s %s: accept %v", VSSVoteKind, t.id, t.accept)
+	}
+	return fmt.Sprintf("%v", data)
+}
+
+//size of data as encoded by the codec of the player
+func (p *Player[E]) size(data interface{}) int {
+	m, isMessage := data.(message)
+	if !isMessage {
+		return 0
+	}
+	return p.codec.EncodedSize(len(m.identifier()))
+}
+
+//Stats is the traffic of the player so far, excluding data it sends to itself
+func (p *Player[E]) Stats() statsnetwork.Stats {
+	return p.stats.Stats()
 }
 
 //Index of player
-func (p *Player) Index() int {
+func (p *Player[E]) Index() int {
 	return p.index
 }
 
 //RegisterNetwork ...
-func (p *Player) RegisterNetwork(network network.Network) {
+func (p *Player[E]) RegisterNetwork(network network.Network) {
 	p.network = network
 }
 
 //********** INTERPRETER **************
 type instruction = []string
 
-//Run executes the computations specified by instructions
+//Run executes the computations specified by instructions.
+//Outputs are integers in [0, p), whatever the type of the elements of the field.
 /*
 INPUT [party_index(number)] [id]
 OUTPUT [value] [output_name]
@@ -869,14 +1474,56 @@ JZ [value] [number]
 
 RANDOM_BIT [id]
 RANDOM [id]
+
+REFRESH [id] ... [id]
+
+Vectors of k values are kept in one packed sharing:
+VINPUT [party_index(number)] [id] [input_1] ... [input_k]
+VOUTPUT [id] [output_name_1] ... [output_name_k]
+VPLUS [id] [id] [id]
+VMINUS [id] [id] [id]
+VMULTIPLY [id] [id] [id]
 */
-func (p *Player) Run() map[string]*big.Int {
+func (p *Player[E]) Run() (map[string]*big.Int, error) {
+	return p.RunContext(context.Background())
+}
+
+//RunContext is Run, but aborts the player when ctx is done.
+//If the player is aborted, the error is returned and the output is nil.
+func (p *Player[E]) RunContext(ctx context.Context) (map[string]*big.Int, error) {
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		select {
+		case <-ctx.Done():
+			p.abort(ctx.Err())
+		case <-stop:
+		}
+	}()
+
+	if err := p.checkVerifiable(); err != nil {
+		return nil, err
+	}
 	output := make(map[string]*big.Int)
+	//Number of values of the vectors computed so far
+	packing := make(map[string]int)
+	vectorOperands := func(insn instruction) (int, error) {
+		k := packing[insn[1]]
+		if k == 0 || packing[insn[2]] != k {
+			return 0, fmt.Errorf("player: %s needs vectors of the same length, %s has %d values and %s %d",
+				insn[0], insn[1], packing[insn[1]], insn[2], packing[insn[2]])
+		}
+		packing[insn[3]] = k
+		return k, nil
+	}
 
 	labels := labelIndexes(p.instructions)
 
 	instructionIndex := -1
 	for instructionIndex+1 < len(p.instructions) {
+		if err := p.Err(); err != nil {
+			return nil, err
+		}
 		instructionIndex++
 		insn := p.instructions[instructionIndex]
 		if len(insn) == 0 {
@@ -889,24 +1536,28 @@ func (p *Player) Run() map[string]*big.Int {
 			if err != nil {
 				fmt.Println()
 			}
+			if err == nil && p.verifiable {
+				p.verifiableInput(index, insn[2])
+				continue
+			}
 			if err != nil || index != p.index {
 				continue
 			}
 			value := p.readInput(insn[2])
-			p.Share(value, insn[2])
+			p.Share(p.field.FromBig(value), insn[2])
 		case "OUTPUT":
 			// OUTPUT [value] [output_name]
-			constant, isNumber := readInt(insn[1])
+			constant, isNumber := p.readConstant(insn[1])
 			if isNumber {
-				output[insn[2]] = constant
+				output[insn[2]] = p.field.Big(constant)
 				continue
 			}
 			p.Open(insn[1])
-			output[insn[2]] = p.Reconstruct(insn[1])
+			output[insn[2]] = p.field.Big(p.Reconstruct(insn[1]))
 
 		case "MOVE":
 			// MOVE [value] [id]
-			val, isNumber := readInt(insn[1])
+			val, isNumber := p.readConstant(insn[1])
 			isSecret := false
 			if !isNumber {
 				val, isSecret = p.getShareValue(insn[1])
@@ -915,12 +1566,12 @@ func (p *Player) Run() map[string]*big.Int {
 
 		case "PLUS":
 			// PLUS [value] [value] [id]
-			constant, isNumber := readInt(insn[1])
+			constant, isNumber := p.readConstant(insn[1])
 			if isNumber {
 				p.AddConstant(constant, insn[2], insn[3])
 				continue
 			}
-			constant, isNumber = readInt(insn[2])
+			constant, isNumber = p.readConstant(insn[2])
 			if isNumber {
 				p.AddConstant(constant, insn[1], insn[3])
 				continue
@@ -928,12 +1579,12 @@ func (p *Player) Run() map[string]*big.Int {
 			p.Add(insn[1], insn[2], insn[3])
 		case "MINUS":
 			// MINUS [value] [value] [id]
-			constant, isNumber := readInt(insn[1])
+			constant, isNumber := p.readConstant(insn[1])
 			if isNumber {
 				p.SubFromConstant(constant, insn[2], insn[3])
 				continue
 			}
-			constant, isNumber = readInt(insn[2])
+			constant, isNumber = p.readConstant(insn[2])
 			if isNumber {
 				p.SubConstant(insn[1], constant, insn[3])
 				continue
@@ -941,12 +1592,12 @@ func (p *Player) Run() map[string]*big.Int {
 			p.Sub(insn[1], insn[2], insn[3])
 		case "MULTIPLY":
 			// MULTIPLY [value] [value] [id]
-			constant, isNumber := readInt(insn[1])
+			constant, isNumber := p.readConstant(insn[1])
 			if isNumber {
 				p.Scale(constant, insn[2], insn[3])
 				continue
 			}
-			constant, isNumber = readInt(insn[2])
+			constant, isNumber = p.readConstant(insn[2])
 			if isNumber {
 				p.Scale(constant, insn[1], insn[3])
 				continue
@@ -954,15 +1605,15 @@ func (p *Player) Run() map[string]*big.Int {
 			p.Multiply(insn[1], insn[2], insn[3])
 		case "AND":
 			// AND [value] [value] [id]
-			constant, isNumber := readInt(insn[1])
+			constant, isNumber := p.readConstant(insn[1])
 			nonConstArg := 2
 			if !isNumber {
-				constant, isNumber = readInt(insn[2])
+				constant, isNumber = p.readConstant(insn[2])
 				nonConstArg = 1
 			}
 			if isNumber {
-				if constant.Sign() == 0 {
-					p.setShareValue(insn[3], big.NewInt(0), false)
+				if p.field.IsZero(constant) {
+					p.setShareValue(insn[3], p.field.Zero(), false)
 				} else {
 					val, isSecret := p.getShareValue(insn[nonConstArg])
 					p.setShareValue(insn[3], val, isSecret)
@@ -972,15 +1623,15 @@ func (p *Player) Run() map[string]*big.Int {
 			p.Multiply(insn[1], insn[2], insn[3])
 		case "OR":
 			// OR [value] [value] [id]
-			constant, isNumber := readInt(insn[1])
+			constant, isNumber := p.readConstant(insn[1])
 			nonConstArg := 2
 			if !isNumber {
-				constant, isNumber = readInt(insn[2])
+				constant, isNumber = p.readConstant(insn[2])
 				nonConstArg = 1
 			}
 			if isNumber {
-				if constant.Sign() == 1 {
-					p.setShareValue(insn[3], big.NewInt(1), false)
+				if !p.field.IsZero(constant) {
+					p.setShareValue(insn[3], p.field.One(), false)
 				} else {
 					val, isSecret := p.getShareValue(insn[nonConstArg])
 					p.setShareValue(insn[3], val, isSecret)
@@ -990,41 +1641,41 @@ func (p *Player) Run() map[string]*big.Int {
 			p.bitOr(insn[1], insn[2], insn[3])
 		case "XOR":
 			// XOR [value] [value] [id]
-			constant, isNumber := readInt(insn[1])
+			constant, isNumber := p.readConstant(insn[1])
 			nonConstArg := 2
 			if !isNumber {
-				constant, isNumber = readInt(insn[2])
+				constant, isNumber = p.readConstant(insn[2])
 				nonConstArg = 1
 			}
 			if isNumber {
 				val, isSecret := p.getShareValue(insn[nonConstArg])
-				if constant.Sign() == 0 {
+				if p.field.IsZero(constant) {
 					p.setShareValue(insn[3], val, isSecret)
 				} else {
-					p.setShareValue(insn[3], bitNot(val), isSecret)
+					p.setShareValue(insn[3], p.bitNot(val), isSecret)
 				}
 				continue
 			}
 			p.bitXor(insn[1], insn[2], insn[3])
 		case "NOT":
 			// NOT [value] [id]
-			constant, isNumber := readInt(insn[1])
+			constant, isNumber := p.readConstant(insn[1])
 			if isNumber {
-				p.setShareValue(insn[2], bitNot(constant), false)
+				p.setShareValue(insn[2], p.bitNot(constant), false)
 				continue
 			}
 			val, isSecret := p.getShareValue(insn[1])
-			p.setShareValue(insn[2], bitNot(val), isSecret)
+			p.setShareValue(insn[2], p.bitNot(val), isSecret)
 		case "GT":
 			// GT [value] [value] [id]
-			constant, isNumber := readInt(insn[1])
+			constant, isNumber := p.readConstant(insn[1])
 			constantID := insn[3] + "_run_tmp"
 			if isNumber {
 				p.setShareValue(constantID, constant, false)
 				p.GreaterThan(constantID, insn[2], insn[3])
 				continue
 			}
-			constant, isNumber = readInt(insn[2])
+			constant, isNumber = p.readConstant(insn[2])
 			if isNumber {
 				p.setShareValue(constantID, constant, false)
 				p.GreaterThan(insn[1], constantID, insn[3])
@@ -1033,14 +1684,14 @@ func (p *Player) Run() map[string]*big.Int {
 			p.GreaterThan(insn[1], insn[2], insn[3])
 		case "LT":
 			// LT [value] [value] [id]
-			constant, isNumber := readInt(insn[1])
+			constant, isNumber := p.readConstant(insn[1])
 			constantID := insn[3] + "_run_tmp"
 			if isNumber {
 				p.setShareValue(constantID, constant, false)
 				p.GreaterThan(insn[2], constantID, insn[3])
 				continue
 			}
-			constant, isNumber = readInt(insn[2])
+			constant, isNumber = p.readConstant(insn[2])
 			if isNumber {
 				p.setShareValue(constantID, constant, false)
 				p.GreaterThan(constantID, insn[1], insn[3])
@@ -1049,14 +1700,14 @@ func (p *Player) Run() map[string]*big.Int {
 			p.GreaterThan(insn[2], insn[1], insn[3])
 		case "GTE":
 			// GTE [value] [value] [id]
-			constant, isNumber := readInt(insn[1])
+			constant, isNumber := p.readConstant(insn[1])
 			constantID := insn[3] + "_run_tmp"
 			if isNumber {
 				p.setShareValue(constantID, constant, false)
 				p.GreaterThanOrEqual(constantID, insn[2], insn[3])
 				continue
 			}
-			constant, isNumber = readInt(insn[2])
+			constant, isNumber = p.readConstant(insn[2])
 			if isNumber {
 				p.setShareValue(constantID, constant, false)
 				p.GreaterThanOrEqual(insn[1], constantID, insn[3])
@@ -1065,14 +1716,14 @@ func (p *Player) Run() map[string]*big.Int {
 			p.GreaterThanOrEqual(insn[1], insn[2], insn[3])
 		case "LTE":
 			// LTE [value] [value] [id]
-			constant, isNumber := readInt(insn[1])
+			constant, isNumber := p.readConstant(insn[1])
 			constantID := insn[3] + "_run_tmp"
 			if isNumber {
 				p.setShareValue(constantID, constant, false)
 				p.GreaterThanOrEqual(insn[2], constantID, insn[3])
 				continue
 			}
-			constant, isNumber = readInt(insn[2])
+			constant, isNumber = p.readConstant(insn[2])
 			if isNumber {
 				p.setShareValue(constantID, constant, false)
 				p.GreaterThanOrEqual(constantID, insn[1], insn[3])
@@ -1081,14 +1732,14 @@ func (p *Player) Run() map[string]*big.Int {
 			p.GreaterThanOrEqual(insn[2], insn[1], insn[3])
 		case "EQUALS":
 			// EQUALS [value] [value] [id]
-			constant, isNumber := readInt(insn[1])
+			constant, isNumber := p.readConstant(insn[1])
 			constantID := insn[3] + "_run_tmp"
 			if isNumber {
 				p.setShareValue(constantID, constant, false)
 				p.Equal(constantID, insn[2], insn[3])
 				continue
 			}
-			constant, isNumber = readInt(insn[2])
+			constant, isNumber = p.readConstant(insn[2])
 			if isNumber {
 				p.setShareValue(constantID, constant, false)
 				p.Equal(constantID, insn[1], insn[3])
@@ -1097,14 +1748,14 @@ func (p *Player) Run() map[string]*big.Int {
 			p.Equal(insn[1], insn[2], insn[3])
 		case "NOT_EQUALS":
 			// NOT_EQUALS [value] [value] [id]
-			constant, isNumber := readInt(insn[1])
+			constant, isNumber := p.readConstant(insn[1])
 			constantID := insn[3] + "_run_tmp"
 			if isNumber {
 				p.setShareValue(constantID, constant, false)
 				p.NotEqual(constantID, insn[2], insn[3])
 				continue
 			}
-			constant, isNumber = readInt(insn[2])
+			constant, isNumber = p.readConstant(insn[2])
 			if isNumber {
 				p.setShareValue(constantID, constant, false)
 				p.NotEqual(constantID, insn[1], insn[3])
@@ -1116,7 +1767,7 @@ func (p *Player) Run() map[string]*big.Int {
 			continue
 		case "LEAK":
 			// LEAK [id] [id]
-			constant, isNumber := readInt(insn[1])
+			constant, isNumber := p.readConstant(insn[1])
 			if isNumber {
 				p.setShareValue(insn[2], constant, false)
 				continue
@@ -1128,9 +1779,9 @@ func (p *Player) Run() map[string]*big.Int {
 			instructionIndex = labels[insn[1]]
 		case "JZ":
 			// JZ [value] [label]
-			constant, isNumber := readInt(insn[1])
+			constant, isNumber := p.readConstant(insn[1])
 			if isNumber {
-				if constant.Sign() == 0 {
+				if p.field.IsZero(constant) {
 					instructionIndex = labels[insn[2]]
 				} else {
 					continue
@@ -1140,7 +1791,7 @@ func (p *Player) Run() map[string]*big.Int {
 			if isSecret {
 				panic("branching on secret condition")
 			}
-			if constant.Sign() == 0 {
+			if p.field.IsZero(constant) {
 				instructionIndex = labels[insn[2]]
 			}
 		case "RANDOM_BIT":
@@ -1149,12 +1800,70 @@ func (p *Player) Run() map[string]*big.Int {
 		case "RANDOM":
 			// RANDOM [id]
 			p.RandomElement(insn[1])
+		case "REFRESH":
+			// REFRESH [id] ... [id]
+			if p.verifiable {
+				return nil, fmt.Errorf("player: refreshing %v needs sharings of zero that are not verified", insn[1:])
+			}
+			p.Refresh(insn[1:]...)
+		case "VINPUT":
+			// VINPUT [party_index(number)] [id] [input_1] ... [input_k]
+			if len(insn) < 4 {
+				return nil, fmt.Errorf("player: vector input needs at least one value: %v", insn)
+			}
+			if p.verifiable {
+				return nil, fmt.Errorf("player: vector input %s cannot be shared verifiably", insn[2])
+			}
+			packing[insn[2]] = len(insn) - 3
+			if index, err := strconv.Atoi(insn[1]); err != nil || index != p.index {
+				continue
+			}
+			values := make([]E, len(insn)-3)
+			for i, name := range insn[3:] {
+				values[i] = p.field.FromBig(p.readInput(name))
+			}
+			p.SharePacked(values, insn[2])
+		case "VOUTPUT":
+			// VOUTPUT [id] [output_name_1] ... [output_name_k]
+			if len(insn) < 3 {
+				return nil, fmt.Errorf("player: vector output needs at least one name: %v", insn)
+			}
+			if packing[insn[1]] != len(insn)-2 {
+				return nil, fmt.Errorf("player: %s has %d values, not %d", insn[1], packing[insn[1]], len(insn)-2)
+			}
+			p.Open(insn[1])
+			for i, value := range p.ReconstructPacked(insn[1], len(insn)-2) {
+				output[insn[2+i]] = p.field.Big(value)
+			}
+		case "VPLUS":
+			// VPLUS [id] [id] [id]
+			if _, err := vectorOperands(insn); err != nil {
+				return nil, err
+			}
+			p.Add(insn[1], insn[2], insn[3])
+		case "VMINUS":
+			// VMINUS [id] [id] [id]
+			if _, err := vectorOperands(insn); err != nil {
+				return nil, err
+			}
+			p.Sub(insn[1], insn[2], insn[3])
+		case "VMULTIPLY":
+			// VMULTIPLY [id] [id] [id]
+			k, err := vectorOperands(insn)
+			if err != nil {
+				return nil, err
+			}
+			p.MultiplyPacked(insn[1], insn[2], insn[3], k)
 		default:
 			fmt.Println("Unsupported instruction:", insn)
 		}
 	}
+	p.flush()
+	if err := p.Err(); err != nil {
+		return nil, err
+	}
 
-	return output
+	return output, nil
 }
 
 func labelIndexes(insns []instruction) map[string]int {
@@ -1171,7 +1880,16 @@ func readInt(s string) (*big.Int, bool) {
 	return new(big.Int).SetString(s, 10)
 }
 
-func (p *Player) readInput(identifier string) *big.Int {
+//readConstant reads a constant of a program as an element of the field
+func (p *Player[E]) readConstant(s string) (E, bool) {
+	constant, isNumber := readInt(s)
+	if !isNumber {
+		return p.field.Zero(), false
+	}
+	return p.field.FromBig(constant), true
+}
+
+func (p *Player[E]) readInput(identifier string) *big.Int {
 	value, exist := p.inputValues[identifier] //todo concurrency?
 	if !exist {
 		fmt.Println("Party", p.index, "has no input value named", identifier)
@@ -1182,11 +1900,11 @@ func (p *Player) readInput(identifier string) *big.Int {
 	return value
 }
 
-func (p *Player) setInput(inputValues map[string]*big.Int) {
+func (p *Player[E]) setInput(inputValues map[string]*big.Int) {
 	p.inputValues = inputValues
 }
 
-func (p *Player) scanInput(path string) {
+func (p *Player[E]) scanInput(path string) {
 	file, err := os.Open(path)
 	if err != nil {
 		if os.IsExist(err) {
@@ -1221,7 +1939,7 @@ func (p *Player) scanInput(path string) {
 	}
 }
 
-func (p *Player) scanInstructions(path string) {
+func (p *Player[E]) scanInstructions(path string) {
 	file, err := os.Open(path)
 	if err != nil {
 		log.Fatal(err)
@@ -1243,12 +1961,44 @@ func (p *Player) scanInstructions(path string) {
 	}
 }
 
+//Options choose the layers the setups put between a player and the network connecting it to its peers.
+//All parties must use the same options.
+type Options struct {
+	//Batch queues the messages to each party and sends each queue as one frame,
+	//when the player is about to wait, when a queue is full or at most a millisecond later
+	Batch bool
+	//ReliableBroadcast runs Bracha's broadcast for the data the player broadcasts,
+	//so all honest parties receive the same data. It needs more than 3t parties.
+	ReliableBroadcast bool
+}
+
+//batchPolicy sends queues at most a millisecond after a message was queued,
+//as messages sent while handling data are not followed by a wait of the player
+var batchPolicy = batchnetwork.Policy{MaxMessages: 1024, MaxDelay: time.Millisecond}
+
+//layers puts the layers chosen by options on top of p. It returns the handler to register with the network
+//connecting p to its peers, and the codec that network must use if it leaves the process.
+func (p *Player[E]) layers(options Options) (network.Handler, network.Codec) {
+	var handler network.Handler = p
+	var codec network.Codec = p.codec
+	if options.ReliableBroadcast {
+		bn := broadcastnetwork.New(broadcastnetwork.Reliable, p.threshold, codec)
+		bn.RegisterHandler(handler)
+		handler, codec = bn, broadcastnetwork.NewCodec(codec)
+	}
+	if options.Batch {
+		bn := batchnetwork.New(batchPolicy)
+		bn.RegisterHandler(handler)
+		handler, codec = bn, batchnetwork.NewCodec(codec)
+	}
+	return handler, codec
+}
+
 //LocalSetup assumes input path is followed by each party's index
-func LocalSetup(prime int64, threshold, n int, programPath, inputPath string) map[int]*Player {
-	parties := make(map[int]*Player, n)
-	handlers := make([]network.Handler, n)
-	for i := range handlers {
-		party := NewPlayer(prime, threshold, n, i+1)
+func LocalSetup[E any](f field.Field[E], threshold, n int, options Options, programPath, inputPath string) map[int]*Player[E] {
+	parties := make(map[int]*Player[E], n)
+	for i := 0; i < n; i++ {
+		party := NewPlayer(f, threshold, n, i+1)
 		if programPath != "" {
 			party.scanInstructions(programPath)
 		}
@@ -1256,14 +2006,103 @@ func LocalSetup(prime int64, threshold, n int, programPath, inputPath string) ma
 			party.scanInput(inputPath + strconv.Itoa(party.index))
 		}
 		parties[i+1] = party
-		handlers[i] = party
 	}
 
-	for _, party := range parties {
-		ln := new(localnetwork.Localnetwork)
-		ln.RegisterHandler(party)
-		ln.SetConnections(handlers...)
+	networks := channetwork.Networks(n)
+	for i, cn := range networks {
+		handler, _ := parties[i+1].layers(options)
+		cn.RegisterHandler(handler)
+	}
+	for _, cn := range networks {
+		cn.SetConnections(networks...)
 	}
 
 	return parties
 }
+
+//TCPSetup creates the party with the given index and connects it to its peers over TCP.
+//addresses holds the listening address of every party.
+//If credentials are given all connections are mutually authenticated TLS channels.
+func TCPSetup[E any](f field.Field[E], threshold, n, index int, options Options, addresses map[int]string, credentials *tcpnetwork.Credentials, programPath, inputPath string) (*Player[E], error) {
+	party := NewPlayer(f, threshold, n, index)
+	if programPath != "" {
+		party.scanInstructions(programPath)
+	}
+	if inputPath != "" {
+		party.scanInput(inputPath + strconv.Itoa(party.index))
+	}
+
+	handler, codec := party.layers(options)
+	var tn *tcpnetwork.Tcpnetwork
+	if credentials != nil {
+		tn = tcpnetwork.NewTLS(codec, *credentials)
+	} else {
+		tn = tcpnetwork.New(codec)
+	}
+	tn.SetConnections(addresses)
+	tn.RegisterHandler(handler)
+	if err := tn.Listen(); err != nil {
+		return nil, err
+	}
+
+	return party, nil
+}
+
+//RelaySetup creates the party with the given index, connected to its peers through the relay at address
+func RelaySetup[E any](f field.Field[E], threshold, n, index int, options Options, address string, keys relaynetwork.Keys, programPath, inputPath string) (*Player[E], error) {
+	party := NewPlayer(f, threshold, n, index)
+	if programPath != "" {
+		party.scanInstructions(programPath)
+	}
+	if inputPath != "" {
+		party.scanInput(inputPath + strconv.Itoa(party.index))
+	}
+
+	handler, codec := party.layers(options)
+	rn := relaynetwork.New(codec, keys)
+	rn.RegisterHandler(handler)
+	if err := rn.Connect(address); err != nil {
+		return nil, err
+	}
+
+	return party, nil
+}
+
+//Record wraps the network of p to write a transcript of everything p sends and receives to w.
+//The network must hand data to the handler registered last, as networks connecting to remote parties do.
+func (p *Player[E]) Record(w io.Writer) *recordnetwork.Recordnetwork {
+	rn := recordnetwork.New(p.network, p.codec, w)
+	rn.Describe = p.Describe
+	rn.RegisterHandler(p)
+	return rn
+}
+
+//ReplaySetup creates the party that recorded events, running programPath on the data it received.
+//Call Replay on the returned network to start handing it the data.
+func ReplaySetup[E any](events []recordnetwork.Event, f field.Field[E], threshold, n, index int, programPath, inputPath string) (*Player[E], *recordnetwork.Replaynetwork) {
+	party := NewPlayer(f, threshold, n, index)
+	if programPath != "" {
+		party.scanInstructions(programPath)
+	}
+	if inputPath != "" {
+		party.scanInput(inputPath + strconv.Itoa(party.index))
+	}
+	rn := recordnetwork.NewReplay(events, party.Codec())
+	rn.Describe = party.Describe
+	rn.RegisterHandler(party)
+	return party, rn
+}
+
+//SessionSetup creates the party of mux running programPath in session id.
+//Every session has its own player, so sessions do not share identifiers.
+func SessionSetup[E any](mux *sessionnetwork.Mux, id string, f field.Field[E], threshold, n int, programPath, inputPath string) *Player[E] {
+	party := NewPlayer(f, threshold, n, mux.Index())
+	if programPath != "" {
+		party.scanInstructions(programPath)
+	}
+	if inputPath != "" {
+		party.scanInput(inputPath + strconv.Itoa(party.index))
+	}
+	mux.Session(id).RegisterHandler(party)
+	return party
+}
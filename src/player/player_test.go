@@ -1,33 +1,204 @@
 package player
 
 import (
+	"bytes"
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"errors"
+	"fmt"
 	"math/big"
+	"reflect"
 	"strconv"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"../bigshamir"
+	"../field"
 	"../network"
+	"../network/batchnetwork"
+	"../network/broadcastnetwork"
+	"../network/channetwork"
+	"../network/faultnetwork"
 	"../network/localnetwork"
+	"../network/recordnetwork"
+	"../network/relaynetwork"
+	"../network/sessionnetwork"
+	"../network/simnetwork"
+	"../network/statsnetwork"
+	"../network/tcpnetwork"
 )
 
-func setting(prime int64, threshold, n int) map[int]*Player {
-	parties := make(map[int]*Player, n)
+//option changes how setting connects the parties
+type option func(c *connection)
+
+//connection is how setting connects the parties. Every party is wrapped by the layers in order, the first next to the party,
+//and the outermost layer is registered with the network of the party, which wrap may wrap in turn.
+//Every layer wraps the codec of the layers below it by the codec in codecs at the same position.
+type connection struct {
+	layers    []func(party *Player[*big.Int]) layer
+	codecs    []func(inner network.Codec) network.Codec
+	transport transport
+	wrap      func(index int, inner network.Network) network.Network
+}
+
+//layer is a network sitting between a handler and the network below, like the layers of the setups
+type layer interface {
+	network.Network
+	network.Handler
+}
+
+//transport creates the networks of n parties sending data encoded by codec, and connects them once handlers are registered
+type transport struct {
+	networks func(codec network.Codec, n int) map[int]network.Network
+	connect  func(networks map[int]network.Network, handlers []network.Handler)
+}
+
+//local connects the parties by local networks, which is the default
+var local = transport{
+	networks: func(codec network.Codec, n int) map[int]network.Network {
+		networks := make(map[int]network.Network, n)
+		for i := 0; i < n; i++ {
+			networks[i+1] = new(localnetwork.Localnetwork)
+		}
+		return networks
+	},
+	connect: func(networks map[int]network.Network, handlers []network.Handler) {
+		for _, ln := range networks {
+			ln.(*localnetwork.Localnetwork).SetConnections(handlers...)
+		}
+	},
+}
+
+//overChannels connects the parties by channel networks with bounded inboxes
+func overChannels() option {
+	return func(c *connection) {
+		c.transport = transport{
+			networks: func(codec network.Codec, n int) map[int]network.Network {
+				networks := make(map[int]network.Network, n)
+				for i, cn := range channetwork.Networks(n) {
+					networks[i+1] = cn
+				}
+				return networks
+			},
+			connect: func(networks map[int]network.Network, handlers []network.Handler) {
+				channels := make([]*channetwork.Channetwork, 0, len(networks))
+				for _, cn := range networks {
+					channels = append(channels, cn.(*channetwork.Channetwork))
+				}
+				for _, cn := range channels {
+					cn.SetConnections(channels...)
+				}
+			},
+		}
+	}
+}
+
+//overLinks connects the parties by simulated links with the given latency and bandwidth
+func overLinks(link simnetwork.Link) option {
+	return func(c *connection) {
+		c.transport = transport{
+			networks: func(codec network.Codec, n int) map[int]network.Network {
+				simulation := simnetwork.NewSimulation(link, codec)
+				networks := make(map[int]network.Network, n)
+				for i, sn := range simulation.Networks(n) {
+					networks[i+1] = sn
+				}
+				return networks
+			},
+			connect: func(networks map[int]network.Network, handlers []network.Handler) {
+				for _, sn := range networks {
+					sn.(*simnetwork.Simnetwork).SetConnections(handlers...)
+				}
+			},
+		}
+	}
+}
+
+//withBroadcast makes the parties broadcast in mode, tolerating as many corrupt parties as their threshold
+func withBroadcast(mode broadcastnetwork.Mode) option {
+	return func(c *connection) {
+		c.layers = append(c.layers, func(party *Player[*big.Int]) layer {
+			return broadcastnetwork.New(mode, party.threshold, party.Codec())
+		})
+		c.codecs = append(c.codecs, func(inner network.Codec) network.Codec {
+			return broadcastnetwork.NewCodec(inner)
+		})
+	}
+}
+
+//withBatches queues the messages of every party according to policy. The batch networks are added to batches unless it is nil.
+func withBatches(policy batchnetwork.Policy, batches map[int]*batchnetwork.Batchnetwork) option {
+	return func(c *connection) {
+		c.layers = append(c.layers, func(party *Player[*big.Int]) layer {
+			bn := batchnetwork.New(policy)
+			if batches != nil {
+				batches[party.index] = bn
+			}
+			return bn
+		})
+		c.codecs = append(c.codecs, func(inner network.Codec) network.Codec {
+			return batchnetwork.NewCodec(inner)
+		})
+	}
+}
+
+//withFaults lets faults be injected into the messages sent by every party through the fault networks added to faults
+func withFaults(faults map[int]*faultnetwork.Faultnetwork) option {
+	return func(c *connection) {
+		c.wrap = func(index int, inner network.Network) network.Network {
+			faults[index] = faultnetwork.New(inner)
+			return faults[index]
+		}
+	}
+}
+
+//addRule adds rule to the fault network fn
+func addRule(fn *faultnetwork.Faultnetwork, rule faultnetwork.Rule, t *testing.T) {
+	if err := fn.AddRule(rule); err != nil {
+		t.Fatal(err)
+	}
+}
+
+//setting creates n parties computing modulo prime, connected by local networks unless options say otherwise
+func setting(prime *big.Int, threshold, n int, options ...option) map[int]*Player[*big.Int] {
+	c := connection{transport: local}
+	for _, o := range options {
+		o(&c)
+	}
+
+	parties := make(map[int]*Player[*big.Int], n)
 	handlers := make([]network.Handler, n)
 	for i := range handlers {
-		parties[i+1] = NewPlayer(prime, threshold, n, i+1)
-		handlers[i] = parties[i+1]
+		parties[i+1] = NewPlayer(field.NewBig(prime), threshold, n, i+1)
+		var handler network.Handler = parties[i+1]
+		for _, newLayer := range c.layers {
+			l := newLayer(parties[i+1])
+			l.RegisterHandler(handler)
+			handler = l
+		}
+		handlers[i] = handler
 	}
 
-	for _, party := range parties {
-		ln := new(localnetwork.Localnetwork)
-		ln.RegisterHandler(party)
-		ln.SetConnections(handlers...)
+	var codec network.Codec = parties[1].Codec()
+	for _, wrapCodec := range c.codecs {
+		codec = wrapCodec(codec)
 	}
-
+	networks := c.transport.networks(codec, n)
+	for index, inner := range networks {
+		if c.wrap != nil {
+			inner = c.wrap(index, inner)
+		}
+		inner.RegisterHandler(handlers[index-1])
+	}
+	c.transport.connect(networks, handlers)
 	return parties
 }
 
 func TestShare(t *testing.T) {
-	parties := setting(11, 1, 3)
+	parties := setting(big.NewInt(11), 1, 3)
 	parties[1].Share(big.NewInt(3), "id3")
 	for _, party := range parties {
 		party.Open("id3")
@@ -37,7 +208,7 @@ func TestShare(t *testing.T) {
 
 func TestAdd(t *testing.T) {
 	testAdd := func(a, b, prime int64) {
-		parties := setting(prime, 1, 3)
+		parties := setting(big.NewInt(prime), 1, 3)
 		parties[1].Share(big.NewInt(a), "a")
 		parties[2].Share(big.NewInt(b), "b")
 		for _, party := range parties {
@@ -58,7 +229,7 @@ func TestAdd(t *testing.T) {
 
 func TestMultiply(t *testing.T) {
 	testMult := func(a, b, prime int64) {
-		parties := setting(prime, 2, 5)
+		parties := setting(big.NewInt(prime), 2, 5)
 		parties[1].Share(big.NewInt(a), "a")
 		parties[2].Share(big.NewInt(b), "b")
 		for _, party := range parties {
@@ -79,9 +250,410 @@ func TestMultiply(t *testing.T) {
 	testMult(0, 9, 11)
 }
 
+func TestMultiplySimulated(t *testing.T) {
+	latency := 20 * time.Millisecond
+	parties := setting(big.NewInt(11), 1, 3, overLinks(simnetwork.Link{Latency: latency, Bandwidth: 1 << 20}))
+	start := time.Now()
+	parties[1].Share(big.NewInt(3), "a")
+	parties[2].Share(big.NewInt(9), "b")
+	for _, party := range parties {
+		go party.Multiply("a", "b", "aTimesB")
+		go party.Open("aTimesB")
+	}
+	shouldBe(5, parties[1].Reconstruct("aTimesB"), "3 * 9 mod 11", t)
+	//Sharing inputs, resharing the product and opening are three rounds
+	if elapsed := time.Since(start); elapsed < 3*latency {
+		t.Errorf("Multiplication took %v, expected at least %v", elapsed, 3*latency)
+	}
+}
+
+func benchmarkGreaterThan(b *testing.B, parties map[int]*Player[*big.Int]) {
+	parties[1].Share(big.NewInt(17), "a")
+	parties[2].Share(big.NewInt(42), "b")
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		id := "a>b" + strconv.Itoa(i)
+		for _, party := range parties {
+			go party.GreaterThan("a", "b", id)
+			go party.Open(id)
+		}
+		parties[1].Reconstruct(id)
+	}
+}
+
+func TestOpenReliableBroadcast(t *testing.T) {
+	parties := setting(big.NewInt(11), 1, 4, overChannels(), withBroadcast(broadcastnetwork.Reliable))
+	parties[1].Share(big.NewInt(3), "a")
+	parties[2].Share(big.NewInt(9), "b")
+	for _, party := range parties {
+		go func(party *Player[*big.Int]) {
+			party.Add("a", "b", "aPlusB")
+			party.Open("aPlusB")
+		}(party)
+	}
+	for _, party := range parties {
+		shouldBe(1, party.Reconstruct("aPlusB"), "3 + 9 mod 11", t)
+	}
+}
+
+func TestGreaterThanChannels(t *testing.T) {
+	parties := setting(big.NewInt(11), 3, 7, overChannels())
+	parties[1].Share(big.NewInt(7), "a")
+	parties[2].Share(big.NewInt(3), "b")
+	for _, pair := range [][2]string{{"a", "b"}, {"b", "a"}} {
+		id := pair[0] + ">" + pair[1]
+		for _, party := range parties {
+			go party.GreaterThan(pair[0], pair[1], id)
+			go party.Open(id)
+		}
+		var testResult int64
+		if pair[0] == "a" {
+			testResult = 1
+		}
+		shouldBe(testResult, parties[1].Reconstruct(id), id, t)
+	}
+}
+
+func BenchmarkGreaterThanChannels(b *testing.B) {
+	benchmarkGreaterThan(b, setting(big.NewInt(4001), 9, 20, overChannels()))
+}
+
+func TestGreaterThanBatched(t *testing.T) {
+	var prime int64 = 11
+	batches := make(map[int]*batchnetwork.Batchnetwork)
+	parties := setting(big.NewInt(prime), 1, 3, withBatches(batchnetwork.Policy{MaxDelay: time.Millisecond}, batches))
+
+	ids := make([]string, prime)
+	for i := range ids {
+		ids[i] = strconv.Itoa(i)
+		parties[1].Share(big.NewInt(int64(i)), ids[i])
+	}
+	for _, pair := range [][2]int{{3, 7}, {7, 3}, {5, 5}, {10, 0}} {
+		id := ids[pair[0]] + " > " + ids[pair[1]]
+		for _, party := range parties {
+			go party.GreaterThan(ids[pair[0]], ids[pair[1]], id)
+			go party.Open(id)
+		}
+		var testResult int64
+		if pair[0] > pair[1] {
+			testResult = 1
+		}
+		shouldBe(testResult, parties[1].Reconstruct(id), id, t)
+	}
+
+	messages, frames := batches[1].Counts()
+	t.Logf("Party 1 sent %d messages in %d frames", messages, frames)
+	if frames >= messages {
+		t.Errorf("Batching did not reduce %d messages", messages)
+	}
+}
+
+func BenchmarkGreaterThanBatched(b *testing.B) {
+	parties := setting(big.NewInt(4001), 1, 3, withBatches(batchnetwork.Policy{MaxDelay: time.Millisecond}, nil))
+	benchmarkGreaterThan(b, parties)
+}
+
+func BenchmarkGreaterThanBatchedSimulatedWAN(b *testing.B) {
+	wan := simnetwork.Link{Latency: 20 * time.Millisecond, Bandwidth: 10 << 20, Jitter: 2 * time.Millisecond}
+	benchmarkGreaterThan(b, setting(big.NewInt(4001), 1, 3, overLinks(wan), withBatches(batchnetwork.Policy{MaxDelay: time.Millisecond}, nil)))
+}
+
+func BenchmarkGreaterThanLocal(b *testing.B) {
+	benchmarkGreaterThan(b, setting(big.NewInt(4001), 1, 3))
+}
+
+func BenchmarkGreaterThanSimulatedWAN(b *testing.B) {
+	//Parties spread across a continent
+	wan := simnetwork.Link{Latency: 20 * time.Millisecond, Bandwidth: 10 << 20, Jitter: 2 * time.Millisecond}
+	benchmarkGreaterThan(b, setting(big.NewInt(4001), 1, 3, overLinks(wan)))
+}
+
+func TestMultiplyWithMoreParties(t *testing.T) {
+	//Parties must recombine the same sharings of local products, whichever arrive first
+	for i := 0; i < 20; i++ {
+		parties := setting(big.NewInt(4001), 1, 5)
+		parties[1].Share(big.NewInt(3), "a")
+		parties[2].Share(big.NewInt(9), "b")
+		for _, party := range parties {
+			go party.Multiply("a", "b", "aTimesB")
+			go party.Open("aTimesB")
+		}
+		for _, party := range parties {
+			shouldBe(27, party.Reconstruct("aTimesB"), "3 * 9", t)
+		}
+	}
+}
+
+func TestMultiplyWithDuplicates(t *testing.T) {
+	faults := make(map[int]*faultnetwork.Faultnetwork)
+	parties := setting(big.NewInt(11), 2, 5, withFaults(faults))
+	for _, fn := range faults {
+		addRule(fn, faultnetwork.Rule{Action: faultnetwork.Duplicate}, t)
+	}
+	parties[1].Share(big.NewInt(3), "a")
+	parties[2].Share(big.NewInt(9), "b")
+	for _, party := range parties {
+		go party.Multiply("a", "b", "aTimesB")
+		go party.Open("aTimesB")
+	}
+	for _, party := range parties {
+		shouldBe(5, party.Reconstruct("aTimesB"), "3 * 9 mod 11", t)
+	}
+}
+
+func TestRandomBitWithDuplicates(t *testing.T) {
+	faults := make(map[int]*faultnetwork.Faultnetwork)
+	parties := setting(big.NewInt(11), 1, 3, withFaults(faults))
+	for _, fn := range faults {
+		addRule(fn, faultnetwork.Rule{Action: faultnetwork.Duplicate}, t)
+	}
+	for _, party := range parties {
+		go party.RandomBit("b")
+		go party.Open("b")
+	}
+	b := parties[1].Reconstruct("b")
+	if b.Cmp(big.NewInt(0)) != 0 && b.Cmp(big.NewInt(1)) != 0 {
+		t.Errorf("Random bit is not a bit: %d", b)
+	}
+}
+
+func TestMultiplyWithReorder(t *testing.T) {
+	faults := make(map[int]*faultnetwork.Faultnetwork)
+	parties := setting(big.NewInt(11), 1, 3, withFaults(faults))
+	//Party 1's multiplication share towards party 2 is overtaken by its opening share.
+	//Its share towards itself is needed before it can open.
+	addRule(faults[1], faultnetwork.Rule{
+		Receiver: 2,
+		Kind:     faultnetwork.Kind(multiplicationShare[*big.Int]{}),
+		Action:   faultnetwork.Reorder,
+	}, t)
+	parties[1].Share(big.NewInt(3), "a")
+	parties[2].Share(big.NewInt(9), "b")
+	for _, party := range parties {
+		go party.Multiply("a", "b", "aTimesB")
+		go party.Open("aTimesB")
+	}
+	for _, party := range parties {
+		shouldBe(5, party.Reconstruct("aTimesB"), "3 * 9 mod 11", t)
+	}
+}
+
+func TestOpenWithDroppedShares(t *testing.T) {
+	faults := make(map[int]*faultnetwork.Faultnetwork)
+	parties := setting(big.NewInt(11), 2, 5, withFaults(faults))
+	parties[1].Share(big.NewInt(7), "a")
+	parties[1].getShareValue("a")
+	//t+1 of the remaining shares suffice to reconstruct
+	addRule(faults[5], faultnetwork.Rule{Action: faultnetwork.Drop}, t)
+	for _, party := range parties {
+		go party.Open("a")
+	}
+	for index, party := range parties {
+		if index != 5 {
+			shouldBe(7, party.Reconstruct("a"), "a", t)
+		}
+	}
+}
+
+func TestOpenWithCorruptedShare(t *testing.T) {
+	faults := make(map[int]*faultnetwork.Faultnetwork)
+	parties := setting(big.NewInt(11), 1, 3, withFaults(faults))
+	parties[1].Share(big.NewInt(7), "a")
+	//Party 2 adds one to its share when opening a towards party 1
+	addRule(faults[2], faultnetwork.Rule{
+		Receiver: 1,
+		Action:   faultnetwork.Corrupt,
+		Corrupt: func(data interface{}) interface{} {
+			share := data.(reconstructionShare[*big.Int])
+			y := new(big.Int).Add(share.point.Y, big.NewInt(1))
+			share.point = bigshamir.SecretShare[*big.Int]{X: share.point.X, Y: y}
+			return share
+		},
+	}, t)
+	//Party 1 reconstructs from its own share and the wrong one
+	addRule(faults[3], faultnetwork.Rule{Receiver: 1, Action: faultnetwork.Drop}, t)
+	for _, party := range parties {
+		go party.Open("a")
+	}
+	//A single wrong share goes unnoticed and changes the reconstructed value
+	if parties[1].Reconstruct("a").Cmp(big.NewInt(7)) == 0 {
+		t.Error("Corrupted share did not change the reconstructed value")
+	}
+	shouldBe(7, parties[3].Reconstruct("a"), "a", t)
+}
+
+//corruptOpen makes party index add one to its share whenever it opens a value
+func corruptOpen(faults map[int]*faultnetwork.Faultnetwork, index int, prime int64, t *testing.T) {
+	addRule(faults[index], faultnetwork.Rule{
+		Kind:   faultnetwork.Kind(reconstructionShare[*big.Int]{}),
+		Action: faultnetwork.Corrupt,
+		Corrupt: func(data interface{}) interface{} {
+			share := data.(reconstructionShare[*big.Int])
+			y := new(big.Int).Add(share.point.Y, big.NewInt(1))
+			share.point = bigshamir.SecretShare[*big.Int]{X: share.point.X, Y: y.Mod(y, big.NewInt(prime))}
+			return share
+		},
+	}, t)
+}
+
+func TestSharesClaimingAnotherSenderAreDropped(t *testing.T) {
+	party := setting(big.NewInt(11), 1, 3)[1]
+	share := bigshamir.SecretShare[*big.Int]{X: 2, Y: big.NewInt(5)}
+	recombination := bigshamir.RecombinationShare[*big.Int]{SecretShare: share, Index: 2}
+	//Party 3 sends shares claiming to be of party 2
+	forged := []interface{}{
+		reconstructionShare[*big.Int]{point: share, id: "x"},
+		multiplicationShare[*big.Int]{recombinationShare: recombination, id: "x"},
+		localRandomFieldElementShare[*big.Int]{point: share, id: "x", index: 2},
+		aSquaredShare[*big.Int]{point: share, id: "x"},
+		packedMultiplicationShare[*big.Int]{recombinationShare: recombination, id: "x", k: 1},
+		refreshShare[*big.Int]{point: share, id: "x", dealer: 2},
+	}
+	for _, data := range forged {
+		party.Handle(data, 3)
+	}
+	kept := map[string]int{
+		OpenKind:                 len(party.reconstructionShares["x"]),
+		MultiplicationKind:       len(party.multShares["x"]),
+		RandomElementKind:        len(party.randFieldElemShares["x"]),
+		ASquaredKind:             len(party.randomBitASquaredShares["x"]),
+		PackedMultiplicationKind: len(party.packedMultShares["x"]),
+		RefreshKind:              len(party.refreshShares["x"]),
+	}
+	for kind, count := range kept {
+		if count != 0 {
+			t.Errorf("Kept %d %s shares claiming to be of another party", count, kind)
+		}
+	}
+}
+
+func TestInputSharesOnlyFromDealer(t *testing.T) {
+	party := setting(big.NewInt(11), 1, 3)[2]
+	party.instructions = []instruction{{"INPUT", "1", "x"}}
+	share := func(y int64) identifiedShare[*big.Int] {
+		return identifiedShare[*big.Int]{point: bigshamir.SecretShare[*big.Int]{X: 2, Y: big.NewInt(y)}, id: "x"}
+	}
+	//Party 3 is not the dealer of x, and the second share of the dealer is a duplicate
+	party.Handle(share(3), 3)
+	party.Handle(share(5), 1)
+	party.Handle(share(6), 1)
+	if value, _ := party.getShareValue("x"); value.Cmp(big.NewInt(5)) != 0 {
+		t.Errorf("Kept share %d of x, not the first share 5 of the dealer", value)
+	}
+}
+
+func TestOpenRobustWithCorruptedShare(t *testing.T) {
+	faults := make(map[int]*faultnetwork.Faultnetwork)
+	parties := setting(big.NewInt(11), 1, 4, withFaults(faults))
+	for _, party := range parties {
+		party.SetRobust(true)
+	}
+	parties[1].Share(big.NewInt(7), "a")
+	//4 shares of threshold 1 correct (4-1-1)/2 = 1 wrong share
+	corruptOpen(faults, 2, 11, t)
+	//The 3 shares arriving first include the wrong one, so parties decode again once the last arrives
+	addRule(faults[4], faultnetwork.Rule{Kind: faultnetwork.Kind(reconstructionShare[*big.Int]{}), Action: faultnetwork.Delay}, t)
+	for _, party := range parties {
+		go party.Open("a")
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		faults[4].Release()
+	}()
+	for index, party := range parties {
+		shouldBe(7, party.Reconstruct("a"), "a", t)
+		if inconsistent := party.InconsistentShares(); !reflect.DeepEqual(inconsistent, map[string][]int{"a": {2}}) {
+			t.Errorf("Party %d found inconsistent shares %v", index, inconsistent)
+		}
+	}
+}
+
+func TestOpenRobustWithSilentParty(t *testing.T) {
+	faults := make(map[int]*faultnetwork.Faultnetwork)
+	parties := setting(big.NewInt(11), 2, 7, withFaults(faults))
+	for _, party := range parties {
+		party.SetRobust(true)
+		party.SetTimeout(5 * time.Second)
+	}
+	parties[1].Share(big.NewInt(7), "a")
+	parties[1].getShareValue("a")
+	//Party 7 never opens and party 2 sends a wrong share, which is all t = 2 corrupt parties can do
+	addRule(faults[7], faultnetwork.Rule{Action: faultnetwork.Drop}, t)
+	corruptOpen(faults, 2, 11, t)
+	for index, party := range parties {
+		if index != 7 {
+			go party.Open("a")
+		}
+	}
+	for index, party := range parties {
+		if index == 7 {
+			continue
+		}
+		shouldBe(7, party.Reconstruct("a"), "a", t)
+		if err := party.Err(); err != nil {
+			t.Errorf("Party %d: %v", index, err)
+		}
+		if inconsistent := party.InconsistentShares()["a"]; len(inconsistent) > 0 && !reflect.DeepEqual(inconsistent, []int{2}) {
+			t.Errorf("Party %d found inconsistent shares %v", index, inconsistent)
+		}
+	}
+}
+
+func TestOpenRobustWithTooManyCorruptedShares(t *testing.T) {
+	faults := make(map[int]*faultnetwork.Faultnetwork)
+	parties := setting(big.NewInt(11), 1, 3, withFaults(faults))
+	for _, party := range parties {
+		party.SetRobust(true)
+	}
+	parties[1].Share(big.NewInt(7), "a")
+	//3 shares of threshold 1 detect a wrong share, but cannot correct it
+	corruptOpen(faults, 2, 11, t)
+	for _, party := range parties {
+		go party.Open("a")
+	}
+	parties[1].Reconstruct("a")
+	if err := parties[1].Err(); !errors.Is(err, bigshamir.ErrTooManyWrongShares) {
+		t.Errorf("Opening with a wrong share aborted with %v", err)
+	}
+}
+
+func TestMultiplyWithDelay(t *testing.T) {
+	faults := make(map[int]*faultnetwork.Faultnetwork)
+	parties := setting(big.NewInt(11), 1, 3, withFaults(faults))
+	parties[1].Share(big.NewInt(3), "a")
+	parties[2].Share(big.NewInt(9), "b")
+	for _, party := range parties {
+		party.getShareValue("a")
+		party.getShareValue("b")
+	}
+	addRule(faults[3], faultnetwork.Rule{
+		Kind:   faultnetwork.Kind(multiplicationShare[*big.Int]{}),
+		Action: faultnetwork.Delay,
+	}, t)
+	for _, party := range parties {
+		party.Multiply("a", "b", "aTimesB")
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	parties[1].shareLock.RLock()
+	_, exists := parties[1].idVals["aTimesB"]
+	parties[1].shareLock.RUnlock()
+	if exists {
+		t.Error("Product was computed without the delayed shares")
+	}
+
+	faults[3].Release()
+	for _, party := range parties {
+		go party.Open("aTimesB")
+	}
+	for _, party := range parties {
+		shouldBe(5, party.Reconstruct("aTimesB"), "3 * 9 mod 11", t)
+	}
+}
+
 func TestGreaterThan(t *testing.T) {
 	var prime int64 = 5
-	parties := setting(prime, 1, 3)
+	parties := setting(big.NewInt(prime), 1, 3)
 
 	ids := make([]string, prime)
 	for i := range ids {
@@ -109,7 +681,7 @@ func TestGreaterThan(t *testing.T) {
 
 func TestGreaterThanOrEqual(t *testing.T) {
 	var prime int64 = 5
-	parties := setting(prime, 1, 3)
+	parties := setting(big.NewInt(prime), 1, 3)
 
 	ids := make([]string, prime)
 	for i := range ids {
@@ -137,7 +709,7 @@ func TestGreaterThanOrEqual(t *testing.T) {
 
 func TestNotEqual(t *testing.T) {
 	var prime int64 = 5
-	parties := setting(prime, 1, 3)
+	parties := setting(big.NewInt(prime), 1, 3)
 
 	ids := make([]string, prime)
 	for i := range ids {
@@ -165,7 +737,7 @@ func TestNotEqual(t *testing.T) {
 
 func TestEqual(t *testing.T) {
 	var prime int64 = 5
-	parties := setting(prime, 1, 3)
+	parties := setting(big.NewInt(prime), 1, 3)
 
 	ids := make([]string, prime)
 	for i := range ids {
@@ -192,13 +764,16 @@ func TestEqual(t *testing.T) {
 }
 
 func TestRun(t *testing.T) {
-	parties := LocalSetup(11, 1, 3,
+	parties := LocalSetup(field.NewBig(big.NewInt(11)), 1, 3, Options{},
 		"tests/test1/prog",
 		"tests/test1/input")
 
 	go parties[1].Run()
 	go parties[2].Run()
-	output := parties[3].Run()
+	output, err := parties[3].Run()
+	if err != nil {
+		t.Fatal(err)
+	}
 	if output["3*3"].Cmp(big.NewInt(9)) != 0 {
 		t.Errorf("3 * 3 mod 11 should be 9 was %d", output["3*3"])
 	}
@@ -210,30 +785,464 @@ func TestRun(t *testing.T) {
 	}
 }
 
+func TestRunBatched(t *testing.T) {
+	parties := LocalSetup(field.NewBig(big.NewInt(11)), 1, 3, Options{Batch: true},
+		"tests/test1/prog",
+		"tests/test1/input")
+
+	go parties[1].Run()
+	go parties[2].Run()
+	output, err := parties[3].Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output["3*3"].Cmp(big.NewInt(9)) != 0 {
+		t.Errorf("3 * 3 mod 11 should be 9 was %d", output["3*3"])
+	}
+	bn, batched := parties[3].network.(*batchnetwork.Batchnetwork)
+	if !batched {
+		t.Fatalf("Party 3 sends through %T, not a batch network", parties[3].network)
+	}
+	if messages, frames := bn.Counts(); frames >= messages {
+		t.Errorf("Batching did not reduce %d messages", messages)
+	}
+}
+
 func TestRunCompiled(t *testing.T) {
-	parties := LocalSetup(11, 1, 3,
+	parties := LocalSetup(field.NewBig(big.NewInt(11)), 1, 3, Options{},
 		"tests/compiled/prog",
 		"tests/compiled/input")
 
 	go parties[1].Run()
 	go parties[2].Run()
-	output := parties[3].Run()
+	output, err := parties[3].Run()
+	if err != nil {
+		t.Fatal(err)
+	}
 	// go parties[3].Run()
 	if output["max_output"] == nil {
 		t.Error("Run compiled")
 	}
 }
 
+//runCompiled runs the compiled program with 3 parties computing in f
+func runCompiled[E any](f field.Field[E], t *testing.T) map[string]*big.Int {
+	parties := LocalSetup(f, 1, 3, Options{}, "tests/compiled/prog", "tests/compiled/input")
+	go parties[1].Run()
+	go parties[2].Run()
+	output, err := parties[3].Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return output
+}
+
+func TestRunMont64(t *testing.T) {
+	mont64, err := field.NewMont64(101)
+	if err != nil {
+		t.Fatal(err)
+	}
+	output := runCompiled(mont64, t)
+	expected := runCompiled(field.NewBig(big.NewInt(101)), t)
+	if output["max_output"] == nil {
+		t.Error("No max_output")
+	}
+	if !reflect.DeepEqual(output, expected) {
+		t.Errorf("Outputs in Mont64 field %v differ from outputs in Big field %v", output, expected)
+	}
+}
+
+func TestRunTCP(t *testing.T) {
+	n := 3
+	parties := make(map[int]*Player[*big.Int], n)
+	networks := make([]*tcpnetwork.Tcpnetwork, n)
+	addresses := make(map[int]string, n)
+	for i := range networks {
+		party := NewPlayer(field.NewBig(big.NewInt(11)), 1, n, i+1)
+		party.scanInstructions("tests/test1/prog")
+		party.scanInput("tests/test1/input" + strconv.Itoa(i+1))
+		parties[i+1] = party
+
+		networks[i] = tcpnetwork.New(party.Codec())
+		networks[i].RegisterHandler(party)
+		networks[i].SetConnections(map[int]string{i + 1: "127.0.0.1:0"})
+		if err := networks[i].Listen(); err != nil {
+			t.Fatal(err)
+		}
+		defer networks[i].Close()
+		addresses[i+1] = networks[i].Addr().String()
+	}
+	for _, tn := range networks {
+		tn.SetConnections(addresses)
+	}
+
+	go parties[1].Run()
+	go parties[2].Run()
+	output, err := parties[3].Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output["4*4"].Cmp(big.NewInt(5)) != 0 {
+		t.Errorf("4 * 4 mod 11 should be 5 was %d", output["4*4"])
+	}
+}
+
+func TestRunRelay(t *testing.T) {
+	n := 3
+	server := relaynetwork.NewServer()
+	if err := server.Listen("127.0.0.1:0"); err != nil {
+		t.Fatal(err)
+	}
+	defer server.Close()
+
+	privates := make(map[int]*ecdh.PrivateKey, n)
+	publics := make(map[int]*ecdh.PublicKey, n)
+	for i := 1; i <= n; i++ {
+		key, err := relaynetwork.GenerateKey()
+		if err != nil {
+			t.Fatal(err)
+		}
+		privates[i] = key
+		publics[i] = key.PublicKey()
+	}
+	parties := make(map[int]*Player[*big.Int], n)
+	for i := 1; i <= n; i++ {
+		keys := relaynetwork.Keys{Private: privates[i], Peers: publics}
+		party, err := RelaySetup(field.NewBig(big.NewInt(11)), 1, n, i, Options{}, server.Addr().String(), keys, "tests/test1/prog", "tests/test1/input")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer party.Close()
+		parties[i] = party
+	}
+
+	go parties[1].Run()
+	go parties[2].Run()
+	output, err := parties[3].Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if output["4*4"].Cmp(big.NewInt(5)) != 0 {
+		t.Errorf("4 * 4 mod 11 should be 5 was %d", output["4*4"])
+	}
+}
+
+func TestReplay(t *testing.T) {
+	parties := LocalSetup(field.NewBig(big.NewInt(11)), 1, 3, Options{}, "tests/compiled/prog", "tests/compiled/input")
+	var transcript bytes.Buffer
+	recorder := parties[2].Record(&transcript)
+
+	go parties[1].Run()
+	go parties[3].Run()
+	recorded, err := parties[2].Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	parties[2].WaitForSends()
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := recordnetwork.ReadTranscript(&transcript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	party, replay := ReplaySetup(events, field.NewBig(big.NewInt(11)), 1, 3, 2, "tests/compiled/prog", "tests/compiled/input")
+	go func() {
+		if err := replay.Replay(); err != nil {
+			t.Error(err)
+		}
+	}()
+	replayed, err := party.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for id, value := range recorded {
+		if replayed[id] == nil || replayed[id].Cmp(value) != 0 {
+			t.Errorf("Replay output %s = %v, recorded %v", id, replayed[id], value)
+		}
+	}
+
+	//The replayed party sends as much as it did, some of it after its output is known
+	sent := 0
+	for _, event := range events {
+		if event.Type == recordnetwork.Sent {
+			sent++
+		}
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(replay.Sent()) < sent && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if len(replay.Sent()) != sent {
+		t.Errorf("Replayed party sent %d messages, recorded %d", len(replay.Sent()), sent)
+	}
+}
+
+func TestSessions(t *testing.T) {
+	n := 3
+	muxes := make([]*sessionnetwork.Mux, n)
+	handlers := make([]network.Handler, n)
+	for i := range muxes {
+		muxes[i] = sessionnetwork.New(i + 1)
+		handlers[i] = muxes[i]
+	}
+	for _, mux := range muxes {
+		ln := new(localnetwork.Localnetwork)
+		ln.RegisterHandler(mux)
+		ln.SetConnections(handlers...)
+	}
+
+	//Both sessions run the same program, so they use the same identifiers
+	inputs := map[string][]int64{"a": {1, 1, 1}, "b": {1, 2, 3}}
+	expected := map[string]int64{"a": 2, "b": 9}
+	results := make(chan error, 2*n)
+	for id, input := range inputs {
+		for index := 1; index <= n; index++ {
+			if id == "b" && index == 3 {
+				//Data for the session arrives before it is set up
+				time.Sleep(10 * time.Millisecond)
+			}
+			party := SessionSetup(muxes[index-1], id, field.NewBig(big.NewInt(11)), 1, n, "tests/test1/prog", "")
+			party.setInput(map[string]*big.Int{string(rune('A' + index - 1)): big.NewInt(input[index-1])})
+			go func(id string, party *Player[*big.Int]) {
+				output, err := party.Run()
+				if err == nil && output["3*3"].Cmp(big.NewInt(expected[id])) != 0 {
+					err = fmt.Errorf("session %s: 3*3 was %d, expected %d", id, output["3*3"], expected[id])
+				}
+				results <- err
+			}(id, party)
+		}
+	}
+	for i := 0; i < 2*n; i++ {
+		if err := <-results; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
+func TestStats(t *testing.T) {
+	n := 3
+	parties := make(map[int]*Player[*big.Int], n)
+	networks := make(map[int]*statsnetwork.Statsnetwork, n)
+	locals := make(map[int]*localnetwork.Localnetwork, n)
+	handlers := make([]network.Handler, n)
+	for i := range handlers {
+		index := i + 1
+		parties[index] = NewPlayer(field.NewBig(big.NewInt(11)), 1, n, index)
+		parties[index].scanInstructions("tests/test1/prog")
+		parties[index].scanInput("tests/test1/input" + strconv.Itoa(index))
+		locals[index] = new(localnetwork.Localnetwork)
+		networks[index] = statsnetwork.New(locals[index], parties[index].Codec())
+		networks[index].Kind = Kind
+		handlers[i] = networks[index]
+	}
+	for index, sn := range networks {
+		sn.RegisterHandler(parties[index])
+	}
+	for _, ln := range locals {
+		ln.SetConnections(handlers...)
+	}
+
+	go parties[2].Run()
+	go parties[3].Run()
+	if _, err := parties[1].Run(); err != nil {
+		t.Fatal(err)
+	}
+	parties[1].WaitForSends()
+
+	stats := parties[1].Stats()
+	//Party 1 shares its input, takes part in 3 multiplications and opens 3 outputs, each towards 2 peers
+	expected := map[string]int{InputKind: 2, MultiplicationKind: 6, OpenKind: 6}
+	for kind, messages := range expected {
+		if stats.SentByKind[kind].Messages != messages {
+			t.Errorf("Sent %d %s messages, expected %d", stats.SentByKind[kind].Messages, kind, messages)
+		}
+	}
+	if stats.Sent.Messages != 14 || stats.SentTo[2].Messages != 7 || stats.SentTo[3].Messages != 7 {
+		t.Errorf("Sent %+v, to peers %+v", stats.Sent, stats.SentTo)
+	}
+	if stats.ReceivedByKind[InputKind].Messages != 2 {
+		t.Errorf("Received %d input shares, expected 2", stats.ReceivedByKind[InputKind].Messages)
+	}
+	//One round for the inputs, one for each of the 3 sequential multiplications and one for each output
+	if stats.Rounds < 4 || stats.Rounds > 7 {
+		t.Errorf("Counted %d rounds", stats.Rounds)
+	}
+
+	//The network counts the same traffic to peers, and also the data a party sends itself
+	sent := networks[1].Stats().SentTo
+	if sent[2] != stats.SentTo[2] || sent[3] != stats.SentTo[3] || sent[1] != stats.SentTo[2] {
+		t.Errorf("Player counted %+v sent, network counted %+v", stats.SentTo, sent)
+	}
+	//The input is named A, all other identifiers have 3 characters
+	codec := parties[1].Codec()
+	if stats.Sent.Bytes != 2*codec.EncodedSize(1)+12*codec.EncodedSize(3) {
+		t.Errorf("Sent %d bytes", stats.Sent.Bytes)
+	}
+}
+
+func TestRunFailsOnClosedNetwork(t *testing.T) {
+	parties := setting(big.NewInt(11), 1, 3)
+	parties[1].instructions = []instruction{{"INPUT", "1", "x"}, {"OUTPUT", "x", "x"}}
+	parties[1].setInput(map[string]*big.Int{"x": big.NewInt(3)})
+	parties[1].network.Close()
+
+	output, err := parties[1].Run()
+	if err != network.ErrClosed {
+		t.Errorf("Expected run to fail as network is closed, got %v %v", output, err)
+	}
+}
+
+func TestRunContextCancelled(t *testing.T) {
+	//Party 2 never provides its input, so party 1 waits forever
+	parties := setting(big.NewInt(11), 1, 3)
+	parties[1].instructions = []instruction{{"INPUT", "2", "x"}, {"OUTPUT", "x", "x"}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := parties[1].RunContext(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Expected run to time out, got %v", err)
+	}
+	if parties[1].Err() != context.DeadlineExceeded {
+		t.Errorf("Player was aborted with %v", parties[1].Err())
+	}
+}
+
+func TestHandleErrorAbortsRun(t *testing.T) {
+	parties := setting(big.NewInt(11), 1, 3)
+	parties[1].instructions = []instruction{{"INPUT", "2", "x"}, {"OUTPUT", "x", "x"}}
+
+	failure := &network.PeerError{Peer: 2, Err: errors.New("connection reset")}
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		parties[1].HandleError(failure)
+	}()
+	if _, err := parties[1].Run(); err != failure {
+		t.Errorf("Expected run to fail with failure of party 2, got %v", err)
+	}
+}
+
+func TestAgree(t *testing.T) {
+	agree := func(digests map[int]string) map[int]error {
+		parties := setting(big.NewInt(11), 1, 3)
+		errs := make(map[int]error, len(parties))
+		var lock sync.Mutex
+		var wg sync.WaitGroup
+		for i, party := range parties {
+			wg.Add(1)
+			go func(i int, party *Player[*big.Int]) {
+				defer wg.Done()
+				err := party.Agree(digests[i])
+				lock.Lock()
+				errs[i] = err
+				lock.Unlock()
+			}(i, party)
+		}
+		wg.Wait()
+		return errs
+	}
+
+	for i, err := range agree(map[int]string{1: "a", 2: "a", 3: "a"}) {
+		if err != nil {
+			t.Errorf("Party %d: %v", i, err)
+		}
+	}
+	for i, err := range agree(map[int]string{1: "a", 2: "b", 3: "a"}) {
+		if err == nil || !strings.Contains(err.Error(), "party 2") {
+			t.Errorf("Expected party %d to report the config of party 2, got %v", i, err)
+		}
+	}
+}
+
+func TestTimeoutNamesMissingParties(t *testing.T) {
+	//Only party 1 runs, so the shares it waits for from the others never arrive
+	tests := []struct {
+		instructions []instruction
+		expected     TimeoutError
+	}{
+		{
+			[]instruction{{"INPUT", "1", "x"}, {"OUTPUT", "x", "x"}},
+			TimeoutError{Kind: OpenKind, Identifier: "x", Missing: []int{2, 3}},
+		},
+		{
+			[]instruction{{"INPUT", "3", "x"}, {"OUTPUT", "x", "x"}},
+			TimeoutError{Kind: InputKind, Identifier: "x", Missing: []int{3}},
+		},
+		{
+			[]instruction{{"INPUT", "1", "x"}, {"MULTIPLY", "x", "x", "y"}, {"OUTPUT", "y", "y"}},
+			TimeoutError{Kind: MultiplicationKind, Identifier: "y", Missing: []int{2, 3}},
+		},
+		{
+			[]instruction{{"RANDOM", "r"}, {"OUTPUT", "r", "r"}},
+			TimeoutError{Kind: RandomElementKind, Identifier: "r", Missing: []int{2, 3}},
+		},
+		{
+			[]instruction{{"INPUT", "1", "x"}, {"REFRESH", "x"}, {"OUTPUT", "x", "x"}},
+			TimeoutError{Kind: RefreshKind, Identifier: "x_refresh_1", Missing: []int{2, 3}},
+		},
+	}
+	for _, test := range tests {
+		parties := setting(big.NewInt(11), 1, 3)
+		parties[1].instructions = test.instructions
+		parties[1].setInput(map[string]*big.Int{"x": big.NewInt(3)})
+		parties[1].SetTimeout(20 * time.Millisecond)
+
+		_, err := parties[1].Run()
+		var timeoutErr *TimeoutError
+		if !errors.As(err, &timeoutErr) {
+			t.Errorf("Expected run of %v to time out, got %v", test.instructions, err)
+			continue
+		}
+		test.expected.Timeout = 20 * time.Millisecond
+		if !reflect.DeepEqual(*timeoutErr, test.expected) {
+			t.Errorf("Run of %v failed with %+v", test.instructions, *timeoutErr)
+		}
+	}
+}
+
+func TestAgreeTimeout(t *testing.T) {
+	parties := setting(big.NewInt(11), 1, 3)
+	parties[1].SetTimeout(20 * time.Millisecond)
+	go parties[2].Agree("a")
+	err := parties[1].Agree("a")
+	var timeoutErr *TimeoutError
+	if !errors.As(err, &timeoutErr) || !reflect.DeepEqual(timeoutErr.Missing, []int{3}) {
+		t.Errorf("Expected party 3 to be missing, got %v", err)
+	}
+}
+
+func TestAgreeOverReliableBroadcast(t *testing.T) {
+	parties := LocalSetup(field.NewBig(big.NewInt(11)), 1, 4, Options{ReliableBroadcast: true, Batch: true}, "", "")
+	if _, reliable := parties[1].network.(*broadcastnetwork.Broadcastnetwork); !reliable {
+		t.Fatalf("Party 1 broadcasts through %T", parties[1].network)
+	}
+	errs := make(chan error, len(parties))
+	for _, party := range parties {
+		go func(party *Player[*big.Int]) {
+			errs <- party.Agree("a")
+		}(party)
+	}
+	for range parties {
+		if err := <-errs; err != nil {
+			t.Error(err)
+		}
+	}
+}
+
 func TestRandomBit(t *testing.T) {
 
 	//The random field element is zero with pr. 1/5
-	parties := setting(5, 1, 3)
+	parties := setting(big.NewInt(5), 1, 3)
 	for _, party := range parties {
 		party.scanInstructions("tests/testRandomBit/prog")
 	}
 	go parties[1].Run()
 	go parties[2].Run()
-	output := parties[3].Run()
+	output, err := parties[3].Run()
+	if err != nil {
+		t.Fatal(err)
+	}
 	b1 := parties[1].Reconstruct("b")
 	b2 := parties[2].Reconstruct("b")
 	b3 := output["b"]
@@ -248,7 +1257,7 @@ func TestRandomBit(t *testing.T) {
 }
 
 func TestRandomSolvedBits(t *testing.T) {
-	parties := setting(4001, 1, 3)
+	parties := setting(big.NewInt(4001), 1, 3)
 
 	go parties[1].randomSolvedBits("r")
 	go parties[2].randomSolvedBits("r")
@@ -275,7 +1284,7 @@ func TestRandomSolvedBits(t *testing.T) {
 }
 
 func TestFullAdder(t *testing.T) {
-	parties := setting(4001, 1, 3)
+	parties := setting(big.NewInt(4001), 1, 3)
 
 	parties[1].Share(big.NewInt(0), "0")
 	parties[2].Share(big.NewInt(1), "1")
@@ -349,7 +1358,7 @@ func bitIDs(val *big.Int, prime int64) []string {
 
 func TestBitCompare(t *testing.T) {
 	var prime int64 = 11
-	parties := setting(prime, 1, 3)
+	parties := setting(big.NewInt(prime), 1, 3)
 
 	parties[1].Share(big.NewInt(0), "0")
 	parties[2].Share(big.NewInt(1), "1")
@@ -377,7 +1386,7 @@ func TestBitCompare(t *testing.T) {
 
 func TestBitSub(t *testing.T) {
 	prime := int64(5)
-	parties := setting(prime, 1, 3)
+	parties := setting(big.NewInt(prime), 1, 3)
 
 	iterations := 5
 
@@ -417,7 +1426,7 @@ func TestBitSub(t *testing.T) {
 
 func TestBits(t *testing.T) {
 	prime := 5
-	parties := setting(int64(prime), 1, 3)
+	parties := setting(big.NewInt(int64(prime)), 1, 3)
 
 	for i := 0; i < prime; i++ {
 		input := big.NewInt(int64(i))
@@ -444,9 +1453,61 @@ func TestBits(t *testing.T) {
 
 }
 
+func TestBitsLargePrimes(t *testing.T) {
+	one := big.NewInt(1)
+	mersenne127 := new(big.Int).Sub(new(big.Int).Lsh(one, 127), one)
+	secp256k1, _ := new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+
+	for _, prime := range []*big.Int{mersenne127, secp256k1} {
+		parties := setting(prime, 1, 3)
+		random, _ := rand.Int(rand.Reader, prime)
+		for _, input := range []*big.Int{big.NewInt(0), random, new(big.Int).Sub(prime, one)} {
+			test := input.String() + "fieldElement"
+			parties[1].Share(input, test)
+
+			resultBitsIDs := make([]string, prime.BitLen()+1)
+			for bitIndex := range resultBitsIDs {
+				resultBitsIDs[bitIndex] = test + "_index_" + strconv.Itoa(bitIndex)
+			}
+			for _, party := range parties {
+				go party.bits(test, resultBitsIDs)
+				for bitIndex := range resultBitsIDs {
+					go party.Open(resultBitsIDs[bitIndex])
+				}
+			}
+
+			for bitIndex := range resultBitsIDs {
+				shouldBe(int64(input.Bit(bitIndex)),
+					parties[1].Reconstruct(resultBitsIDs[bitIndex]), resultBitsIDs[bitIndex], t)
+			}
+		}
+	}
+}
+
+func TestMultiplyLargePrime(t *testing.T) {
+	one := big.NewInt(1)
+	prime := new(big.Int).Sub(new(big.Int).Lsh(one, 127), one)
+	parties := setting(prime, 1, 3)
+	a := new(big.Int).Sub(prime, big.NewInt(2))
+	b := new(big.Int).Lsh(one, 100)
+	parties[1].Share(a, "a")
+	parties[2].Share(b, "b")
+	for _, party := range parties {
+		go func(party *Player[*big.Int]) {
+			party.Multiply("a", "b", "c")
+			party.Open("c")
+		}(party)
+	}
+	expected := new(big.Int).Mul(a, b)
+	expected.Mod(expected, prime)
+	if c := parties[3].Reconstruct("c"); c.Cmp(expected) != 0 {
+		t.Errorf("Expected %s, got %s", expected, c)
+	}
+}
+
 func TestMostSignificant1(t *testing.T) {
 	prime := 5
-	parties := setting(int64(prime), 1, 3)
+	parties := setting(big.NewInt(int64(prime)), 1, 3)
 
 	for i := 0; i < prime; i++ {
 		input := big.NewInt(int64(i))
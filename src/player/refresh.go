@@ -0,0 +1,139 @@
+package player
+
+import (
+	"strconv"
+	"strings"
+
+	"../bigshamir"
+)
+
+//refreshShare is a share of a sharing of zero dealt by a party to refresh the shares of a value
+type refreshShare[E any] struct {
+	point  bigshamir.SecretShare[E]
+	id     string
+	dealer int
+}
+
+//RefreshKind is the kind of messages of share refreshes
+const RefreshKind = "refresh"
+
+func (m refreshShare[E]) kind() string       { return RefreshKind }
+func (m refreshShare[E]) identifier() string { return m.id }
+
+//refreshID identifies the sum of the sharings of zero of the round-th refresh of identifier
+func refreshID(identifier string, round int) string {
+	return identifier + "_refresh_" + strconv.Itoa(round)
+}
+
+//refreshRound is the round of the refresh id, or 0 if id is not one
+func refreshRound(id string) int {
+	i := strings.LastIndex(id, "_refresh_")
+	if i < 0 {
+		return 0
+	}
+	round, err := strconv.Atoi(id[i+len("_refresh_"):])
+	if err != nil {
+		return 0
+	}
+	return round
+}
+
+//Refresh re-randomizes the shares of the values identifiers without changing the values.
+//Every party deals a random sharing of zero for each value, and every party adds the shares of all n sharings to its share.
+//The new shares lie on a fresh random polynomial, so shares held before and after a refresh do not reconstruct the value together,
+//and an adversary corrupting different parties over time must collect t+1 shares between two refreshes.
+//All parties must refresh the same values in the same order. Public values have no shares and are left as they are.
+//The sharings of zero are not verified and every party must deal one, so a program run with verifiable input cannot refresh.
+func (p *Player[E]) Refresh(identifiers ...string) {
+	var secrets []string
+	for _, identifier := range identifiers {
+		if _, isSecret := p.getShareValue(identifier); isSecret {
+			secrets = append(secrets, identifier)
+		}
+	}
+
+	//Start the round and mark its values as being refreshed at once,
+	//so shares of rounds that were started are only kept for values being refreshed
+	p.refreshLock.Lock()
+	p.refreshes++
+	round := p.refreshes
+	for _, identifier := range secrets {
+		//A timeout names the parties whose shares are missing
+		id := refreshID(identifier, round)
+		if p.refreshShares[id] == nil {
+			p.refreshShares[id] = make(map[int]E)
+		}
+	}
+	p.refreshLock.Unlock()
+
+	for _, identifier := range secrets {
+		id := refreshID(identifier, round)
+		for _, point := range p.ss.Share(p.field.Zero()) {
+			p.Send(refreshShare[E]{point: point, id: id, dealer: p.index}, point.X)
+		}
+	}
+
+	for _, identifier := range secrets {
+		id := refreshID(identifier, round)
+		old, _ := p.getShareValue(identifier)
+		//Handle sums the shares of zero once those of all parties have arrived
+		zero, _ := p.getShareValue(id)
+		p.setShareValue(identifier, p.field.Add(old, zero), true)
+
+		//Forget the refresh, as programs may refresh long-lived values any number of times
+		p.refreshLock.Lock()
+		delete(p.refreshShares, id)
+		p.refreshLock.Unlock()
+		p.deleteShareValue(id)
+	}
+}
+
+//handleRefreshShare sums the shares of zero of a refresh once those of all parties have arrived.
+//Shares are kept under the party that sent them, which must be their dealer.
+//Shares of rounds p has started are dropped unless p is refreshing the value, so late duplicates of finished refreshes are not kept.
+func (p *Player[E]) handleRefreshShare(share refreshShare[E], sender int) {
+	if share.dealer != sender || sender < 1 || sender > p.n {
+		return
+	}
+	p.refreshLock.Lock()
+	shares := p.refreshShares[share.id]
+	if shares == nil {
+		if refreshRound(share.id) <= p.refreshes {
+			p.refreshLock.Unlock()
+			return
+		}
+		shares = make(map[int]E)
+		p.refreshShares[share.id] = shares
+	}
+	if _, exists := shares[sender]; exists {
+		//Duplicate
+		p.refreshLock.Unlock()
+		return
+	}
+	shares[sender] = share.point.Y
+	complete := len(shares) == p.n
+	sum := p.field.Zero()
+	if complete {
+		for _, y := range shares {
+			sum = p.field.Add(sum, y)
+		}
+	}
+	p.refreshLock.Unlock()
+	if complete {
+		p.setShareValue(share.id, sum, true)
+	}
+}
+
+//missingRefreshShares are the parties whose shares of zero of the refresh id have not arrived, and whether id is a refresh
+func (p *Player[E]) missingRefreshShares(id string) ([]int, bool) {
+	p.refreshLock.Lock()
+	defer p.refreshLock.Unlock()
+	shares, refreshing := p.refreshShares[id]
+	if !refreshing {
+		return nil, false
+	}
+	return p.missingParties(func(index int) bool {
+		_, exists := shares[index]
+		return exists
+	}), true
+}
@@ -0,0 +1,112 @@
+package player
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+
+	"../bigshamir"
+	"../network/broadcastnetwork"
+)
+
+func TestRefresh(t *testing.T) {
+	parties := setting(big.NewInt(4001), 1, 3)
+	program := []instruction{
+		{"INPUT", "1", "a"},
+		{"INPUT", "2", "b"},
+		{"PLUS", "a", "1", "c"},
+		{"LEAK", "c", "d"},
+		{"REFRESH", "a", "b", "d"},
+		{"MULTIPLY", "a", "b", "e"},
+		{"REFRESH", "a", "e"},
+		{"REFRESH", "e"},
+		{"OUTPUT", "a", "a"},
+		{"OUTPUT", "d", "d"},
+		{"OUTPUT", "e", "e"},
+	}
+	for _, party := range parties {
+		party.instructions = program
+	}
+	parties[1].setInput(map[string]*big.Int{"a": big.NewInt(6)})
+	parties[2].setInput(map[string]*big.Int{"b": big.NewInt(7)})
+	for index, output := range runParties(parties, t) {
+		shouldBe(6, output["a"], "a", t)
+		shouldBe(7, output["d"], "d", t)
+		shouldBe(42, output["e"], "e", t)
+		//The public value d is not refreshed, and shares a party deals itself are not counted
+		if refreshes := parties[index].Stats().SentByKind[RefreshKind].Messages; refreshes != 2*5 {
+			t.Errorf("Party %d sent %d shares of zero for 5 refreshed values", index, refreshes)
+		}
+		//Refreshes are forgotten once their shares are added
+		party := parties[index]
+		party.refreshLock.Lock()
+		if len(party.refreshShares) != 0 {
+			t.Errorf("Party %d kept the shares of %d refreshes", index, len(party.refreshShares))
+		}
+		party.refreshLock.Unlock()
+		if _, exists := party.idVals[refreshID("a", 1)]; exists {
+			t.Errorf("Party %d kept the sum of the shares of zero of a", index)
+		}
+	}
+}
+
+func TestRefreshedSharesDoNotMixWithOldShares(t *testing.T) {
+	//A large prime, so mixed shares reconstruct the value only with negligible probability
+	parties := setting(big.NewInt(2305843009213693951), 1, 3)
+	for _, party := range parties {
+		party.instructions = []instruction{{"INPUT", "1", "x"}}
+	}
+	parties[1].setInput(map[string]*big.Int{"x": big.NewInt(1234)})
+	runParties(parties, t)
+
+	shares := func() map[int]*big.Int {
+		shares := make(map[int]*big.Int)
+		for index, party := range parties {
+			shares[index], _ = party.getShareValue("x")
+		}
+		return shares
+	}
+	old := shares()
+	var wg sync.WaitGroup
+	for _, party := range parties {
+		wg.Add(1)
+		go func(party *Player[*big.Int]) {
+			defer wg.Done()
+			party.Refresh("x")
+		}(party)
+	}
+	wg.Wait()
+	refreshed := shares()
+
+	ss := parties[1].ss
+	reconstruct := func(first, second map[int]*big.Int) *big.Int {
+		x, _ := ss.Reconstruct([]bigshamir.SecretShare[*big.Int]{{X: 1, Y: first[1]}, {X: 2, Y: second[2]}})
+		return x
+	}
+	if x := reconstruct(old, old); x.Cmp(big.NewInt(1234)) != 0 {
+		t.Errorf("Old shares reconstructed %d", x)
+	}
+	if x := reconstruct(refreshed, refreshed); x.Cmp(big.NewInt(1234)) != 0 {
+		t.Errorf("Refreshed shares reconstructed %d", x)
+	}
+	for index := range parties {
+		if old[index].Cmp(refreshed[index]) == 0 {
+			t.Errorf("Share of party %d was not refreshed", index)
+		}
+	}
+	if x := reconstruct(old, refreshed); x.Cmp(big.NewInt(1234)) == 0 {
+		t.Error("An old and a refreshed share reconstructed the value")
+	}
+	if x := reconstruct(refreshed, old); x.Cmp(big.NewInt(1234)) == 0 {
+		t.Error("A refreshed and an old share reconstructed the value")
+	}
+}
+
+func TestRefreshNeedsUnverifiedInput(t *testing.T) {
+	parties := setting(big.NewInt(4001), 1, 4, withBroadcast(broadcastnetwork.Reliable))
+	parties[1].instructions = []instruction{{"REFRESH", "a"}}
+	parties[1].SetVerifiable(true)
+	if _, err := parties[1].Run(); err == nil {
+		t.Error("Refreshed with unverified sharings of zero in a verifiable program")
+	}
+}
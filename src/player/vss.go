@@ -0,0 +1,530 @@
+package player
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"../bigshamir"
+	"../network"
+)
+
+//Verifiable secret sharing of inputs as in BGW, for t < n/3 and without computational assumptions.
+//The dealer draws a symmetric bivariate polynomial S with S(0, 0) = input and sends party i its row S(x, i).
+//Party i sends party j its row at j, which equals the row of j at i if the dealer is honest.
+//Parties broadcast complaints about rows that disagree. The dealer broadcasts which complainants it answers
+//and resolves their complaints by broadcasting the row of the party that is wrong. Every party then votes whether the sharing is consistent.
+//With at least n-t votes for it, party i's share is its row at 0, otherwise the dealer is disqualified and the input is 0.
+//Complaints, answers and votes go over the reliable broadcast, so all parties decide alike on what was broadcast,
+//however long they waited for parties that never respond.
+
+type (
+	//vssRow is a coefficient of the row the dealer sends to a party
+	vssRow[E any] struct {
+		id          string
+		coefficient int
+		value       E
+	}
+	//vssCheck is the row of the sender at the receiver
+	vssCheck[E any] struct {
+		id    string
+		value E
+	}
+	//vssComplaint is broadcast when the check from party against differs from the row of the sender at against,
+	//which is value
+	vssComplaint[E any] struct {
+		id      string
+		against int
+		value   E
+	}
+	//vssComplaints is broadcast after the complaints of the sender with their number
+	vssComplaints struct {
+		id    string
+		count int
+	}
+	//vssReveal is a coefficient of the row of party, broadcast by the dealer to resolve complaints
+	vssReveal[E any] struct {
+		id          string
+		party       int
+		coefficient int
+		value       E
+	}
+	//vssAnswer is broadcast by the dealer for every complainant whose complaints it answers
+	vssAnswer struct {
+		id          string
+		complainant int
+	}
+	//vssReveals is broadcast by the dealer after the rows it revealed and the complainants it answered with their numbers
+	vssReveals struct {
+		id      string
+		count   int
+		answers int
+	}
+	//vssVote is broadcast by every party once complaints are resolved
+	vssVote struct {
+		id     string
+		accept bool
+	}
+)
+
+//Kinds of messages of verifiable input
+const (
+	VSSRowKind        = "vss row"
+	VSSCheckKind      = "vss check"
+	VSSComplaintKind  = "vss complaint"
+	VSSComplaintsKind = "vss complaints"
+	VSSRevealKind     = "vss reveal"
+	VSSAnswerKind     = "vss answer"
+	VSSRevealsKind    = "vss reveals"
+	VSSVoteKind       = "vss vote"
+)
+
+func (m vssRow[E]) kind() string       { return VSSRowKind }
+func (m vssCheck[E]) kind() string     { return VSSCheckKind }
+func (m vssComplaint[E]) kind() string { return VSSComplaintKind }
+func (m vssComplaints) kind() string   { return VSSComplaintsKind }
+func (m vssReveal[E]) kind() string    { return VSSRevealKind }
+func (m vssAnswer) kind() string       { return VSSAnswerKind }
+func (m vssReveals) kind() string      { return VSSRevealsKind }
+func (m vssVote) kind() string         { return VSSVoteKind }
+
+func (m vssRow[E]) identifier() string       { return m.id }
+func (m vssCheck[E]) identifier() string     { return m.id }
+func (m vssComplaint[E]) identifier() string { return m.id }
+func (m vssComplaints) identifier() string   { return m.id }
+func (m vssReveal[E]) identifier() string    { return m.id }
+func (m vssAnswer) identifier() string       { return m.id }
+func (m vssReveals) identifier() string      { return m.id }
+func (m vssVote) identifier() string         { return m.id }
+
+//vssSharing collects the messages of the verifiable sharing of an input
+type vssSharing[E any] struct {
+	//Coefficients of the row of this party
+	row map[int]E
+	//Rows of the other parties at this party
+	checks map[int]E
+	//Values of complaints by complainant and the party complained about
+	complaints      map[int]map[int]E
+	complaintCounts map[int]int
+	//Coefficients of revealed rows by party
+	reveals     map[int]map[int]E
+	revealCount int
+	//Complainants answered by the dealer and their number
+	answered    map[int]bool
+	answerCount int
+	votes       map[int]bool
+	//Closed and replaced whenever a message arrives
+	updated chan struct{}
+}
+
+//SetVerifiable makes p share inputs with verifiable secret sharing, so a dealer cannot hand out inconsistent shares.
+//It needs t < n/3 and a reliable broadcast, and must be set for all parties before they run.
+func (p *Player[E]) SetVerifiable(verifiable bool) {
+	p.verifiable = verifiable
+}
+
+//Disqualified maps every input shared verifiably so far whose dealer was disqualified to the dealer
+func (p *Player[E]) Disqualified() map[string]int {
+	p.vssLock.Lock()
+	defer p.vssLock.Unlock()
+	disqualified := make(map[string]int, len(p.disqualified))
+	for identifier, dealer := range p.disqualified {
+		disqualified[identifier] = dealer
+	}
+	return disqualified
+}
+
+//sharing returns the state of the verifiable sharing of identifier, vssLock must be held
+func (p *Player[E]) sharing(identifier string) *vssSharing[E] {
+	s, exists := p.vssSharings[identifier]
+	if !exists {
+		s = &vssSharing[E]{
+			row:             make(map[int]E),
+			checks:          make(map[int]E),
+			complaints:      make(map[int]map[int]E),
+			complaintCounts: make(map[int]int),
+			reveals:         make(map[int]map[int]E),
+			revealCount:     -1,
+			answered:        make(map[int]bool),
+			answerCount:     -1,
+			votes:           make(map[int]bool),
+			updated:         make(chan struct{}),
+		}
+		p.vssSharings[identifier] = s
+	}
+	return s
+}
+
+//handleVSS records a message of verifiable input. The first message of a kind from a sender counts.
+//Rows, reveals and answers are only taken from the dealer of the input.
+func (p *Player[E]) handleVSS(data interface{}, sender int) {
+	m := data.(message)
+	dealer, _ := p.inputDealer(m.identifier())
+	p.vssLock.Lock()
+	defer p.vssLock.Unlock()
+	s := p.sharing(m.identifier())
+	switch t := data.(type) {
+	case vssRow[E]:
+		if sender != dealer || t.coefficient < 0 || t.coefficient > p.threshold {
+			return
+		}
+		if _, exists := s.row[t.coefficient]; !exists {
+			s.row[t.coefficient] = t.value
+		}
+	case vssCheck[E]:
+		if _, exists := s.checks[sender]; !exists {
+			s.checks[sender] = t.value
+		}
+	case vssComplaint[E]:
+		if s.complaints[sender] == nil {
+			s.complaints[sender] = make(map[int]E)
+		}
+		if _, exists := s.complaints[sender][t.against]; !exists {
+			s.complaints[sender][t.against] = t.value
+		}
+	case vssComplaints:
+		if _, exists := s.complaintCounts[sender]; !exists {
+			s.complaintCounts[sender] = t.count
+		}
+	case vssReveal[E]:
+		if sender != dealer || t.coefficient < 0 || t.coefficient > p.threshold {
+			return
+		}
+		if s.reveals[t.party] == nil {
+			s.reveals[t.party] = make(map[int]E)
+		}
+		if _, exists := s.reveals[t.party][t.coefficient]; !exists {
+			s.reveals[t.party][t.coefficient] = t.value
+		}
+	case vssAnswer:
+		if sender == dealer {
+			s.answered[t.complainant] = true
+		}
+	case vssReveals:
+		if sender == dealer && s.revealCount < 0 {
+			s.revealCount, s.answerCount = t.count, t.answers
+		}
+	case vssVote:
+		if _, exists := s.votes[sender]; !exists {
+			s.votes[sender] = t.accept
+		}
+	}
+	close(s.updated)
+	s.updated = make(chan struct{})
+}
+
+//DefaultVSSGrace is how long a party without a timeout waits for the remaining checks and complaints
+//once n-t parties have sent theirs
+const DefaultVSSGrace = time.Second
+
+//vssGrace is how long p waits for the remaining parties once enough have responded, its timeout if it has one
+func (p *Player[E]) vssGrace() time.Duration {
+	if p.timeout > 0 {
+		return p.timeout
+	}
+	return DefaultVSSGrace
+}
+
+//waitVSS blocks until ready holds for the sharing of identifier, or until quorum parties have responded and the grace period has passed since.
+//With a quorum of n it waits until ready holds.
+//It reports false if p is aborted meanwhile. If p times out before quorum parties have responded, the error names the parties that have not.
+func (p *Player[E]) waitVSS(identifier, kind string, quorum int, ready func(v *vssSharing[E]) bool, responded func(v *vssSharing[E]) func(int) bool) bool {
+	deadline := p.deadline()
+	var grace <-chan time.Time
+	for {
+		p.vssLock.Lock()
+		s := p.sharing(identifier)
+		if ready(s) {
+			p.vssLock.Unlock()
+			return true
+		}
+		if grace == nil && p.n-len(p.missingParties(responded(s))) >= quorum {
+			grace = time.After(p.vssGrace())
+			deadline = nil
+		}
+		updated := s.updated
+		p.vssLock.Unlock()
+		p.flush()
+		select {
+		case <-updated:
+		case <-grace:
+			return true
+		case <-p.done:
+			return false
+		case <-deadline:
+			p.vssLock.Lock()
+			missingParties := p.missingParties(responded(p.sharing(identifier)))
+			p.vssLock.Unlock()
+			p.timedOut(kind, identifier, missingParties)
+			return false
+		}
+	}
+}
+
+//coefficients of a row, or nil if some are missing
+func (p *Player[E]) coefficients(row map[int]E) []E {
+	if len(row) != p.threshold+1 {
+		return nil
+	}
+	coefficients := make([]E, p.threshold+1)
+	for k, c := range row {
+		coefficients[k] = c
+	}
+	return coefficients
+}
+
+//verifiableInput runs the verifiable sharing of the input identifier provided by dealer. Every party runs it.
+//Afterwards p holds its share of the input, or the constant 0 if the dealer was disqualified.
+func (p *Player[E]) verifiableInput(dealer int, identifier string) {
+	f := p.field
+	var s bigshamir.Bivariate[E]
+	if p.index == dealer {
+		s = p.ss.ShareBivariate(f.FromBig(p.readInput(identifier)))
+		for i := 1; i <= p.n; i++ {
+			for k, c := range s.Row(i) {
+				p.Send(vssRow[E]{id: identifier, coefficient: k, value: c}, i)
+			}
+		}
+	}
+	//As up to t parties may never respond, checks and complaints are waited for only for a grace period once n-t parties have sent theirs
+	quorum := p.n - p.threshold
+
+	//Check the row from the dealer against the rows of the other parties
+	hasRow := func(v *vssSharing[E]) bool { return len(v.row) == p.threshold+1 }
+	if !p.waitVSS(identifier, VSSRowKind, p.n, hasRow, func(v *vssSharing[E]) func(int) bool {
+		return func(index int) bool { return index != dealer || hasRow(v) }
+	}) {
+		return
+	}
+	p.vssLock.Lock()
+	row := p.coefficients(p.sharing(identifier).row)
+	p.vssLock.Unlock()
+	for i := 1; i <= p.n; i++ {
+		if i != p.index {
+			p.Send(vssCheck[E]{id: identifier, value: bigshamir.EvaluateRow(f, row, i)}, i)
+		}
+	}
+	checked := func(v *vssSharing[E]) func(int) bool {
+		return func(index int) bool { _, exists := v.checks[index]; return exists || index == p.index }
+	}
+	if !p.waitVSS(identifier, VSSCheckKind, quorum,
+		func(v *vssSharing[E]) bool { return len(p.missingParties(checked(v))) == 0 },
+		checked) {
+		return
+	}
+
+	//Complain about the checks that disagree. A missing check is no complaint, so the row of a slow party is not revealed.
+	p.vssLock.Lock()
+	checks := p.sharing(identifier).checks
+	var complaints []vssComplaint[E]
+	for i := 1; i <= p.n; i++ {
+		if check, exists := checks[i]; exists && !f.Equal(check, bigshamir.EvaluateRow(f, row, i)) {
+			complaints = append(complaints, vssComplaint[E]{id: identifier, against: i, value: bigshamir.EvaluateRow(f, row, i)})
+		}
+	}
+	p.vssLock.Unlock()
+	for _, complaint := range complaints {
+		p.Broadcast(complaint)
+	}
+	p.Broadcast(vssComplaints{id: identifier, count: len(complaints)})
+	complained := func(v *vssSharing[E]) func(int) bool {
+		return func(index int) bool {
+			count, exists := v.complaintCounts[index]
+			return exists && len(v.complaints[index]) >= count
+		}
+	}
+	if !p.waitVSS(identifier, VSSComplaintsKind, quorum,
+		func(v *vssSharing[E]) bool { return len(p.missingParties(complained(v))) == 0 },
+		complained) {
+		return
+	}
+
+	//The dealer answers the complainants whose complaints are all in. It reveals the row of the complainant
+	//if its value is wrong, or else the row complained about.
+	if p.index == dealer {
+		p.vssLock.Lock()
+		v := p.sharing(identifier)
+		var answered []int
+		revealed := make(map[int]bool)
+		for complainant, against := range v.complaints {
+			if !complained(v)(complainant) {
+				continue
+			}
+			answered = append(answered, complainant)
+			for party, value := range against {
+				if f.Equal(value, s.At(party, complainant)) {
+					revealed[party] = true
+				} else {
+					revealed[complainant] = true
+				}
+			}
+		}
+		p.vssLock.Unlock()
+		sort.Ints(answered)
+		parties := make([]int, 0, len(revealed))
+		for party := range revealed {
+			parties = append(parties, party)
+		}
+		sort.Ints(parties)
+		for _, complainant := range answered {
+			p.Broadcast(vssAnswer{id: identifier, complainant: complainant})
+		}
+		for _, party := range parties {
+			for k, c := range s.Row(party) {
+				p.Broadcast(vssReveal[E]{id: identifier, party: party, coefficient: k, value: c})
+			}
+		}
+		p.Broadcast(vssReveals{id: identifier, count: len(parties), answers: len(answered)})
+	}
+	complete := func(v *vssSharing[E]) map[int][]E {
+		rows := make(map[int][]E)
+		for party, row := range v.reveals {
+			if coefficients := p.coefficients(row); coefficients != nil {
+				rows[party] = coefficients
+			}
+		}
+		return rows
+	}
+	//The answer of the dealer is complete once its rows, its answers and the complaints it answered are in.
+	//All of them were broadcast reliably, so every party sees the same answer.
+	answeredAll := func(v *vssSharing[E]) bool {
+		if v.revealCount < 0 || len(complete(v)) < v.revealCount || len(v.answered) < v.answerCount {
+			return false
+		}
+		for complainant := range v.answered {
+			if !complained(v)(complainant) {
+				return false
+			}
+		}
+		return true
+	}
+	if !p.waitVSS(identifier, VSSRevealsKind, p.n, answeredAll, func(v *vssSharing[E]) func(int) bool {
+		return func(index int) bool { return index != dealer || answeredAll(v) }
+	}) {
+		return
+	}
+
+	//Accept if every answered complaint is resolved, every revealed row agrees with ours,
+	//and ours agrees with every check from a party whose row was not revealed.
+	//A party whose own row was revealed takes the revealed one.
+	p.vssLock.Lock()
+	v := p.sharing(identifier)
+	revealed := complete(v)
+	revealedRow, ownRevealed := revealed[p.index]
+	if ownRevealed {
+		row = revealedRow
+	}
+	accept := true
+	for complainant := range v.answered {
+		for party, value := range v.complaints[complainant] {
+			if _, exists := revealed[complainant]; exists {
+				continue
+			}
+			if partyRow, exists := revealed[party]; exists && f.Equal(bigshamir.EvaluateRow(f, partyRow, complainant), value) {
+				continue
+			}
+			accept = false
+		}
+	}
+	for party, partyRow := range revealed {
+		if party != p.index && !f.Equal(bigshamir.EvaluateRow(f, partyRow, p.index), bigshamir.EvaluateRow(f, row, party)) {
+			accept = false
+		}
+	}
+	if !ownRevealed {
+		for party, check := range v.checks {
+			if _, exists := revealed[party]; !exists && !f.Equal(check, bigshamir.EvaluateRow(f, row, party)) {
+				accept = false
+			}
+		}
+	}
+	p.vssLock.Unlock()
+	p.Broadcast(vssVote{id: identifier, accept: accept})
+
+	//n-t votes for the sharing accept it and more than t against reject it. Votes are broadcast reliably
+	//and no party votes twice, so all parties decide alike.
+	votes := func(v *vssSharing[E]) (accepting, rejecting int) {
+		for _, vote := range v.votes {
+			if vote {
+				accepting++
+			} else {
+				rejecting++
+			}
+		}
+		return
+	}
+	if !p.waitVSS(identifier, VSSVoteKind, p.n,
+		func(v *vssSharing[E]) bool {
+			accepting, rejecting := votes(v)
+			return accepting >= quorum || rejecting > p.threshold
+		},
+		func(v *vssSharing[E]) func(int) bool {
+			return func(index int) bool { _, exists := v.votes[index]; return exists }
+		}) {
+		return
+	}
+	p.vssLock.Lock()
+	accepting, _ := votes(p.sharing(identifier))
+	if accepting < quorum {
+		p.disqualified[identifier] = dealer
+	}
+	p.vssLock.Unlock()
+	if accepting < quorum {
+		p.setShareValue(identifier, f.Zero(), false)
+		return
+	}
+	if accept || ownRevealed {
+		p.setShareValue(identifier, bigshamir.EvaluateRow(f, row, 0), true)
+		return
+	}
+	share, err := p.recoverShare(identifier)
+	if err != nil {
+		p.abort(fmt.Errorf("player: recovering the share of %s: %w", identifier, err))
+		return
+	}
+	p.setShareValue(identifier, share, true)
+}
+
+//recoverShare decodes the share of p of the accepted sharing of identifier when its own row is inconsistent.
+//The row of party j at p equals the row of p at j, so the revealed rows and the checks from the parties that voted for the sharing
+//are points of the row of p. It is accepted if at least 2t+1 of them lie on it, t+1 of which are from honest parties.
+func (p *Player[E]) recoverShare(identifier string) (E, error) {
+	f := p.field
+	p.vssLock.Lock()
+	v := p.sharing(identifier)
+	var points []bigshamir.SecretShare[E]
+	for party := 1; party <= p.n; party++ {
+		if party == p.index {
+			continue
+		}
+		if revealedRow := p.coefficients(v.reveals[party]); revealedRow != nil {
+			points = append(points, bigshamir.SecretShare[E]{X: party, Y: bigshamir.EvaluateRow(f, revealedRow, p.index)})
+		} else if check, exists := v.checks[party]; exists && v.votes[party] {
+			points = append(points, bigshamir.SecretShare[E]{X: party, Y: check})
+		}
+	}
+	p.vssLock.Unlock()
+	share, wrong, err := p.ss.RobustReconstruct(points)
+	if err != nil {
+		return f.Zero(), err
+	}
+	if agreeing := len(points) - len(wrong); agreeing < 2*p.threshold+1 {
+		return f.Zero(), fmt.Errorf("only %d of %d points agree", agreeing, len(points))
+	}
+	return share, nil
+}
+
+//checkVerifiable fails if p shares inputs verifiably with too many corrupt parties or without a reliable broadcast
+func (p *Player[E]) checkVerifiable() error {
+	if !p.verifiable {
+		return nil
+	}
+	if 3*p.threshold >= p.n {
+		return fmt.Errorf("player: verifiable input needs threshold %d below n/3, there are %d parties", p.threshold, p.n)
+	}
+	if !network.IsReliable(p.network) {
+		return errors.New("player: verifiable input needs a reliable broadcast")
+	}
+	return nil
+}
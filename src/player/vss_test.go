@@ -0,0 +1,217 @@
+package player
+
+import (
+	"math/big"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"../bigshamir"
+	"../network/broadcastnetwork"
+	"../network/faultnetwork"
+)
+
+//verifiable makes the parties run program with verifiable input
+func verifiable(parties map[int]*Player[*big.Int], program []instruction, inputs map[int]map[string]*big.Int) {
+	for index, party := range parties {
+		party.instructions = program
+		party.SetVerifiable(true)
+		if inputs[index] != nil {
+			party.setInput(inputs[index])
+		}
+	}
+}
+
+//runParties runs all parties but the ones in skip and returns their outputs
+func runParties(parties map[int]*Player[*big.Int], t *testing.T, skip ...int) map[int]map[string]*big.Int {
+	outputs := make(map[int]map[string]*big.Int)
+	var lock sync.Mutex
+	var wg sync.WaitGroup
+	for index, party := range parties {
+		if contains(skip, index) {
+			continue
+		}
+		wg.Add(1)
+		go func(index int, party *Player[*big.Int]) {
+			defer wg.Done()
+			output, err := party.Run()
+			if err != nil {
+				t.Errorf("Party %d failed: %v", index, err)
+			}
+			lock.Lock()
+			outputs[index] = output
+			lock.Unlock()
+		}(index, party)
+	}
+	wg.Wait()
+	return outputs
+}
+
+func contains(indexes []int, index int) bool {
+	for _, i := range indexes {
+		if i == index {
+			return true
+		}
+	}
+	return false
+}
+
+func TestVerifiableInput(t *testing.T) {
+	parties := setting(big.NewInt(4001), 1, 4, withBroadcast(broadcastnetwork.Reliable))
+	program := []instruction{{"INPUT", "1", "x"}, {"INPUT", "2", "y"}, {"MULTIPLY", "x", "y", "z"}, {"OUTPUT", "z", "z"}}
+	inputs := map[int]map[string]*big.Int{1: {"x": big.NewInt(12)}, 2: {"y": big.NewInt(34)}}
+	verifiable(parties, program, inputs)
+	for index, output := range runParties(parties, t) {
+		shouldBe(12*34, output["z"], "z", t)
+		if disqualified := parties[index].Disqualified(); len(disqualified) != 0 {
+			t.Errorf("Party %d disqualified %v", index, disqualified)
+		}
+	}
+}
+
+func TestVerifiableInputResolvesComplaints(t *testing.T) {
+	faults := make(map[int]*faultnetwork.Faultnetwork)
+	parties := setting(big.NewInt(4001), 1, 4, withBroadcast(broadcastnetwork.Reliable), withFaults(faults))
+	//Party 2 receives a wrong row, so it and the others complain about each other
+	addRule(faults[1], faultnetwork.Rule{
+		Receiver: 2,
+		Kind:     faultnetwork.Kind(vssRow[*big.Int]{}),
+		Action:   faultnetwork.Corrupt,
+		Corrupt: func(data interface{}) interface{} {
+			row := data.(vssRow[*big.Int])
+			row.value = new(big.Int).Add(row.value, big.NewInt(1))
+			row.value.Mod(row.value, big.NewInt(4001))
+			return row
+		},
+	}, t)
+	program := []instruction{{"INPUT", "1", "x"}, {"OUTPUT", "x", "x"}}
+	verifiable(parties, program, map[int]map[string]*big.Int{1: {"x": big.NewInt(1234)}})
+	outputs := runParties(parties, t)
+	for index, output := range outputs {
+		shouldBe(1234, output["x"], "x", t)
+		if disqualified := parties[index].Disqualified(); len(disqualified) != 0 {
+			t.Errorf("Party %d disqualified %v", index, disqualified)
+		}
+		parties[index].vssLock.Lock()
+		if complaints := parties[index].sharing("x").complaints; len(complaints) != 4 {
+			t.Errorf("Party %d received complaints %v, expected all parties to complain about party 2", index, complaints)
+		}
+		parties[index].vssLock.Unlock()
+	}
+}
+
+func TestVerifiableInputDisqualifiesCheatingDealer(t *testing.T) {
+	parties := setting(big.NewInt(4001), 1, 4, withBroadcast(broadcastnetwork.Reliable))
+	program := []instruction{{"INPUT", "1", "x"}, {"OUTPUT", "x", "x"}}
+	//Party 1 deals rows of two different polynomials and resolves no complaint
+	ss := bigshamir.NewSS(big.NewInt(4001), 1, 4)
+	s, other := ss.ShareBivariate(big.NewInt(7)), ss.ShareBivariate(big.NewInt(7))
+	verifiable(parties, program, nil)
+	dealer := parties[1]
+	for i := 1; i <= 4; i++ {
+		row := s.Row(i)
+		if i == 4 {
+			row = other.Row(i)
+		}
+		for k, c := range row {
+			dealer.Send(vssRow[*big.Int]{id: "x", coefficient: k, value: c}, i)
+		}
+	}
+	for i := 2; i <= 4; i++ {
+		dealer.Send(vssCheck[*big.Int]{id: "x", value: s.At(i, 1)}, i)
+	}
+	dealer.Broadcast(vssComplaints{id: "x", count: 0})
+	dealer.Broadcast(vssReveals{id: "x", count: 0, answers: 0})
+	dealer.Broadcast(vssVote{id: "x", accept: true})
+
+	outputs := runParties(parties, t, 1)
+	for index, output := range outputs {
+		shouldBe(0, output["x"], "x", t)
+		if disqualified := parties[index].Disqualified(); !reflect.DeepEqual(disqualified, map[string]int{"x": 1}) {
+			t.Errorf("Party %d disqualified %v", index, disqualified)
+		}
+	}
+}
+
+func TestVerifiableInputRecoversInconsistentRow(t *testing.T) {
+	parties := setting(big.NewInt(4001), 1, 4, withBroadcast(broadcastnetwork.Reliable))
+	program := []instruction{{"INPUT", "1", "x"}}
+	//Party 1 deals party 4 a row of another polynomial and answers only the complaints of parties 2 and 3 by revealing their rows.
+	//Parties 1 to 3 accept, so party 4 votes against the sharing but must recover its share.
+	ss := bigshamir.NewSS(big.NewInt(4001), 1, 4)
+	s, other := ss.ShareBivariate(big.NewInt(7)), ss.ShareBivariate(big.NewInt(7))
+	verifiable(parties, program, nil)
+	dealer := parties[1]
+	for i := 1; i <= 4; i++ {
+		row := s.Row(i)
+		if i == 4 {
+			row = other.Row(i)
+		}
+		for k, c := range row {
+			dealer.Send(vssRow[*big.Int]{id: "x", coefficient: k, value: c}, i)
+		}
+	}
+	for i := 2; i <= 4; i++ {
+		dealer.Send(vssCheck[*big.Int]{id: "x", value: s.At(i, 1)}, i)
+	}
+	dealer.Broadcast(vssComplaints{id: "x", count: 0})
+	for _, party := range []int{2, 3} {
+		dealer.Broadcast(vssAnswer{id: "x", complainant: party})
+		for k, c := range s.Row(party) {
+			dealer.Broadcast(vssReveal[*big.Int]{id: "x", party: party, coefficient: k, value: c})
+		}
+	}
+	dealer.Broadcast(vssReveals{id: "x", count: 2, answers: 2})
+	dealer.Broadcast(vssVote{id: "x", accept: true})
+
+	runParties(parties, t, 1)
+	for index := 2; index <= 4; index++ {
+		if share, _ := parties[index].getShareValue("x"); share.Cmp(s.At(0, index)) != 0 {
+			t.Errorf("Party %d holds share %d, not %d", index, share, s.At(0, index))
+		}
+		if disqualified := parties[index].Disqualified(); len(disqualified) != 0 {
+			t.Errorf("Party %d disqualified %v", index, disqualified)
+		}
+	}
+	parties[4].vssLock.Lock()
+	if vote := parties[4].sharing("x").votes[4]; vote {
+		t.Error("Party 4 voted for the sharing")
+	}
+	parties[4].vssLock.Unlock()
+}
+
+func TestVerifiableInputWithSilentParty(t *testing.T) {
+	parties := setting(big.NewInt(4001), 1, 4, withBroadcast(broadcastnetwork.Reliable))
+	program := []instruction{{"INPUT", "1", "x"}, {"OUTPUT", "x", "x"}}
+	verifiable(parties, program, map[int]map[string]*big.Int{1: {"x": big.NewInt(1234)}})
+	for _, party := range parties {
+		party.SetTimeout(time.Second)
+	}
+	//Party 4 never runs, so its checks, complaints and vote are missing and the dealer reveals its row
+	outputs := runParties(parties, t, 4)
+	for index, output := range outputs {
+		shouldBe(1234, output["x"], "x", t)
+		if disqualified := parties[index].Disqualified(); len(disqualified) != 0 {
+			t.Errorf("Party %d disqualified %v", index, disqualified)
+		}
+	}
+}
+
+func TestVerifiableInputNeedsThresholdBelowThird(t *testing.T) {
+	parties := setting(big.NewInt(4001), 1, 3)
+	parties[1].instructions = []instruction{{"INPUT", "1", "x"}, {"OUTPUT", "x", "x"}}
+	parties[1].SetVerifiable(true)
+	if _, err := parties[1].Run(); err == nil {
+		t.Error("Ran verifiable input with threshold 1 of 3 parties")
+	}
+}
+
+func TestVerifiableInputNeedsReliableBroadcast(t *testing.T) {
+	parties := setting(big.NewInt(4001), 1, 4)
+	parties[1].instructions = []instruction{{"INPUT", "1", "x"}, {"OUTPUT", "x", "x"}}
+	parties[1].SetVerifiable(true)
+	if _, err := parties[1].Run(); err == nil {
+		t.Error("Ran verifiable input without a reliable broadcast")
+	}
+}
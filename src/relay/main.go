@@ -0,0 +1,47 @@
+package main
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+
+	"../network/relaynetwork"
+)
+
+//keygen writes a new private key to prefix.key and its public key to prefix.pub
+func keygen(prefix string) {
+	key, err := relaynetwork.GenerateKey()
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(prefix+".key", []byte(hex.EncodeToString(key.Bytes())+"\n"), 0600); err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(prefix+".pub", []byte(hex.EncodeToString(key.PublicKey().Bytes())+"\n"), 0644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func main() {
+	if len(os.Args) == 3 && os.Args[1] == "keygen" {
+		keygen(os.Args[2])
+		return
+	}
+	if len(os.Args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: relay address | relay keygen prefix")
+		os.Exit(2)
+	}
+
+	server := relaynetwork.NewServer()
+	if err := server.Listen(os.Args[1]); err != nil {
+		log.Fatal(err)
+	}
+	log.Println("relaying on", server.Addr())
+
+	interrupt := make(chan os.Signal, 1)
+	signal.Notify(interrupt, os.Interrupt)
+	<-interrupt
+	server.Close()
+}
@@ -0,0 +1,6 @@
+//go:build !race
+
+package uintshamir
+
+//raceEnabled tells whether the tests run with the race detector, which makes code allocate
+const raceEnabled = false
@@ -1,188 +1,228 @@
 package uintshamir
 
 import (
+	"errors"
 	"fmt"
-	"crypto/rand"
 	"math/big"
-)
+	"strconv"
 
-//The SecretSharingScheme defines the parameters for the Shamir secret sharing
-type SecretSharingScheme struct {
-	p int64
-	t int
-	n int
-}
+	"../field"
+)
 
-var standardSetting = SecretSharingScheme { p : 5, t : 2,	n : 3}
-var wikipediaExample = SecretSharingScheme { p : 11, t : 2,	n : 5}
+type (
+	//SecretShare represented by a point on a polynomial
+	//i.e. the share of party X has value Y, an integer in [0, p)
+	SecretShare struct {
+		X int
+		Y uint64
+	}
+	//RecombinationShare is indexed by the party who shared it for recombining
+	RecombinationShare struct {
+		SecretShare SecretShare
+		Index       int
+	}
+)
 
-type point = struct {
-	x, y int64
+func (p SecretShare) String() string {
+	return "(" + strconv.Itoa(p.X) + "," + strconv.FormatUint(p.Y, 10) + ")"
 }
 
-type polynomial = []int64
-
-//Share splits a secret into shares
-func (ss *SecretSharingScheme) Share(secret int64) []point { //todo probably returns array not slice?
-	secret = secret % ss.p
-
-	fmt.Println("Creating", ss.n, "shares")
-	var h = polynomial{secret}
+//SecretSharingScheme defines a shamir secret sharing scheme for n parties in Z_p for a prime p below 2^63.
+//Values fit in a machine word and arithmetic is done in a Mont64 field, so apart from
+//the slices returned by Share, Add, Scale and Mul nothing is allocated.
+type SecretSharingScheme struct {
+	field     *field.Mont64
+	p         uint64
+	threshold int
+	n         int
+}
 
-	for coefficient := 1; coefficient <= ss.t; coefficient++ {
-		randBigInt, _ := rand.Int(rand.Reader, big.NewInt( int64(ss.p)  ))
-		h = append(h, randBigInt.Int64())
+//NewSS constructs a secret sharing scheme over the field of integers modulo the prime p.
+//p must be an odd prime below 2^63 and larger than n, and the threshold below n. Mul needs 2t+1 <= n.
+func NewSS(p uint64, threshold, n int) (SecretSharingScheme, error) {
+	f, err := field.NewMont64(p)
+	if err != nil {
+		return SecretSharingScheme{}, err
 	}
-
-	fmt.Println(h)
-
-	var shares []point
-
-	for i := 1; i <= ss.n; i++ {//Todo optimize and make overflow safe
-		//Evaluate h in "i" and add share (i, h(i))
-		y := secret
-		x := int64(i)
-		xpower := int64(i)
-		for coefficient := 1; coefficient <= ss.t; coefficient++ {
-			// fmt.Println(i, y)
-			y += h[coefficient] * xpower
-			xpower = xpower * x
-		}
-		// fmt.Println(i, y)
-		fmt.Println(i, y % ss.p)
-		shares = append(shares, point{x: x, y : y % ss.p})
+	if !new(big.Int).SetUint64(p).ProbablyPrime(20) {
+		return SecretSharingScheme{}, fmt.Errorf("uintshamir: %d is not a prime", p)
+	}
+	if threshold < 0 || threshold >= n {
+		return SecretSharingScheme{}, fmt.Errorf("uintshamir: threshold %d needs more than %d parties, there are %d", threshold, threshold, n)
 	}
+	if uint64(n) >= p {
+		return SecretSharingScheme{}, fmt.Errorf("uintshamir: prime %d must be larger than the number of parties %d", p, n)
+	}
+	return SecretSharingScheme{field: f, p: p, threshold: threshold, n: n}, nil
+}
 
+//Share splits a secret into shares (points). The secret is reduced mod p.
+func (ss *SecretSharingScheme) Share(secret uint64) []SecretShare {
+	shares := make([]SecretShare, ss.n)
+	ss.ShareTo(shares, secret)
 	return shares
 }
 
-//Reconstruct extracts the secret from t or more shares
-func (ss *SecretSharingScheme) Reconstruct(shares []point) int64{
-	k := len(shares)
-	if k < ss.t + 1 {
-		fmt.Println("Not enough shares to reconstruct")
-		return 0
-	}
-	//todo check k distinct x's
-
-
-	
-	var nums []int64
-	var dens []int64
-	for _, share := range(shares) {
-		fmt.Println("Coordinates", share.x, share.y)
-		num := int64(1)
-		den := int64(1)
-		for _, otherShare := range(shares) {
-			if share.x == otherShare.x { continue}
-			num = num * (0 - otherShare.x) 
-			den = den * (share.x - otherShare.x)
+//ShareTo is Share writing the n shares to shares instead of allocating them
+func (ss *SecretSharingScheme) ShareTo(shares []SecretShare, secret uint64) {
+	if len(shares) != ss.n {
+		panic("uintshamir: " + strconv.Itoa(len(shares)) + " shares for " + strconv.Itoa(ss.n) + " parties")
+	}
+	f := ss.field
+	//Evaluate a random polynomial h with h(0) = secret at all points at once by Horner's rule,
+	//drawing its coefficients from the highest down, so they need not be stored
+	for i := range shares {
+		shares[i] = SecretShare{X: i + 1, Y: uint64(f.Zero())}
+	}
+	for coefficient := ss.threshold; coefficient >= 0; coefficient-- {
+		var c field.Mont64Element
+		if coefficient > 0 {
+			c = f.Random()
+		} else {
+			c = f.FromUint64(secret)
+		}
+		for i := range shares {
+			//Y holds the partial evaluation in Montgomery form until the end
+			y := f.Mul(field.Mont64Element(shares[i].Y), f.FromUint64(uint64(shares[i].X)))
+			shares[i].Y = uint64(f.Add(y, c))
 		}
-		nums = append(nums, num)
-		dens = append(dens, den)
-		
 	}
-
-	den := int64(1)
-	for _, d := range(dens) {den *= d}
-
-	num := int64(0)
-	for i := range(dens) {
-		num += divideMod(nums[i] * den * shares[i].y % ss.p, dens[i], ss.p)
+	for i := range shares {
+		shares[i].Y = f.Uint64(field.Mont64Element(shares[i].Y))
 	}
+}
 
-	return (divideMod(num, den, ss.p) + ss.p) % ss.p
-
-	// secret := int64(0)
-	// for _, share := range(shares) {
-	// 	fmt.Println("Coordinates", share.x, share.y)
-	// 	num := int64(1)
-	// 	den := int64(1)
-	// 	for _, otherShare := range(shares) {
-	// 		if share.x == otherShare.x { continue}
-	// 		num = num * (otherShare.x)
-	// 		den = den * (otherShare.x - share.x)
-	// 	}
-	// 	//num*(den)^-1= delta_i
-	// 	secret += share.y * num * multiplicativeInverse(den, ss.p)
-	// }
-
-
-	// return secret % ss.p
+//Reconstruct extracts the secret from threshold+1 or more shares.
+//It fails if there are too few shares or two shares of the same party.
+func (ss *SecretSharingScheme) Reconstruct(shares []SecretShare) (uint64, error) {
+	if len(shares) < ss.threshold+1 {
+		return 0, fmt.Errorf("uintshamir: %d shares cannot reconstruct a secret of threshold %d", len(shares), ss.threshold)
+	}
+	for i := range shares {
+		if shares[i].X <= 0 || uint64(shares[i].X) >= ss.p {
+			return 0, fmt.Errorf("uintshamir: share at %d, parties are at 1 to p-1", shares[i].X)
+		}
+		for j := i + 1; j < len(shares); j++ {
+			if shares[i].X == shares[j].X {
+				return 0, fmt.Errorf("uintshamir: two shares of party %d", shares[i].X)
+			}
+		}
+	}
 
+	f := ss.field
+	sum := f.Zero()
+	for i := range shares {
+		delta := ss.lagrangeAtZero(len(shares), func(j int) int { return shares[j].X }, i)
+		sum = f.Add(sum, f.Mul(delta, f.FromUint64(shares[i].Y))) //y_i*delta_i(0)
+	}
+	return f.Uint64(sum), nil
 }
 
-//**********Number theory:
-func extEuclid(a, b int64) (int64, int64) {
-	//fmt.Println("a", a, "b", b)
-	x := int64(0)
-	lastX := int64(1)
-	y := int64(1)
-	lastY := int64(0)
-	for b != 0 {
-		quot := a / b
-		//fmt.Println(quot)
-		a, b = b, a % b
-		//fmt.Println("a", a, "b", b)
-		x, lastX = lastX - quot * x, x
-		y, lastY = lastY - quot * y, y
-	}
-
-	//lastX is multiplicative inverse of a mod b
-	return lastX, lastY
+//Add creates a secret sharing of the sum of two secret shared values.
+//Slices must hold shares of the same parties in the same order, or Add panics.
+func (ss *SecretSharingScheme) Add(aShares, bShares []SecretShare) []SecretShare {
+	checkSameParties(aShares, bShares)
+	aPlusBShares := make([]SecretShare, len(aShares))
+	for i := range aShares {
+		//Addition mod p is the same on integers and on their Montgomery forms, so no conversion is needed
+		y := ss.field.Add(field.Mont64Element(aShares[i].Y%ss.p), field.Mont64Element(bShares[i].Y%ss.p))
+		aPlusBShares[i] = SecretShare{X: aShares[i].X, Y: uint64(y)}
+	}
+	return aPlusBShares
 }
 
-func extGcd(a, b int64) (int64, int64, int64) {
-	if a == 0 {
-		return b, 0, 1
+//Scale multiplies a secret sharing by a constant
+func (ss *SecretSharingScheme) Scale(scalar uint64, shares []SecretShare) []SecretShare {
+	f := ss.field
+	s := f.FromUint64(scalar)
+	scaledShares := make([]SecretShare, len(shares))
+	for i, share := range shares {
+		scaledShares[i] = SecretShare{X: share.X, Y: f.Uint64(f.Mul(s, f.FromUint64(share.Y)))}
 	}
-
-	//gcd, x, y := extGcd(b % a, a)
-
-	return 0, 1, 2
+	return scaledShares
 }
 
-func divideMod(dividend, divisor, p int64) int64 {
-	divisorInv, _ := extEuclid(mod(divisor, p), p)
-	//fmt.Println(divisorInv)
-	//for divisorInv < 0 {divisorInv += int64(mod)}
-
-	return (dividend * divisorInv) //% mod
-}
+//Mul creates a secret sharing of the product of two secret shared values, running the protocol for all parties.
+//Slices must hold the shares of all n parties in the same order, or Mul panics.
+func (ss *SecretSharingScheme) Mul(aShares, bShares []SecretShare) []SecretShare {
+	if len(aShares) != ss.n || len(bShares) != ss.n {
+		panic("uintshamir: missing shares")
+	}
+	if 2*ss.threshold+1 > ss.n {
+		panic("uintshamir: " + strconv.Itoa(ss.n) + " parties cannot multiply sharings of threshold " + strconv.Itoa(ss.threshold))
+	}
+	checkSameParties(aShares, bShares)
+	f := ss.field
+
+	//Step 1 and 2: Each party P_i computes the product h(i) of its two shares and distributes [h(i);f_i]_t
+	partyLocalShares := make([][]RecombinationShare, ss.n)
+	newShares := make([]SecretShare, ss.n)
+	for i, share := range aShares {
+		product := f.Mul(f.FromUint64(share.Y), f.FromUint64(bShares[i].Y))
+		ss.ShareTo(newShares, f.Uint64(product))
+		for i, newShare := range newShares {
+			partyLocalShares[i] = append(partyLocalShares[i], RecombinationShare{SecretShare: newShare, Index: share.X})
+		}
+	}
 
-func multiplicativeInverse(element, mod int64) int64 {
-	inv, _ := extEuclid(element % mod, mod)
-	for inv < 0 {inv += mod}
-	return int64(inv) % mod
+	//Step 3: Create degree threshold sharing
+	shares := make([]SecretShare, ss.n)
+	for party := range shares {
+		y, err := ss.RecombineMultiplicationShares(partyLocalShares[party])
+		if err != nil {
+			panic(err)
+		}
+		shares[party] = SecretShare{X: party + 1, Y: y}
+	}
+	return shares
 }
 
-func lagrangeInterpolationAtZero(points []point, prime int64) int64 {
-	res := int64(0)
-	for _, pointI := range(points) {
-		num := int64(1)
-		den := int64(1)
-		for _, pointJ := range(points) {
-			if pointI.x != pointJ.x {
-				num = (num * pointJ.x)// % prime
-				den = (den * (pointJ.x - pointI.x))// % prime
+//RecombineMultiplicationShares takes at least 2t+1 shares of degree <2t+1
+//returns shares of degree t. It fails if two shares are of the same party.
+func (ss *SecretSharingScheme) RecombineMultiplicationShares(shares []RecombinationShare) (uint64, error) {
+	for i := range shares {
+		for j := i + 1; j < len(shares); j++ {
+			if shares[i].Index == shares[j].Index {
+				return 0, fmt.Errorf("uintshamir: two shares of party %d", shares[i].Index)
 			}
 		}
-		//fmt.Println("num", num, "den", den)
+	}
 
+	f := ss.field
+	sum := f.Zero()
+	for i := range shares {
+		ri := ss.lagrangeAtZero(len(shares), func(j int) int { return shares[j].Index }, i)
+		sum = f.Add(sum, f.Mul(ri, f.FromUint64(shares[i].SecretShare.Y)))
+	}
+	return f.Uint64(sum), nil
+}
 
-		// res = (res + (pointI.y * num * multiplicativeInverse(den, prime)))// % prime
-		delta := divideMod(num, den, prime)
-		//fmt.Println("delta", delta)
-		res = (res + (pointI.y * delta))// % prime
-		//fmt.Println(res )
+//lagrangeAtZero is delta_i(0) = prod_j x_j / (x_j - x_i) for the k points x(0), ..., x(k-1), in Montgomery form
+func (ss *SecretSharingScheme) lagrangeAtZero(k int, x func(j int) int, i int) field.Mont64Element {
+	f := ss.field
+	xi := f.FromUint64(uint64(x(i)))
+	num := f.One()
+	den := f.One()
+	for j := 0; j < k; j++ {
+		if j == i {
+			continue
+		}
+		xj := f.FromUint64(uint64(x(j)))
+		num = f.Mul(num, xj)
+		den = f.Mul(den, f.Sub(xj, xi))
 	}
-	return mod(res, prime)
+	return f.Mul(num, f.Inverse(den))
 }
 
-func mod(a, b int64) int64 {
-	res := a % b
-	if res < 0 {
-		return res + b
+var errDifferentParties = errors.New("uintshamir: sharings of different parties")
+
+func checkSameParties(aShares, bShares []SecretShare) {
+	if len(aShares) != len(bShares) {
+		panic(errDifferentParties)
 	}
-	return res
-}
\ No newline at end of file
+	for i := range aShares {
+		if aShares[i].X != bShares[i].X {
+			panic(errDifferentParties)
+		}
+	}
+}
@@ -1,10 +1,185 @@
 package uintshamir
 
-import "testing"
+import (
+	"math/big"
+	mathrand "math/rand"
+	"testing"
 
-func TestMod(t *testing.T) {
-	aModB := mod(3, -7)
-	if aModB != 3 {
-		t.Errorf("asd")
+	"../bigshamir"
+)
+
+//primes include the largest prime below 2^63, where products of two values overflow 64 bits
+var primes = []uint64{11, 4001, 2147483647, 9223372036854775783}
+
+func toBig(shares []SecretShare) []bigshamir.SecretShare[*big.Int] {
+	bigShares := make([]bigshamir.SecretShare[*big.Int], len(shares))
+	for i, share := range shares {
+		bigShares[i] = bigshamir.SecretShare[*big.Int]{X: share.X, Y: new(big.Int).SetUint64(share.Y)}
+	}
+	return bigShares
+}
+
+func fromBig(bigShares []bigshamir.SecretShare[*big.Int]) []SecretShare {
+	shares := make([]SecretShare, len(bigShares))
+	for i, share := range bigShares {
+		shares[i] = SecretShare{X: share.X, Y: share.Y.Uint64()}
+	}
+	return shares
+}
+
+//property runs check on random schemes and secrets, with the matching bigshamir scheme
+func property(t *testing.T, check func(ss SecretSharingScheme, bigSS bigshamir.SecretSharingScheme[*big.Int], a, b uint64)) {
+	random := mathrand.New(mathrand.NewSource(1))
+	for _, p := range primes {
+		for i := 0; i < 20; i++ {
+			n := 1 + random.Intn(10)
+			if uint64(n) >= p {
+				n = int(p - 1)
+			}
+			threshold := random.Intn((n + 1) / 2)
+			ss, err := NewSS(p, threshold, n)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bigSS := bigshamir.NewSS(new(big.Int).SetUint64(p), threshold, n)
+			a, b := random.Uint64()%p, random.Uint64()%p
+			if i == 0 {
+				a, b = p-1, p-1
+			}
+			check(ss, bigSS, a, b)
+		}
+	}
+}
+
+func mulMod(a, b, p uint64) uint64 {
+	product := new(big.Int).Mul(new(big.Int).SetUint64(a), new(big.Int).SetUint64(b))
+	return product.Mod(product, new(big.Int).SetUint64(p)).Uint64()
+}
+
+func TestShareReconstructsWithBigshamir(t *testing.T) {
+	property(t, func(ss SecretSharingScheme, bigSS bigshamir.SecretSharingScheme[*big.Int], a, _ uint64) {
+		shares := ss.Share(a)
+		//Any t+1 shares determine the secret
+		subset := shares[len(shares)-ss.threshold-1:]
+		if reconstructed, err := bigSS.Reconstruct(toBig(subset)); err != nil || reconstructed.Uint64() != a {
+			t.Errorf("bigshamir reconstructed %d, %v from %v, shared %d mod %d", reconstructed, err, subset, a, ss.p)
+		}
+		reconstructed, err := ss.Reconstruct(fromBig(bigSS.Share(new(big.Int).SetUint64(a))))
+		if err != nil || reconstructed != a {
+			t.Errorf("Reconstructed %d, %v from bigshamir shares of %d mod %d", reconstructed, err, a, ss.p)
+		}
+	})
+}
+
+func TestAddScaleMul(t *testing.T) {
+	property(t, func(ss SecretSharingScheme, _ bigshamir.SecretSharingScheme[*big.Int], a, b uint64) {
+		aShares, bShares := ss.Share(a), ss.Share(b)
+		test := func(operation string, shares []SecretShare, expected uint64) {
+			reconstructed, err := ss.Reconstruct(shares)
+			if err != nil || reconstructed != expected {
+				t.Errorf("%s of %d and %d mod %d reconstructed to %d, %v, expected %d", operation, a, b, ss.p, reconstructed, err, expected)
+			}
+		}
+		test("Add", ss.Add(aShares, bShares), (a+b)%ss.p)
+		test("Scale", ss.Scale(b, aShares), mulMod(a, b, ss.p))
+		aTimesB := ss.Mul(aShares, bShares)
+		test("Mul", aTimesB, mulMod(a, b, ss.p))
+		//Products can be multiplied again, as Mul reduces the degree
+		test("Mul of Mul", ss.Mul(aTimesB, aTimesB), mulMod(mulMod(a, b, ss.p), mulMod(a, b, ss.p), ss.p))
+	})
+}
+
+func TestRecombineMultiplicationSharesLikeBigshamir(t *testing.T) {
+	property(t, func(ss SecretSharingScheme, bigSS bigshamir.SecretSharingScheme[*big.Int], a, b uint64) {
+		if 2*ss.threshold+1 > ss.n {
+			return
+		}
+		shares := make([]RecombinationShare, ss.n)
+		bigShares := make([]bigshamir.RecombinationShare[*big.Int], ss.n)
+		for i := range shares {
+			y := (a + uint64(i)*b) % ss.p
+			shares[i] = RecombinationShare{SecretShare: SecretShare{X: 1, Y: y}, Index: i + 1}
+			bigShares[i] = bigshamir.RecombinationShare[*big.Int]{
+				SecretShare: bigshamir.SecretShare[*big.Int]{X: 1, Y: new(big.Int).SetUint64(y)},
+				Index:       i + 1,
+			}
+		}
+		recombined, err := ss.RecombineMultiplicationShares(shares)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if expected := bigSS.RecombineMultiplicationShares(bigShares); recombined != expected.Uint64() {
+			t.Errorf("Recombined %d, bigshamir recombined %d mod %d", recombined, expected, ss.p)
+		}
+	})
+}
+
+func TestReconstructRejectsInvalidShares(t *testing.T) {
+	ss, _ := NewSS(11, 1, 3)
+	shares := ss.Share(7)
+	if _, err := ss.Reconstruct(shares[:1]); err == nil {
+		t.Error("Reconstructed from too few shares")
+	}
+	if _, err := ss.Reconstruct([]SecretShare{shares[0], shares[0]}); err == nil {
+		t.Error("Reconstructed from two shares of the same party")
+	}
+	if _, err := ss.Reconstruct([]SecretShare{shares[0], {X: 11, Y: 1}}); err == nil {
+		t.Error("Reconstructed from share at a multiple of p")
+	}
+}
+
+func TestRecombineMultiplicationSharesRejectsDuplicates(t *testing.T) {
+	ss, _ := NewSS(11, 1, 3)
+	share := RecombinationShare{SecretShare: SecretShare{X: 1, Y: 7}, Index: 2}
+	if _, err := ss.RecombineMultiplicationShares([]RecombinationShare{share, {SecretShare: SecretShare{X: 1, Y: 3}, Index: 1}, share}); err == nil {
+		t.Error("Recombined two shares of the same party")
+	}
+}
+
+func TestNewSS(t *testing.T) {
+	invalid := []struct {
+		p            uint64
+		threshold, n int
+	}{
+		{4000, 1, 3},
+		{2, 0, 1},
+		{1 << 63, 1, 3},
+		{11, 3, 3},
+		{11, -1, 3},
+		{11, 1, 11},
+	}
+	for _, scheme := range invalid {
+		if _, err := NewSS(scheme.p, scheme.threshold, scheme.n); err == nil {
+			t.Errorf("Created scheme %+v", scheme)
+		}
+	}
+}
+
+func TestDoesNotAllocate(t *testing.T) {
+	if raceEnabled {
+		t.Skip("The race detector allocates")
+	}
+	ss, _ := NewSS(9223372036854775783, 3, 7)
+	shares := make([]SecretShare, 7)
+	recombinationShares := make([]RecombinationShare, 7)
+	allocations := testing.AllocsPerRun(100, func() {
+		ss.ShareTo(shares, 42)
+		ss.Reconstruct(shares)
+		for i := range recombinationShares {
+			recombinationShares[i] = RecombinationShare{SecretShare: shares[i], Index: i + 1}
+		}
+		ss.RecombineMultiplicationShares(recombinationShares)
+	})
+	if allocations != 0 {
+		t.Errorf("Sharing and reconstructing allocates %v times", allocations)
+	}
+}
+
+func BenchmarkShareReconstruct(b *testing.B) {
+	ss, _ := NewSS(4001, 9, 20)
+	shares := make([]SecretShare, 20)
+	for i := 0; i < b.N; i++ {
+		ss.ShareTo(shares, 42)
+		ss.Reconstruct(shares)
 	}
 }